@@ -0,0 +1,11 @@
+ALTER TABLE order_products
+    DROP COLUMN IF EXISTS discount,
+    DROP COLUMN IF EXISTS unit_price;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS promotion_id,
+    DROP COLUMN IF EXISTS coupon_code,
+    DROP COLUMN IF EXISTS order_discount,
+    DROP COLUMN IF EXISTS subtotal;
+
+DROP TABLE IF EXISTS promotions;
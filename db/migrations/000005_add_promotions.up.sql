@@ -0,0 +1,23 @@
+CREATE TABLE promotions (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(10) NOT NULL,
+    type TEXT NOT NULL,
+    value DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    category VARCHAR(255) NOT NULL DEFAULT '',
+    buy_quantity INT NOT NULL DEFAULT 0,
+    get_quantity INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_promotions_code UNIQUE (code),
+    CONSTRAINT chk_promotions_type CHECK (type IN ('percentage', 'fixed_amount', 'buy_x_get_y', 'cheapest_item_free'))
+);
+
+-- persist what was charged so order totals can be reproduced later
+ALTER TABLE orders
+    ADD COLUMN subtotal DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN order_discount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN coupon_code VARCHAR(10),
+    ADD COLUMN promotion_id INT REFERENCES promotions(id) ON DELETE SET NULL;
+
+ALTER TABLE order_products
+    ADD COLUMN unit_price DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN discount DECIMAL(10, 2) NOT NULL DEFAULT 0;
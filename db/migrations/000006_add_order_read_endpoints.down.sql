@@ -0,0 +1,5 @@
+DELETE FROM endpoints
+WHERE http_method = 'GET' AND http_endpoint IN ('/orders', '/orders/[0-9a-fA-F-]{36}');
+
+UPDATE endpoints SET http_endpoint = '/products/\d+}', updated_at = NOW()
+WHERE http_method = 'GET' AND http_endpoint = '/products/\d+';
@@ -0,0 +1,9 @@
+-- fix product pattern seeded in 000004, endpoints are matched as anchored regular expressions
+UPDATE endpoints SET http_endpoint = '/products/\d+', updated_at = NOW()
+WHERE http_method = 'GET' AND http_endpoint = '/products/\d+}';
+
+INSERT INTO endpoints (http_method, http_endpoint)
+VALUES
+        ('GET', '/orders'),
+        ('GET', '/orders/[0-9a-fA-F-]{36}')
+ON CONFLICT (http_method, http_endpoint) DO NOTHING;
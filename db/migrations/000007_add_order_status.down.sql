@@ -0,0 +1,14 @@
+DELETE FROM endpoints
+WHERE http_method = 'POST' AND http_endpoint IN (
+    '/orders/[0-9a-fA-F-]{36}/pay',
+    '/orders/[0-9a-fA-F-]{36}/fulfil',
+    '/orders/[0-9a-fA-F-]{36}/cancel',
+    '/orders/[0-9a-fA-F-]{36}/refund'
+);
+
+DROP TABLE IF EXISTS order_status_transitions;
+
+ALTER TABLE orders
+    DROP CONSTRAINT IF EXISTS chk_orders_status,
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS status;
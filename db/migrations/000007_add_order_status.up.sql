@@ -0,0 +1,26 @@
+ALTER TABLE orders
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'pending',
+    ADD COLUMN updated_at TIMESTAMP DEFAULT NOW(),
+    ADD CONSTRAINT chk_orders_status CHECK (status IN ('pending', 'paid', 'fulfilled', 'cancelled', 'refunded'));
+
+CREATE INDEX idx_orders_status ON orders(status);
+
+CREATE TABLE order_status_transitions (
+    id SERIAL PRIMARY KEY,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    from_status TEXT NOT NULL,
+    to_status TEXT NOT NULL,
+    actor TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_order_status_transitions_order_id ON order_status_transitions(order_id);
+
+INSERT INTO endpoints (http_method, http_endpoint)
+VALUES
+        ('POST', '/orders/[0-9a-fA-F-]{36}/pay'),
+        ('POST', '/orders/[0-9a-fA-F-]{36}/fulfil'),
+        ('POST', '/orders/[0-9a-fA-F-]{36}/cancel'),
+        ('POST', '/orders/[0-9a-fA-F-]{36}/refund')
+ON CONFLICT (http_method, http_endpoint) DO NOTHING;
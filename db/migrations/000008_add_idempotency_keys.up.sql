@@ -0,0 +1,13 @@
+CREATE TABLE idempotency_keys (
+    client_id TEXT NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    request_hash TEXT NOT NULL,
+    status_code INT NOT NULL DEFAULT 0, -- 0 while the first request is still being processed
+    content_type TEXT NOT NULL DEFAULT '',
+    response BYTEA,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (client_id, key)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
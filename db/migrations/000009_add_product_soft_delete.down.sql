@@ -0,0 +1,15 @@
+DELETE FROM endpoints
+WHERE (http_method, http_endpoint) IN (
+    ('POST', '/admin/products'),
+    ('PUT', '/admin/products/\d+'),
+    ('DELETE', '/admin/products/\d+'),
+    ('POST', '/admin/products/\d+/restore')
+);
+
+DROP INDEX IF EXISTS uq_product_images_product_id;
+CREATE INDEX idx_product_images_product_id ON product_images(product_id);
+
+DROP INDEX IF EXISTS idx_products_deleted_at;
+ALTER TABLE products
+    DROP COLUMN IF EXISTS deleted_at,
+    DROP COLUMN IF EXISTS updated_at;
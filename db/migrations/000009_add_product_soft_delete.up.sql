@@ -0,0 +1,17 @@
+ALTER TABLE products
+    ADD COLUMN updated_at TIMESTAMP DEFAULT NOW(),
+    ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX idx_products_deleted_at ON products(deleted_at);
+
+-- one image row per product, required for upserts
+DROP INDEX IF EXISTS idx_product_images_product_id;
+CREATE UNIQUE INDEX uq_product_images_product_id ON product_images(product_id);
+
+INSERT INTO endpoints (http_method, http_endpoint)
+VALUES
+        ('POST', '/admin/products'),
+        ('PUT', '/admin/products/\d+'),
+        ('DELETE', '/admin/products/\d+'),
+        ('POST', '/admin/products/\d+/restore')
+ON CONFLICT (http_method, http_endpoint) DO NOTHING;
@@ -0,0 +1,4 @@
+-- NULL stock means the product is not stock tracked
+ALTER TABLE products
+    ADD COLUMN stock INT,
+    ADD CONSTRAINT chk_products_stock CHECK (stock >= 0);
@@ -0,0 +1,45 @@
+UPDATE promotions SET value = amount_off_amount / 100.0 WHERE type = 'fixed_amount';
+ALTER TABLE promotions
+    DROP COLUMN amount_off_amount,
+    DROP COLUMN amount_off_currency;
+
+ALTER TABLE order_products
+    ADD COLUMN unit_price DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN discount DECIMAL(10, 2) NOT NULL DEFAULT 0;
+UPDATE order_products SET
+    unit_price = unit_price_amount / 100.0,
+    discount = discount_amount / 100.0;
+ALTER TABLE order_products
+    DROP COLUMN unit_price_amount,
+    DROP COLUMN unit_price_currency,
+    DROP COLUMN discount_amount,
+    DROP COLUMN discount_currency;
+
+ALTER TABLE orders
+    ADD COLUMN subtotal DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN total DECIMAL(10, 2),
+    ADD COLUMN discounts DECIMAL(10, 2),
+    ADD COLUMN order_discount DECIMAL(10, 2) NOT NULL DEFAULT 0;
+UPDATE orders SET
+    subtotal = subtotal_amount / 100.0,
+    total = total_amount / 100.0,
+    discounts = discounts_amount / 100.0,
+    order_discount = order_discount_amount / 100.0;
+ALTER TABLE orders
+    ALTER COLUMN total SET NOT NULL,
+    ALTER COLUMN discounts SET NOT NULL,
+    DROP COLUMN subtotal_amount,
+    DROP COLUMN subtotal_currency,
+    DROP COLUMN total_amount,
+    DROP COLUMN total_currency,
+    DROP COLUMN discounts_amount,
+    DROP COLUMN discounts_currency,
+    DROP COLUMN order_discount_amount,
+    DROP COLUMN order_discount_currency;
+
+ALTER TABLE products ADD COLUMN price DECIMAL(10, 2);
+UPDATE products SET price = price_amount / 100.0;
+ALTER TABLE products
+    ALTER COLUMN price SET NOT NULL,
+    DROP COLUMN price_amount,
+    DROP COLUMN price_currency;
@@ -0,0 +1,63 @@
+-- amounts are stored in minor units with their ISO 4217 currency, existing rows were priced in USD
+ALTER TABLE products
+    ADD COLUMN price_amount BIGINT,
+    ADD COLUMN price_currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+UPDATE products SET price_amount = ROUND(price * 100);
+ALTER TABLE products
+    ALTER COLUMN price_amount SET NOT NULL,
+    ALTER COLUMN price_currency DROP DEFAULT,
+    DROP COLUMN price;
+
+ALTER TABLE orders
+    ADD COLUMN subtotal_amount BIGINT,
+    ADD COLUMN subtotal_currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    ADD COLUMN total_amount BIGINT,
+    ADD COLUMN total_currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    ADD COLUMN discounts_amount BIGINT,
+    ADD COLUMN discounts_currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    ADD COLUMN order_discount_amount BIGINT,
+    ADD COLUMN order_discount_currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+UPDATE orders SET
+    subtotal_amount = ROUND(subtotal * 100),
+    total_amount = ROUND(total * 100),
+    discounts_amount = ROUND(discounts * 100),
+    order_discount_amount = ROUND(order_discount * 100);
+ALTER TABLE orders
+    ALTER COLUMN subtotal_amount SET NOT NULL,
+    ALTER COLUMN total_amount SET NOT NULL,
+    ALTER COLUMN discounts_amount SET NOT NULL,
+    ALTER COLUMN order_discount_amount SET NOT NULL,
+    ALTER COLUMN subtotal_currency DROP DEFAULT,
+    ALTER COLUMN total_currency DROP DEFAULT,
+    ALTER COLUMN discounts_currency DROP DEFAULT,
+    ALTER COLUMN order_discount_currency DROP DEFAULT,
+    DROP COLUMN subtotal,
+    DROP COLUMN total,
+    DROP COLUMN discounts,
+    DROP COLUMN order_discount;
+
+ALTER TABLE order_products
+    ADD COLUMN unit_price_amount BIGINT,
+    ADD COLUMN unit_price_currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    ADD COLUMN discount_amount BIGINT,
+    ADD COLUMN discount_currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+UPDATE order_products SET
+    unit_price_amount = ROUND(unit_price * 100),
+    discount_amount = ROUND(discount * 100);
+ALTER TABLE order_products
+    ALTER COLUMN unit_price_amount SET NOT NULL,
+    ALTER COLUMN discount_amount SET NOT NULL,
+    ALTER COLUMN unit_price_currency DROP DEFAULT,
+    ALTER COLUMN discount_currency DROP DEFAULT,
+    DROP COLUMN unit_price,
+    DROP COLUMN discount;
+
+-- value stays the percent of percentage promotions, fixed amounts move to amount_off
+ALTER TABLE promotions
+    ADD COLUMN amount_off_amount BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN amount_off_currency VARCHAR(3) NOT NULL DEFAULT '';
+UPDATE promotions SET
+    amount_off_amount = ROUND(value * 100),
+    amount_off_currency = 'USD',
+    value = 0
+WHERE type = 'fixed_amount';
@@ -0,0 +1,15 @@
+DELETE FROM endpoints
+WHERE (http_method, http_endpoint) IN (
+    ('GET', '/admin/apikeys'),
+    ('POST', '/admin/apikeys/[A-Za-z0-9_-]+/revoke'),
+    ('POST', '/admin/apikeys/[A-Za-z0-9_-]+/rotate'),
+    ('PUT', '/admin/apikeys/[A-Za-z0-9_-]+/expiry')
+);
+
+DROP INDEX IF EXISTS uq_api_keys_client_id;
+
+ALTER TABLE api_keys
+    DROP COLUMN IF EXISTS expires_at,
+    DROP COLUMN IF EXISTS revoked_at,
+    DROP COLUMN IF EXISTS previous_api_key,
+    DROP COLUMN IF EXISTS previous_expires_at;
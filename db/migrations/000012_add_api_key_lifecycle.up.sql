@@ -0,0 +1,16 @@
+-- previous_api_key keeps the hash of a rotated secret until previous_expires_at
+ALTER TABLE api_keys
+    ADD COLUMN expires_at TIMESTAMP,
+    ADD COLUMN revoked_at TIMESTAMP,
+    ADD COLUMN previous_api_key TEXT,
+    ADD COLUMN previous_expires_at TIMESTAMP;
+
+CREATE UNIQUE INDEX uq_api_keys_client_id ON api_keys(client_id);
+
+INSERT INTO endpoints (http_method, http_endpoint)
+VALUES
+        ('GET', '/admin/apikeys'),
+        ('POST', '/admin/apikeys/[A-Za-z0-9_-]+/revoke'),
+        ('POST', '/admin/apikeys/[A-Za-z0-9_-]+/rotate'),
+        ('PUT', '/admin/apikeys/[A-Za-z0-9_-]+/expiry')
+ON CONFLICT (http_method, http_endpoint) DO NOTHING;
@@ -0,0 +1,11 @@
+DELETE FROM endpoints
+WHERE (http_method, http_endpoint) IN (
+    ('POST', '/admin/apikeys/[A-Za-z0-9_-]+/endpoints'),
+    ('DELETE', '/admin/apikeys/[A-Za-z0-9_-]+/endpoints/\d+'),
+    ('POST', '/admin/apikeys/[A-Za-z0-9_-]+/endpoints/\d+/activate'),
+    ('POST', '/admin/apikeys/[A-Za-z0-9_-]+/endpoints/\d+/deactivate')
+);
+
+ALTER TABLE api_key_endpoints
+    ALTER COLUMN is_active DROP NOT NULL,
+    ALTER COLUMN is_active DROP DEFAULT;
@@ -0,0 +1,13 @@
+-- grants created before is_active was enforced were all honoured
+UPDATE api_key_endpoints SET is_active = TRUE WHERE is_active IS NULL;
+ALTER TABLE api_key_endpoints
+    ALTER COLUMN is_active SET DEFAULT TRUE,
+    ALTER COLUMN is_active SET NOT NULL;
+
+INSERT INTO endpoints (http_method, http_endpoint)
+VALUES
+        ('POST', '/admin/apikeys/[A-Za-z0-9_-]+/endpoints'),
+        ('DELETE', '/admin/apikeys/[A-Za-z0-9_-]+/endpoints/\d+'),
+        ('POST', '/admin/apikeys/[A-Za-z0-9_-]+/endpoints/\d+/activate'),
+        ('POST', '/admin/apikeys/[A-Za-z0-9_-]+/endpoints/\d+/deactivate')
+ON CONFLICT (http_method, http_endpoint) DO NOTHING;
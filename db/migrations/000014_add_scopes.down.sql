@@ -0,0 +1,25 @@
+DELETE FROM endpoints WHERE http_method = 'PUT' AND http_endpoint = '/admin/apikeys/{clientID}/scopes';
+
+UPDATE endpoints e SET http_endpoint = m.old_pattern, updated_at = NOW()
+FROM (VALUES
+        ('GET', '/products/{productID}', '/products/\d+'),
+        ('GET', '/orders/{orderID}', '/orders/[0-9a-fA-F-]{36}'),
+        ('POST', '/orders/{orderID}/pay', '/orders/[0-9a-fA-F-]{36}/pay'),
+        ('POST', '/orders/{orderID}/fulfil', '/orders/[0-9a-fA-F-]{36}/fulfil'),
+        ('POST', '/orders/{orderID}/cancel', '/orders/[0-9a-fA-F-]{36}/cancel'),
+        ('POST', '/orders/{orderID}/refund', '/orders/[0-9a-fA-F-]{36}/refund'),
+        ('PUT', '/admin/products/{productID}', '/admin/products/\d+'),
+        ('DELETE', '/admin/products/{productID}', '/admin/products/\d+'),
+        ('POST', '/admin/products/{productID}/restore', '/admin/products/\d+/restore'),
+        ('POST', '/admin/apikeys/{clientID}/revoke', '/admin/apikeys/[A-Za-z0-9_-]+/revoke'),
+        ('POST', '/admin/apikeys/{clientID}/rotate', '/admin/apikeys/[A-Za-z0-9_-]+/rotate'),
+        ('PUT', '/admin/apikeys/{clientID}/expiry', '/admin/apikeys/[A-Za-z0-9_-]+/expiry'),
+        ('POST', '/admin/apikeys/{clientID}/endpoints', '/admin/apikeys/[A-Za-z0-9_-]+/endpoints'),
+        ('DELETE', '/admin/apikeys/{clientID}/endpoints/{endpointID}', '/admin/apikeys/[A-Za-z0-9_-]+/endpoints/\d+'),
+        ('POST', '/admin/apikeys/{clientID}/endpoints/{endpointID}/activate', '/admin/apikeys/[A-Za-z0-9_-]+/endpoints/\d+/activate'),
+        ('POST', '/admin/apikeys/{clientID}/endpoints/{endpointID}/deactivate', '/admin/apikeys/[A-Za-z0-9_-]+/endpoints/\d+/deactivate')
+    ) AS m(http_method, pattern, old_pattern)
+WHERE e.http_method = m.http_method AND e.http_endpoint = m.pattern;
+
+ALTER TABLE endpoints DROP COLUMN IF EXISTS scope;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS scopes;
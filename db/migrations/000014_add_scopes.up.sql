@@ -0,0 +1,37 @@
+-- scopes holds space separated scopes and roles, see pkg/scopes
+ALTER TABLE api_keys ADD COLUMN scopes TEXT NOT NULL DEFAULT '';
+ALTER TABLE endpoints ADD COLUMN scope TEXT NOT NULL DEFAULT '';
+
+-- endpoints are now chi route patterns matched exactly against the routed request, not regular expressions
+UPDATE endpoints e SET http_endpoint = m.pattern, scope = m.scope, updated_at = NOW()
+FROM (VALUES
+        ('POST', '/admin/apikeys', '/admin/apikeys', 'admin'),
+        ('GET', '/admin/endpoints', '/admin/endpoints', 'admin'),
+        ('GET', '/products', '/products', 'products:read'),
+        ('GET', '/products/\d+', '/products/{productID}', 'products:read'),
+        ('POST', '/orders', '/orders', 'orders:write'),
+        ('GET', '/orders', '/orders', 'orders:read'),
+        ('GET', '/orders/[0-9a-fA-F-]{36}', '/orders/{orderID}', 'orders:read'),
+        ('POST', '/orders/[0-9a-fA-F-]{36}/pay', '/orders/{orderID}/pay', 'orders:write'),
+        ('POST', '/orders/[0-9a-fA-F-]{36}/fulfil', '/orders/{orderID}/fulfil', 'orders:write'),
+        ('POST', '/orders/[0-9a-fA-F-]{36}/cancel', '/orders/{orderID}/cancel', 'orders:write'),
+        ('POST', '/orders/[0-9a-fA-F-]{36}/refund', '/orders/{orderID}/refund', 'orders:write'),
+        ('POST', '/admin/products', '/admin/products', 'products:write'),
+        ('PUT', '/admin/products/\d+', '/admin/products/{productID}', 'products:write'),
+        ('DELETE', '/admin/products/\d+', '/admin/products/{productID}', 'products:write'),
+        ('POST', '/admin/products/\d+/restore', '/admin/products/{productID}/restore', 'products:write'),
+        ('GET', '/admin/apikeys', '/admin/apikeys', 'admin'),
+        ('POST', '/admin/apikeys/[A-Za-z0-9_-]+/revoke', '/admin/apikeys/{clientID}/revoke', 'admin'),
+        ('POST', '/admin/apikeys/[A-Za-z0-9_-]+/rotate', '/admin/apikeys/{clientID}/rotate', 'admin'),
+        ('PUT', '/admin/apikeys/[A-Za-z0-9_-]+/expiry', '/admin/apikeys/{clientID}/expiry', 'admin'),
+        ('POST', '/admin/apikeys/[A-Za-z0-9_-]+/endpoints', '/admin/apikeys/{clientID}/endpoints', 'admin'),
+        ('DELETE', '/admin/apikeys/[A-Za-z0-9_-]+/endpoints/\d+', '/admin/apikeys/{clientID}/endpoints/{endpointID}', 'admin'),
+        ('POST', '/admin/apikeys/[A-Za-z0-9_-]+/endpoints/\d+/activate', '/admin/apikeys/{clientID}/endpoints/{endpointID}/activate', 'admin'),
+        ('POST', '/admin/apikeys/[A-Za-z0-9_-]+/endpoints/\d+/deactivate', '/admin/apikeys/{clientID}/endpoints/{endpointID}/deactivate', 'admin')
+    ) AS m(http_method, old_pattern, pattern, scope)
+WHERE e.http_method = m.http_method AND e.http_endpoint = m.old_pattern;
+
+INSERT INTO endpoints (http_method, http_endpoint, scope)
+VALUES
+        ('PUT', '/admin/apikeys/{clientID}/scopes', 'admin')
+ON CONFLICT (http_method, http_endpoint) DO NOTHING;
@@ -0,0 +1,2 @@
+-- endpoints are synced from the router on start up, rows of removed routes are retired instead of deleted
+ALTER TABLE endpoints ADD COLUMN retired_at TIMESTAMP;
@@ -0,0 +1,2 @@
+-- signing_secret is the HMAC request signing secret, sealed with the server key from auth.hmac.secretKey
+ALTER TABLE api_keys ADD COLUMN signing_secret TEXT;
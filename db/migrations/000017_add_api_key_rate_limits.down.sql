@@ -0,0 +1,5 @@
+ALTER TABLE api_keys DROP COLUMN IF EXISTS monthly_quota;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS daily_quota;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS rate_burst;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS rate_limit;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS rate_limit_tier;
@@ -0,0 +1,6 @@
+-- rate limits of a key: a tier of server.rateLimitTiers and overrides of its values, NULL keeps the tier value
+ALTER TABLE api_keys ADD COLUMN rate_limit_tier VARCHAR(50);
+ALTER TABLE api_keys ADD COLUMN rate_limit INTEGER CHECK (rate_limit > 0);
+ALTER TABLE api_keys ADD COLUMN rate_burst INTEGER CHECK (rate_burst > 0);
+ALTER TABLE api_keys ADD COLUMN daily_quota BIGINT CHECK (daily_quota >= 0);
+ALTER TABLE api_keys ADD COLUMN monthly_quota BIGINT CHECK (monthly_quota >= 0);
@@ -0,0 +1,14 @@
+-- rate_limits holds the token buckets and quota counters of clients when server.rateLimitBackend is database
+CREATE TABLE rate_limits (
+    client_id TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    refilled_at TIMESTAMP NOT NULL,
+    day_start TIMESTAMP NOT NULL,
+    daily_used BIGINT NOT NULL DEFAULT 0,
+    month_start TIMESTAMP NOT NULL,
+    monthly_used BIGINT NOT NULL DEFAULT 0,
+    quotas BOOLEAN NOT NULL DEFAULT FALSE, -- counters are kept until their month is over
+    last_seen TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_rate_limits_last_seen ON rate_limits(last_seen);
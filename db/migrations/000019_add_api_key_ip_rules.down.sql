@@ -0,0 +1,2 @@
+ALTER TABLE api_keys DROP COLUMN IF EXISTS ip_denylist;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS ip_allowlist;
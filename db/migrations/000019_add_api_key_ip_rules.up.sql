@@ -0,0 +1,3 @@
+-- space separated CIDRs, requests from outside ip_allowlist (when not empty) or from inside ip_denylist are rejected
+ALTER TABLE api_keys ADD COLUMN ip_allowlist TEXT NOT NULL DEFAULT '';
+ALTER TABLE api_keys ADD COLUMN ip_denylist TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,27 @@
+CREATE TABLE audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    actor TEXT NOT NULL DEFAULT '', -- client ID of the key, or the client ID claimed by failed requests
+    action VARCHAR(100) NOT NULL,
+    target TEXT NOT NULL DEFAULT '',
+    outcome VARCHAR(20) NOT NULL,
+    detail TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    trace_id VARCHAR(32) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_audit_events_occurred_at ON audit_events(occurred_at);
+CREATE INDEX idx_audit_events_actor_occurred_at ON audit_events(actor, occurred_at);
+
+-- audit events are append-only
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_no_update_or_delete BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE TRIGGER audit_events_no_truncate BEFORE TRUNCATE ON audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
@@ -0,0 +1,10 @@
+-- used_nonces remembers the nonces of signed requests until they fail the clock skew check, so a request
+-- is accepted once across all instances
+CREATE TABLE used_nonces (
+    client_id TEXT NOT NULL,
+    nonce VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (client_id, nonce)
+);
+
+CREATE INDEX idx_used_nonces_expires_at ON used_nonces(expires_at);
@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
 	"github.com/malakagl/go-template/pkg/errors"
 	"github.com/malakagl/go-template/pkg/log"
 	"github.com/malakagl/go-template/pkg/models/dto/request"
@@ -15,10 +18,11 @@ import (
 type AdminHandler struct {
 	endpointService services.IEndpointService
 	apiKeyService   services.IAPIKeyService
+	validator       *validator.Validate
 }
 
 func NewAdminHandler(o services.IEndpointService, a services.IAPIKeyService) *AdminHandler {
-	return &AdminHandler{endpointService: o, apiKeyService: a}
+	return &AdminHandler{endpointService: o, apiKeyService: a, validator: validator.New()}
 }
 
 func (a *AdminHandler) GetEndpoints(w http.ResponseWriter, r *http.Request) {
@@ -53,3 +57,247 @@ func (a *AdminHandler) CreateAPIKeys(w http.ResponseWriter, r *http.Request) {
 
 	response.Success(w, http.StatusCreated, orderRes)
 }
+
+func (a *AdminHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	keys, err := a.apiKeyService.FindAll(ctx)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error fetching api keys: %v", err)
+		code, msg := util.MapErrorToHTTP(err)
+		response.Error(w, code, msg, err.Error())
+		return
+	}
+
+	response.Success(w, http.StatusOK, keys)
+}
+
+func (a *AdminHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	clientID := chi.URLParam(r, "clientID")
+	key, err := a.apiKeyService.Revoke(ctx, clientID)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error revoking api key %s: %v", clientID, err)
+		code, msg := util.MapErrorToHTTP(err)
+		response.Error(w, code, msg, err.Error())
+		return
+	}
+
+	response.Success(w, http.StatusOK, key)
+}
+
+// RotateAPIKey issues a new secret for the key in the path. The grace period body is optional.
+func (a *AdminHandler) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var rotateReq request.RotateAPIKeyRequest
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&rotateReq); err != nil && !errors.Is(err, io.EOF) {
+		log.WithCtx(ctx).Error().Msgf("Error decoding request body: %v", err)
+		response.Error(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if err := a.validator.Struct(rotateReq); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	clientID := chi.URLParam(r, "clientID")
+	key, err := a.apiKeyService.Rotate(ctx, clientID, &rotateReq)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error rotating api key %s: %v", clientID, err)
+		code, msg := util.MapErrorToHTTP(err)
+		response.Error(w, code, msg, err.Error())
+		return
+	}
+
+	response.Success(w, http.StatusOK, key)
+}
+
+func (a *AdminHandler) SetAPIKeyExpiry(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var expiryReq request.APIKeyExpiryRequest
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&expiryReq); err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error decoding request body: %v", err)
+		response.Error(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	clientID := chi.URLParam(r, "clientID")
+	key, err := a.apiKeyService.SetExpiry(ctx, clientID, &expiryReq)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error setting expiry of api key %s: %v", clientID, err)
+		code, msg := util.MapErrorToHTTP(err)
+		response.Error(w, code, msg, err.Error())
+		return
+	}
+
+	response.Success(w, http.StatusOK, key)
+}
+
+func (a *AdminHandler) AddAPIKeyEndpoints(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var endpointsReq request.APIKeyEndpointsRequest
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&endpointsReq); err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error decoding request body: %v", err)
+		response.Error(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if err := a.validator.Struct(endpointsReq); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	clientID := chi.URLParam(r, "clientID")
+	key, err := a.apiKeyService.AddEndpoints(ctx, clientID, &endpointsReq)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error granting endpoints to api key %s: %v", clientID, err)
+		code, msg := util.MapErrorToHTTP(err)
+		response.Error(w, code, msg, err.Error())
+		return
+	}
+
+	response.Success(w, http.StatusOK, key)
+}
+
+func (a *AdminHandler) RemoveAPIKeyEndpoint(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	clientID, endpointID := chi.URLParam(r, "clientID"), chi.URLParam(r, "endpointID")
+	key, err := a.apiKeyService.RemoveEndpoint(ctx, clientID, endpointID)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error removing endpoint %s from api key %s: %v", endpointID, clientID, err)
+		code, msg := util.MapErrorToHTTP(err)
+		response.Error(w, code, msg, err.Error())
+		return
+	}
+
+	response.Success(w, http.StatusOK, key)
+}
+
+// SetAPIKeyEndpointActive returns a handler activating or deactivating the endpoint grant in the path.
+func (a *AdminHandler) SetAPIKeyEndpointActive(active bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		clientID, endpointID := chi.URLParam(r, "clientID"), chi.URLParam(r, "endpointID")
+		key, err := a.apiKeyService.SetEndpointActive(ctx, clientID, endpointID, active)
+		if err != nil {
+			log.WithCtx(ctx).Error().Msgf("Error setting endpoint %s of api key %s active=%v: %v", endpointID, clientID, active, err)
+			code, msg := util.MapErrorToHTTP(err)
+			response.Error(w, code, msg, err.Error())
+			return
+		}
+
+		response.Success(w, http.StatusOK, key)
+	}
+}
+
+func (a *AdminHandler) SetAPIKeyScopes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var scopesReq request.APIKeyScopesRequest
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&scopesReq); err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error decoding request body: %v", err)
+		response.Error(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if err := a.validator.Struct(scopesReq); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	clientID := chi.URLParam(r, "clientID")
+	key, err := a.apiKeyService.SetScopes(ctx, clientID, &scopesReq)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error setting scopes of api key %s: %v", clientID, err)
+		code, msg := util.MapErrorToHTTP(err)
+		response.Error(w, code, msg, err.Error())
+		return
+	}
+
+	response.Success(w, http.StatusOK, key)
+}
+
+func (a *AdminHandler) IssueSigningSecret(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	clientID := chi.URLParam(r, "clientID")
+	secret, err := a.apiKeyService.IssueSigningSecret(ctx, clientID)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error issuing signing secret of api key %s: %v", clientID, err)
+		code, msg := util.MapErrorToHTTP(err)
+		response.Error(w, code, msg, err.Error())
+		return
+	}
+
+	response.Success(w, http.StatusCreated, secret)
+}
+
+func (a *AdminHandler) SetAPIKeyIPRules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var rulesReq request.APIKeyIPRulesRequest
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&rulesReq); err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error decoding request body: %v", err)
+		response.Error(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if err := a.validator.Struct(rulesReq); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	clientID := chi.URLParam(r, "clientID")
+	key, err := a.apiKeyService.SetIPRules(ctx, clientID, &rulesReq)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error setting ip rules of api key %s: %v", clientID, err)
+		code, msg := util.MapErrorToHTTP(err)
+		response.Error(w, code, msg, err.Error())
+		return
+	}
+
+	response.Success(w, http.StatusOK, key)
+}
+
+func (a *AdminHandler) SetAPIKeyRateLimits(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var limitsReq request.APIKeyRateLimitsRequest
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&limitsReq); err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error decoding request body: %v", err)
+		response.Error(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if err := a.validator.Struct(limitsReq); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	clientID := chi.URLParam(r, "clientID")
+	usage, err := a.apiKeyService.SetRateLimits(ctx, clientID, &limitsReq)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error setting rate limits of api key %s: %v", clientID, err)
+		code, msg := util.MapErrorToHTTP(err)
+		response.Error(w, code, msg, err.Error())
+		return
+	}
+
+	response.Success(w, http.StatusOK, usage)
+}
+
+func (a *AdminHandler) GetAPIKeyUsage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	clientID := chi.URLParam(r, "clientID")
+	usage, err := a.apiKeyService.Usage(ctx, clientID)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error fetching usage of api key %s: %v", clientID, err)
+		code, msg := util.MapErrorToHTTP(err)
+		response.Error(w, code, msg, err.Error())
+		return
+	}
+
+	response.Success(w, http.StatusOK, usage)
+}
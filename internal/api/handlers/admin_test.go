@@ -0,0 +1,407 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/malakagl/go-template/pkg/errors"
+	"github.com/malakagl/go-template/pkg/models/dto/request"
+	"github.com/malakagl/go-template/pkg/models/dto/response"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAPIKeyService implements APIKeyService for testing
+type MockAPIKeyService struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyService) Create(_ context.Context, req *request.ApiKeyRequest) (*response.APIKeyResponse, error) {
+	args := m.Called(req)
+	return args.Get(0).(*response.APIKeyResponse), args.Error(1)
+}
+
+func (m *MockAPIKeyService) FindAll(_ context.Context) (*response.APIKeysResponse, error) {
+	args := m.Called()
+	return args.Get(0).(*response.APIKeysResponse), args.Error(1)
+}
+
+func (m *MockAPIKeyService) Revoke(_ context.Context, clientID string) (*response.APIKey, error) {
+	args := m.Called(clientID)
+	return args.Get(0).(*response.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyService) Rotate(_ context.Context, clientID string, req *request.RotateAPIKeyRequest) (*response.APIKeyResponse, error) {
+	args := m.Called(clientID, req)
+	return args.Get(0).(*response.APIKeyResponse), args.Error(1)
+}
+
+func (m *MockAPIKeyService) SetExpiry(_ context.Context, clientID string, req *request.APIKeyExpiryRequest) (*response.APIKey, error) {
+	args := m.Called(clientID, req)
+	return args.Get(0).(*response.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyService) AddEndpoints(_ context.Context, clientID string, req *request.APIKeyEndpointsRequest) (*response.APIKey, error) {
+	args := m.Called(clientID, req)
+	return args.Get(0).(*response.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyService) RemoveEndpoint(_ context.Context, clientID, endpointID string) (*response.APIKey, error) {
+	args := m.Called(clientID, endpointID)
+	return args.Get(0).(*response.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyService) SetEndpointActive(_ context.Context, clientID, endpointID string, active bool) (*response.APIKey, error) {
+	args := m.Called(clientID, endpointID, active)
+	return args.Get(0).(*response.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyService) SetScopes(_ context.Context, clientID string, req *request.APIKeyScopesRequest) (*response.APIKey, error) {
+	args := m.Called(clientID, req)
+	return args.Get(0).(*response.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyService) IssueSigningSecret(_ context.Context, clientID string) (*response.SigningSecretResponse, error) {
+	args := m.Called(clientID)
+	return args.Get(0).(*response.SigningSecretResponse), args.Error(1)
+}
+
+func (m *MockAPIKeyService) SetIPRules(_ context.Context, clientID string, req *request.APIKeyIPRulesRequest) (*response.APIKey, error) {
+	args := m.Called(clientID, req)
+	return args.Get(0).(*response.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyService) SetRateLimits(_ context.Context, clientID string, req *request.APIKeyRateLimitsRequest) (*response.APIKeyUsage, error) {
+	args := m.Called(clientID, req)
+	return args.Get(0).(*response.APIKeyUsage), args.Error(1)
+}
+
+func (m *MockAPIKeyService) Usage(_ context.Context, clientID string) (*response.APIKeyUsage, error) {
+	args := m.Called(clientID)
+	return args.Get(0).(*response.APIKeyUsage), args.Error(1)
+}
+
+func withURLParam(r *http.Request, key, value string) *http.Request {
+	ctx := chi.NewRouteContext()
+	ctx.URLParams.Add(key, value)
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, ctx))
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockRes        *response.APIKey
+		mockErr        error
+		expectedStatus int
+	}{
+		{
+			name:           "successful request",
+			mockRes:        &response.APIKey{ClientID: "client-a", Status: response.APIKeyRevoked},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unknown key",
+			mockErr:        errors.ErrAPIKeyNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := withURLParam(httptest.NewRequest(http.MethodPost, "/admin/apikeys/client-a/revoke", nil), "clientID", "client-a")
+			w := httptest.NewRecorder()
+
+			mockService := new(MockAPIKeyService)
+			mockService.On("Revoke", "client-a").Return(tt.mockRes, tt.mockErr)
+
+			handler := NewAdminHandler(nil, mockService)
+			handler.RevokeAPIKey(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestRotateAPIKey(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		mockRes        *response.APIKeyResponse
+		mockErr        error
+		expectedStatus int
+	}{
+		{
+			name:           "default grace period",
+			mockRes:        &response.APIKeyResponse{ApiKey: "client-a.secret"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "custom grace period",
+			body:           `{"gracePeriodSeconds":60}`,
+			mockRes:        &response.APIKeyResponse{ApiKey: "client-a.secret"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "negative grace period",
+			body:           `{"gracePeriodSeconds":-1}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "revoked key",
+			mockErr:        errors.ErrAPIKeyRevoked,
+			expectedStatus: http.StatusConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/apikeys/client-a/rotate", strings.NewReader(tt.body))
+			req = withURLParam(req, "clientID", "client-a")
+			w := httptest.NewRecorder()
+
+			mockService := new(MockAPIKeyService)
+			mockService.On("Rotate", "client-a", mock.Anything).Return(tt.mockRes, tt.mockErr)
+
+			handler := NewAdminHandler(nil, mockService)
+			handler.RotateAPIKey(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestAddAPIKeyEndpoints(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		mockRes        *response.APIKey
+		mockErr        error
+		expectedStatus int
+	}{
+		{
+			name:           "successful request",
+			body:           `{"endPoints":["3","4"]}`,
+			mockRes:        &response.APIKey{ClientID: "client-a"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "no endpoints",
+			body:           `{"endPoints":[]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "non numeric endpoint",
+			body:           `{"endPoints":["abc"]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown endpoint",
+			body:           `{"endPoints":["99"]}`,
+			mockErr:        errors.ErrEndpointsNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/apikeys/client-a/endpoints", strings.NewReader(tt.body))
+			req = withURLParam(req, "clientID", "client-a")
+			w := httptest.NewRecorder()
+
+			mockService := new(MockAPIKeyService)
+			mockService.On("AddEndpoints", "client-a", mock.Anything).Return(tt.mockRes, tt.mockErr)
+
+			handler := NewAdminHandler(nil, mockService)
+			handler.AddAPIKeyEndpoints(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestSetAPIKeyEndpointActive(t *testing.T) {
+	for _, active := range []bool{true, false} {
+		ctx := chi.NewRouteContext()
+		ctx.URLParams.Add("clientID", "client-a")
+		ctx.URLParams.Add("endpointID", "3")
+		req := httptest.NewRequest(http.MethodPost, "/admin/apikeys/client-a/endpoints/3/activate", nil)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+		w := httptest.NewRecorder()
+
+		mockService := new(MockAPIKeyService)
+		mockService.On("SetEndpointActive", "client-a", "3", active).Return((*response.APIKey)(nil), errors.ErrGrantNotFound)
+
+		handler := NewAdminHandler(nil, mockService)
+		handler.SetAPIKeyEndpointActive(active)(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status %d for a missing grant, got %d", http.StatusNotFound, w.Code)
+		}
+		mockService.AssertExpectations(t)
+	}
+}
+
+func TestSetAPIKeyScopes(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		mockRes        *response.APIKey
+		mockErr        error
+		expectedStatus int
+	}{
+		{
+			name:           "successful request",
+			body:           `{"scopes":["customer","admin"]}`,
+			mockRes:        &response.APIKey{ClientID: "client-a", Scopes: []string{"customer", "admin"}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "clear scopes",
+			body:           `{"scopes":[]}`,
+			mockRes:        &response.APIKey{ClientID: "client-a"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing scopes",
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown scope",
+			body:           `{"scopes":["root"]}`,
+			mockErr:        errors.ErrUnknownScope,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/admin/apikeys/client-a/scopes", strings.NewReader(tt.body))
+			req = withURLParam(req, "clientID", "client-a")
+			w := httptest.NewRecorder()
+
+			mockService := new(MockAPIKeyService)
+			mockService.On("SetScopes", "client-a", mock.Anything).Return(tt.mockRes, tt.mockErr)
+
+			handler := NewAdminHandler(nil, mockService)
+			handler.SetAPIKeyScopes(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestSetAPIKeyRateLimits(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		mockRes        *response.APIKeyUsage
+		mockErr        error
+		expectedStatus int
+	}{
+		{
+			name:           "tier with override",
+			body:           `{"tier":"standard","dailyQuota":0}`,
+			mockRes:        &response.APIKeyUsage{ClientID: "client-a", Tier: "standard"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "default limits",
+			body:           `{}`,
+			mockRes:        &response.APIKeyUsage{ClientID: "client-a"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "zero burst",
+			body:           `{"burst":0}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown tier",
+			body:           `{"tier":"gold"}`,
+			mockErr:        errors.ErrUnknownTier,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown key",
+			body:           `{"tier":"standard"}`,
+			mockErr:        errors.ErrAPIKeyNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/admin/apikeys/client-a/rate-limits", strings.NewReader(tt.body))
+			req = withURLParam(req, "clientID", "client-a")
+			w := httptest.NewRecorder()
+
+			mockService := new(MockAPIKeyService)
+			mockService.On("SetRateLimits", "client-a", mock.Anything).Return(tt.mockRes, tt.mockErr)
+
+			handler := NewAdminHandler(nil, mockService)
+			handler.SetAPIKeyRateLimits(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestSetAPIKeyIPRules(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		mockRes        *response.APIKey
+		mockErr        error
+		expectedStatus int
+	}{
+		{
+			name:           "successful request",
+			body:           `{"allow":["10.0.0.0/8","203.0.113.7"],"deny":["10.0.66.0/24"]}`,
+			mockRes:        &response.APIKey{ClientID: "client-a", IPAllow: []string{"10.0.0.0/8", "203.0.113.7"}, IPDeny: []string{"10.0.66.0/24"}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "clear rules",
+			body:           `{}`,
+			mockRes:        &response.APIKey{ClientID: "client-a"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid cidr",
+			body:           `{"allow":["office"]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown key",
+			body:           `{"deny":["203.0.113.0/24"]}`,
+			mockErr:        errors.ErrAPIKeyNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/admin/apikeys/client-a/ip-rules", strings.NewReader(tt.body))
+			req = withURLParam(req, "clientID", "client-a")
+			w := httptest.NewRecorder()
+
+			mockService := new(MockAPIKeyService)
+			mockService.On("SetIPRules", "client-a", mock.Anything).Return(tt.mockRes, tt.mockErr)
+
+			handler := NewAdminHandler(nil, mockService)
+			handler.SetAPIKeyIPRules(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/malakagl/go-template/pkg/log"
+	"github.com/malakagl/go-template/pkg/models/dto/request"
+	"github.com/malakagl/go-template/pkg/models/dto/response"
+	"github.com/malakagl/go-template/pkg/services"
+	"github.com/malakagl/go-template/pkg/util"
+)
+
+type AuditHandler struct {
+	auditLogger services.IAuditLogger
+	validator   *validator.Validate
+}
+
+func NewAuditHandler(a services.IAuditLogger) *AuditHandler {
+	return &AuditHandler{auditLogger: a, validator: validator.New()}
+}
+
+func (a *AuditHandler) ListAuditEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	filter, err := parseAuditFilter(r.URL.Query())
+	if err != nil {
+		log.WithCtx(ctx).Warn().Msgf("Invalid audit filter: %v", err)
+		response.Error(w, http.StatusBadRequest, "Invalid request data", err.Error())
+		return
+	}
+
+	if err := a.validator.Struct(filter); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	events, err := a.auditLogger.FindAll(ctx, filter)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error fetching audit events: %v", err)
+		code, msg := util.MapErrorToHTTP(err)
+		response.Error(w, code, msg, err.Error())
+		return
+	}
+
+	response.Success(w, http.StatusOK, events)
+}
+
+const defaultAuditPageSize = 50
+
+// parseAuditFilter reads from, to (RFC3339), actor, action, limit and offset.
+func parseAuditFilter(q url.Values) (*request.AuditFilter, error) {
+	f := &request.AuditFilter{Limit: defaultAuditPageSize, Actor: q.Get("actor"), Action: q.Get("action")}
+	var err error
+	if f.From, err = parseTimeParam(q, "from"); err != nil {
+		return nil, err
+	}
+	if f.To, err = parseTimeParam(q, "to"); err != nil {
+		return nil, err
+	}
+	if v := q.Get("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("invalid limit: %s", v)
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if f.Offset, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("invalid offset: %s", v)
+		}
+	}
+
+	return f, nil
+}
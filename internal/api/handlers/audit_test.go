@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/malakagl/go-template/pkg/errors"
+	"github.com/malakagl/go-template/pkg/models/db"
+	"github.com/malakagl/go-template/pkg/models/dto/request"
+	"github.com/malakagl/go-template/pkg/models/dto/response"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAuditLogger implements IAuditLogger for testing
+type MockAuditLogger struct {
+	mock.Mock
+}
+
+func (m *MockAuditLogger) Log(_ context.Context, e *db.AuditEvent) {
+	m.Called(e)
+}
+
+func (m *MockAuditLogger) Record(_ context.Context, action, target, detail string, err error) {
+	m.Called(action, target, detail, err)
+}
+
+func (m *MockAuditLogger) FindAll(_ context.Context, f *request.AuditFilter) (*response.AuditEventsResponse, error) {
+	args := m.Called(f)
+	return args.Get(0).(*response.AuditEventsResponse), args.Error(1)
+}
+
+func TestListAuditEvents(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		query          string
+		filter         *request.AuditFilter
+		mockRes        *response.AuditEventsResponse
+		mockErr        error
+		expectedStatus int
+	}{
+		{
+			name:           "time range and actor",
+			query:          "?from=2025-01-01T00:00:00Z&actor=admin&limit=10",
+			filter:         &request.AuditFilter{From: &from, Actor: "admin", Limit: 10},
+			mockRes:        &response.AuditEventsResponse{Events: []response.AuditEvent{{ID: 1, Actor: "admin"}}, Total: 1},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "default page",
+			filter:         &request.AuditFilter{Limit: defaultAuditPageSize},
+			mockRes:        &response.AuditEventsResponse{},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid time range",
+			query:          "?to=tomorrow",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "limit out of range",
+			query:          "?limit=1000",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "service error",
+			filter:         &request.AuditFilter{Limit: defaultAuditPageSize},
+			mockRes:        (*response.AuditEventsResponse)(nil),
+			mockErr:        errors.ErrDatabaseError,
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/audit"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			mockLogger := new(MockAuditLogger)
+			if tt.filter != nil {
+				mockLogger.On("FindAll", tt.filter).Return(tt.mockRes, tt.mockErr)
+			}
+			NewAuditHandler(mockLogger).ListAuditEvents(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			mockLogger.AssertExpectations(t)
+		})
+	}
+}
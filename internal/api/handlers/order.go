@@ -4,11 +4,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
 	"github.com/malakagl/go-template/pkg/log"
+	"github.com/malakagl/go-template/pkg/models/db"
 	"github.com/malakagl/go-template/pkg/models/dto/request"
 	"github.com/malakagl/go-template/pkg/models/dto/response"
 	"github.com/malakagl/go-template/pkg/services"
@@ -38,17 +44,7 @@ func (o *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := o.validator.Struct(orderReq); err != nil {
-		var ve validator.ValidationErrors
-		if errors.As(err, &ve) {
-			errs := make([]string, len(ve))
-			for i, fe := range ve {
-				errs[i] = fmt.Sprintf("%s failed on %s", fe.Field(), fe.Tag())
-			}
-			response.Error(w, http.StatusBadRequest, "Invalid request data", strings.Join(errs, ", "))
-			return
-		}
-
-		response.Error(w, http.StatusBadRequest, "Invalid request data", err.Error())
+		validationError(w, err)
 		return
 	}
 
@@ -62,3 +58,146 @@ func (o *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 
 	response.Success(w, http.StatusCreated, orderRes)
 }
+
+func (o *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	orderID := chi.URLParam(r, "orderID")
+	orderRes, err := o.orderService.FindByID(ctx, orderID)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error fetching order %s: %v", orderID, err)
+		code, msg := util.MapErrorToHTTP(err)
+		response.Error(w, code, msg, err.Error())
+		return
+	}
+
+	response.Success(w, http.StatusOK, orderRes)
+}
+
+func (o *OrderHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	filter, err := parseOrderFilter(r.URL.Query())
+	if err != nil {
+		log.WithCtx(ctx).Warn().Msgf("Invalid order filter: %v", err)
+		response.Error(w, http.StatusBadRequest, "Invalid request data", err.Error())
+		return
+	}
+
+	if err := o.validator.Struct(filter); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	orders, err := o.orderService.FindAll(ctx, filter)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error fetching orders: %v", err)
+		code, msg := util.MapErrorToHTTP(err)
+		response.Error(w, code, msg, err.Error())
+		return
+	}
+
+	response.Success(w, http.StatusOK, orders)
+}
+
+// UpdateStatus returns a handler moving the order in the path to status. The reason body is optional.
+func (o *OrderHandler) UpdateStatus(status db.OrderStatus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var statusReq request.OrderStatusRequest
+		defer r.Body.Close()
+		if err := json.NewDecoder(r.Body).Decode(&statusReq); err != nil && !errors.Is(err, io.EOF) {
+			log.WithCtx(ctx).Error().Msgf("Error decoding request body: %v", err)
+			response.Error(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+
+		if err := o.validator.Struct(statusReq); err != nil {
+			validationError(w, err)
+			return
+		}
+
+		orderID := chi.URLParam(r, "orderID")
+		orderRes, err := o.orderService.UpdateStatus(ctx, orderID, status, &statusReq)
+		if err != nil {
+			log.WithCtx(ctx).Error().Msgf("Error moving order %s to %s: %v", orderID, status, err)
+			code, msg := util.MapErrorToHTTP(err)
+			response.Error(w, code, msg, err.Error())
+			return
+		}
+
+		response.Success(w, http.StatusOK, orderRes)
+	}
+}
+
+const defaultOrderPageSize = 20
+
+// parseOrderFilter reads from, to (RFC3339), minTotal, maxTotal (minor units), productId, status, limit and offset.
+func parseOrderFilter(q url.Values) (*request.OrderFilter, error) {
+	f := &request.OrderFilter{Limit: defaultOrderPageSize, ProductID: q.Get("productId"), Status: q.Get("status")}
+	var err error
+	if f.From, err = parseTimeParam(q, "from"); err != nil {
+		return nil, err
+	}
+	if f.To, err = parseTimeParam(q, "to"); err != nil {
+		return nil, err
+	}
+	if f.MinTotal, err = parseIntParam(q, "minTotal"); err != nil {
+		return nil, err
+	}
+	if f.MaxTotal, err = parseIntParam(q, "maxTotal"); err != nil {
+		return nil, err
+	}
+	if v := q.Get("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("invalid limit: %s", v)
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if f.Offset, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("invalid offset: %s", v)
+		}
+	}
+
+	return f, nil
+}
+
+func parseTimeParam(q url.Values, name string) (*time.Time, error) {
+	v := q.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", name, v)
+	}
+
+	return &t, nil
+}
+
+func parseIntParam(q url.Values, name string) (*int64, error) {
+	v := q.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+
+	i, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", name, v)
+	}
+
+	return &i, nil
+}
+
+func validationError(w http.ResponseWriter, err error) {
+	var ve validator.ValidationErrors
+	if errors.As(err, &ve) {
+		errs := make([]string, len(ve))
+		for i, fe := range ve {
+			errs[i] = fmt.Sprintf("%s failed on %s", fe.Field(), fe.Tag())
+		}
+		response.Error(w, http.StatusBadRequest, "Invalid request data", strings.Join(errs, ", "))
+		return
+	}
+
+	response.Error(w, http.StatusBadRequest, "Invalid request data", err.Error())
+}
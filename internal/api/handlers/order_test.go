@@ -4,13 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/malakagl/go-template/pkg/errors"
+	"github.com/malakagl/go-template/pkg/models/db"
 	"github.com/malakagl/go-template/pkg/models/dto/request"
 	"github.com/malakagl/go-template/pkg/models/dto/response"
+	"github.com/malakagl/go-template/pkg/money"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -24,6 +28,21 @@ func (m *MockOrderService) Create(_ context.Context, _ *request.OrderRequest) (*
 	return args.Get(0).(*response.OrderResponse), args.Error(1)
 }
 
+func (m *MockOrderService) FindByID(_ context.Context, id string) (*response.OrderResponse, error) {
+	args := m.Called(id)
+	return args.Get(0).(*response.OrderResponse), args.Error(1)
+}
+
+func (m *MockOrderService) FindAll(_ context.Context, f *request.OrderFilter) (*response.OrdersResponse, error) {
+	args := m.Called(f)
+	return args.Get(0).(*response.OrdersResponse), args.Error(1)
+}
+
+func (m *MockOrderService) UpdateStatus(_ context.Context, id string, status db.OrderStatus, req *request.OrderStatusRequest) (*response.OrderResponse, error) {
+	args := m.Called(id, status, req.Reason)
+	return args.Get(0).(*response.OrderResponse), args.Error(1)
+}
+
 func TestCreateOrder(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -38,7 +57,7 @@ func TestCreateOrder(t *testing.T) {
 				CouponCode: "HAPPYHRS",
 				Items:      []request.Item{{ProductID: "1", Quantity: 2}},
 			},
-			mockRes:        &response.OrderResponse{ID: "1234", Total: 100.0},
+			mockRes:        &response.OrderResponse{ID: "1234", Total: money.New(10000, "USD")},
 			expectedStatus: http.StatusCreated,
 		},
 		{
@@ -73,6 +92,15 @@ func TestCreateOrder(t *testing.T) {
 			mockErr:        errors.New("db error"),
 			expectedStatus: http.StatusInternalServerError,
 		},
+		{
+			name: "insufficient stock",
+			body: request.OrderRequest{
+				CouponCode: "HAPPYHRS",
+				Items:      []request.Item{{ProductID: "1", Quantity: 2}},
+			},
+			mockErr:        fmt.Errorf("%w: product 1", errors.ErrInsufficientStock),
+			expectedStatus: http.StatusConflict,
+		},
 	}
 
 	for _, tt := range tests {
@@ -100,3 +128,165 @@ func TestCreateOrder(t *testing.T) {
 		})
 	}
 }
+
+func TestGetOrder(t *testing.T) {
+	const orderID = "0b6c2f4e-3f7e-4d39-9a55-7a3c1f1e2d10"
+	tests := []struct {
+		name           string
+		mockRes        *response.OrderResponse
+		mockErr        error
+		expectedStatus int
+	}{
+		{
+			name:           "successful request",
+			mockRes:        &response.OrderResponse{ID: orderID},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid order id",
+			mockErr:        errors.ErrInvalidOrderID,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "order not found",
+			mockErr:        errors.ErrOrderNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "service error",
+			mockErr:        errors.ErrDatabaseError,
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := chi.NewRouteContext()
+			ctx.URLParams.Add("orderID", orderID)
+			req := httptest.NewRequest(http.MethodGet, "/orders/"+orderID, nil)
+			req = req.WithContext(context.WithValue(context.Background(), chi.RouteCtxKey, ctx))
+			w := httptest.NewRecorder()
+
+			mockService := new(MockOrderService)
+			mockService.On("FindByID", orderID).Return(tt.mockRes, tt.mockErr)
+			handler := NewOrderHandler(mockService)
+			handler.GetOrder(w, req)
+
+			resp := w.Result()
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestListOrders(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		mockRes        *response.OrdersResponse
+		mockErr        error
+		expectedStatus int
+	}{
+		{
+			name:           "successful request",
+			query:          "?from=2025-01-01T00:00:00Z&minTotal=10&productId=1&limit=5&offset=10",
+			mockRes:        &response.OrdersResponse{Orders: []response.OrderResponse{{ID: "1"}}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid time range",
+			query:          "?from=yesterday",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "limit out of range",
+			query:          "?limit=1000",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid product id",
+			query:          "?productId=abc",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "service error",
+			mockErr:        errors.ErrDatabaseError,
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/orders"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			mockService := new(MockOrderService)
+			mockService.On("FindAll", mock.Anything).Return(tt.mockRes, tt.mockErr)
+			handler := NewOrderHandler(mockService)
+			handler.ListOrders(w, req)
+
+			resp := w.Result()
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestUpdateOrderStatus(t *testing.T) {
+	const orderID = "0b6c2f4e-3f7e-4d39-9a55-7a3c1f1e2d10"
+	tests := []struct {
+		name           string
+		body           string
+		reason         string
+		mockRes        *response.OrderResponse
+		mockErr        error
+		expectedStatus int
+	}{
+		{
+			name:           "cancel without reason",
+			mockRes:        &response.OrderResponse{ID: orderID, Status: "cancelled"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "cancel with reason",
+			body:           `{"reason": "customer request"}`,
+			reason:         "customer request",
+			mockRes:        &response.OrderResponse{ID: orderID, Status: "cancelled"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid body",
+			body:           "{invalid-json}",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid transition",
+			mockErr:        errors.ErrInvalidTransition,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "order not found",
+			mockErr:        errors.ErrOrderNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := chi.NewRouteContext()
+			ctx.URLParams.Add("orderID", orderID)
+			req := httptest.NewRequest(http.MethodPost, "/orders/"+orderID+"/cancel", bytes.NewReader([]byte(tt.body)))
+			req = req.WithContext(context.WithValue(context.Background(), chi.RouteCtxKey, ctx))
+			w := httptest.NewRecorder()
+
+			mockService := new(MockOrderService)
+			mockService.On("UpdateStatus", orderID, db.OrderStatusCancelled, tt.reason).Return(tt.mockRes, tt.mockErr)
+			handler := NewOrderHandler(mockService)
+			handler.UpdateStatus(db.OrderStatusCancelled)(w, req)
+
+			resp := w.Result()
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+}
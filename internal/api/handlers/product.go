@@ -1,27 +1,54 @@
 package handlers
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
 	"github.com/malakagl/go-template/pkg/errors"
 	"github.com/malakagl/go-template/pkg/log"
+	"github.com/malakagl/go-template/pkg/models/dto/request"
 	"github.com/malakagl/go-template/pkg/models/dto/response"
 	"github.com/malakagl/go-template/pkg/services"
 	"github.com/malakagl/go-template/pkg/util"
 )
 
 type ProductHandler struct {
-	service services.IProductService
+	service   services.IProductService
+	validator *validator.Validate
 }
 
 func NewProductHandler(s services.IProductService) *ProductHandler {
-	return &ProductHandler{service: s}
+	return &ProductHandler{
+		service:   s,
+		validator: validator.New(),
+	}
 }
 
 func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	products, err := h.service.FindAll(ctx)
+	filter, err := parseProductFilter(r.URL.Query())
+	if err != nil {
+		log.WithCtx(ctx).Warn().Msgf("Invalid product filter: %v", err)
+		response.Error(w, http.StatusBadRequest, "Invalid request data", err.Error())
+		return
+	}
+
+	if err := h.validator.Struct(filter); err != nil {
+		validationError(w, err)
+		return
+	}
+
+	products, err := h.service.FindAll(ctx, filter)
+	if errors.Is(err, errors.ErrInvalidCursor) {
+		response.Error(w, http.StatusBadRequest, "Invalid request data", err.Error())
+		return
+	}
+
 	if err != nil && !errors.Is(err, errors.ErrProductNotFound) {
 		log.WithCtx(ctx).Error().Msgf("Error fetching products: %v", err)
 		response.Error(w, http.StatusInternalServerError, "Error fetching products", "Error fetching products")
@@ -56,3 +83,135 @@ func (h *ProductHandler) GetProductByID(w http.ResponseWriter, r *http.Request)
 
 	response.Success(w, http.StatusOK, product)
 }
+
+func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	req, ok := h.decodeProductRequest(w, r)
+	if !ok {
+		return
+	}
+
+	product, err := h.service.Create(ctx, req)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error creating product: %v", err)
+		code, msg := util.MapErrorToHTTP(err)
+		response.Error(w, code, msg, err.Error())
+		return
+	}
+
+	response.Success(w, http.StatusCreated, product)
+}
+
+func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	productID, ok := productIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	req, ok := h.decodeProductRequest(w, r)
+	if !ok {
+		return
+	}
+
+	product, err := h.service.Update(ctx, productID, req)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error updating product %d: %v", productID, err)
+		code, msg := util.MapErrorToHTTP(err)
+		response.Error(w, code, msg, err.Error())
+		return
+	}
+
+	response.Success(w, http.StatusOK, product)
+}
+
+func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	productID, ok := productIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(ctx, productID); err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error deleting product %d: %v", productID, err)
+		code, msg := util.MapErrorToHTTP(err)
+		response.Error(w, code, msg, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ProductHandler) RestoreProduct(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	productID, ok := productIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	product, err := h.service.Restore(ctx, productID)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error restoring product %d: %v", productID, err)
+		code, msg := util.MapErrorToHTTP(err)
+		response.Error(w, code, msg, err.Error())
+		return
+	}
+
+	response.Success(w, http.StatusOK, product)
+}
+
+func (h *ProductHandler) decodeProductRequest(w http.ResponseWriter, r *http.Request) (*request.ProductRequest, bool) {
+	var req request.ProductRequest
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.WithCtx(r.Context()).Error().Msgf("Error decoding request body: %v", err)
+		response.Error(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return nil, false
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		validationError(w, err)
+		return nil, false
+	}
+
+	return &req, true
+}
+
+func productIDParam(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	pID := chi.URLParam(r, "productID")
+	productID, err := util.StringToUint(pID)
+	if err != nil || productID == 0 {
+		log.WithCtx(r.Context()).Warn().Msgf("Invalid product ID: %s", pID)
+		response.Error(w, http.StatusBadRequest, "Invalid product ID", "Invalid product ID")
+		return 0, false
+	}
+
+	return productID, true
+}
+
+const defaultProductPageSize = 20
+
+// parseProductFilter reads category, minPrice, maxPrice (minor units), search, sort, limit and cursor.
+func parseProductFilter(q url.Values) (*request.ProductFilter, error) {
+	f := &request.ProductFilter{
+		Category: q.Get("category"),
+		Search:   q.Get("search"),
+		Sort:     q.Get("sort"),
+		Cursor:   q.Get("cursor"),
+		Limit:    defaultProductPageSize,
+	}
+	var err error
+	if f.MinPrice, err = parseIntParam(q, "minPrice"); err != nil {
+		return nil, err
+	}
+	if f.MaxPrice, err = parseIntParam(q, "maxPrice"); err != nil {
+		return nil, err
+	}
+	if v := q.Get("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("invalid limit: %s", v)
+		}
+	}
+
+	return f, nil
+}
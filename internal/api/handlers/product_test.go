@@ -5,10 +5,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/malakagl/go-template/pkg/errors"
+	"github.com/malakagl/go-template/pkg/models/dto/request"
 	"github.com/malakagl/go-template/pkg/models/dto/response"
 	"github.com/stretchr/testify/mock"
 )
@@ -18,8 +20,8 @@ type MockProductService struct {
 	mock.Mock
 }
 
-func (m *MockProductService) FindAll(_ context.Context) (*response.ProductsResponse, error) {
-	args := m.Called()
+func (m *MockProductService) FindAll(_ context.Context, filter *request.ProductFilter) (*response.ProductsResponse, error) {
+	args := m.Called(filter)
 	return args.Get(0).(*response.ProductsResponse), args.Error(1)
 }
 
@@ -28,9 +30,30 @@ func (m *MockProductService) FindByID(_ context.Context, id uint) (*response.Pro
 	return args.Get(0).(*response.ProductResponse), args.Error(1)
 }
 
+func (m *MockProductService) Create(_ context.Context, req *request.ProductRequest) (*response.ProductResponse, error) {
+	args := m.Called(req)
+	return args.Get(0).(*response.ProductResponse), args.Error(1)
+}
+
+func (m *MockProductService) Update(_ context.Context, id uint, req *request.ProductRequest) (*response.ProductResponse, error) {
+	args := m.Called(id, req)
+	return args.Get(0).(*response.ProductResponse), args.Error(1)
+}
+
+func (m *MockProductService) Delete(_ context.Context, id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockProductService) Restore(_ context.Context, id uint) (*response.ProductResponse, error) {
+	args := m.Called(id)
+	return args.Get(0).(*response.ProductResponse), args.Error(1)
+}
+
 func TestListProducts(t *testing.T) {
 	tests := []struct {
 		name           string
+		query          string
 		mockRes        *response.ProductsResponse
 		mockErr        error
 		expectedStatus int
@@ -40,6 +63,33 @@ func TestListProducts(t *testing.T) {
 			mockRes:        &response.ProductsResponse{Products: []response.Product{{ID: "1"}}},
 			expectedStatus: http.StatusOK,
 		},
+		{
+			name:           "filtered and sorted page",
+			query:          "?category=Waffle&minPrice=100&maxPrice=1050&search=ber&sort=-price&limit=2",
+			mockRes:        &response.ProductsResponse{Products: []response.Product{{ID: "1"}}, NextCursor: "eyJpZCI6MX0"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid sort",
+			query:          "?sort=category",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "limit above maximum",
+			query:          "?limit=101",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "price in major units",
+			query:          "?minPrice=10.5",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "cursor for another sort",
+			query:          "?cursor=eyJpZCI6MX0",
+			mockErr:        errors.ErrInvalidCursor,
+			expectedStatus: http.StatusBadRequest,
+		},
 		{
 			name:           "error response",
 			mockErr:        errors.New("some error"),
@@ -53,12 +103,12 @@ func TestListProducts(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest(http.MethodGet, "/products", nil)
+			req := httptest.NewRequest(http.MethodGet, "/products"+tt.query, nil)
 			w := httptest.NewRecorder()
 
 			mockService := new(MockProductService)
 			handler := NewProductHandler(mockService)
-			mockService.On("FindAll").Return(tt.mockRes, tt.mockErr)
+			mockService.On("FindAll", mock.Anything).Return(tt.mockRes, tt.mockErr)
 			handler.ListProducts(w, req)
 
 			resp := w.Result()
@@ -122,3 +172,119 @@ func TestGetProductByID(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateProduct(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		mockRes        *response.ProductResponse
+		mockErr        error
+		expectedStatus int
+	}{
+		{
+			name:           "successful request",
+			body:           `{"name":"Waffle","price":{"amount":650,"currency":"USD"},"category":"Waffle","image":{"thumbnail":"t","mobile":"m","tablet":"t","desktop":"d"}}`,
+			mockRes:        &response.ProductResponse{ID: "1"},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "missing name",
+			body:           `{"price":{"amount":650,"currency":"USD"},"category":"Waffle"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown currency",
+			body:           `{"name":"Waffle","price":{"amount":650,"currency":"XYZ"},"category":"Waffle"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "zero price",
+			body:           `{"name":"Waffle","price":{"amount":0,"currency":"USD"},"category":"Waffle"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "negative price",
+			body:           `{"name":"Waffle","price":{"amount":-650,"currency":"USD"},"category":"Waffle"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "price without currency",
+			body:           `{"name":"Waffle","price":{"amount":650},"category":"Waffle"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "incomplete image",
+			body:           `{"name":"Waffle","price":{"amount":650,"currency":"USD"},"category":"Waffle","image":{"thumbnail":"t"}}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "database error",
+			body:           `{"name":"Waffle","price":{"amount":650,"currency":"USD"},"category":"Waffle"}`,
+			mockErr:        errors.ErrDatabaseError,
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/products", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			mockService := new(MockProductService)
+			mockService.On("Create", mock.Anything).Return(tt.mockRes, tt.mockErr)
+
+			handler := NewProductHandler(mockService)
+			handler.CreateProduct(w, req)
+
+			resp := w.Result()
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestDeleteProduct(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		mockErr        error
+		expectedStatus int
+	}{
+		{
+			name:           "successful request",
+			id:             "1",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "invalid product id",
+			id:             "abc",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "product not found",
+			id:             "1",
+			mockErr:        errors.ErrProductNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := chi.NewRouteContext()
+			ctx.URLParams.Add("productID", tt.id)
+			req := httptest.NewRequest(http.MethodDelete, "/admin/products/"+tt.id, nil)
+			req = req.WithContext(context.WithValue(context.Background(), chi.RouteCtxKey, ctx))
+			w := httptest.NewRecorder()
+
+			mockService := new(MockProductService)
+			mockService.On("Delete", uint(1)).Return(tt.mockErr)
+
+			handler := NewProductHandler(mockService)
+			handler.DeleteProduct(w, req)
+
+			resp := w.Result()
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+}
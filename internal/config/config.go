@@ -15,13 +15,56 @@ type Config struct {
 	Logging    LoggingConfig    `yaml:"logging"`
 	CouponCode CouponCodeConfig `yaml:"couponCode"`
 	Telemetry  TelemetryConfig  `yaml:"telemetry"`
+	Auth       AuthConfig       `yaml:"auth"`
+}
+
+type AuthConfig struct {
+	HMAC HMACConfig `yaml:"hmac"`
+	JWT  JWTConfig  `yaml:"jwt"`
+}
+
+// JWTConfig configures bearer token authentication with RS256 or ES256 tokens of an OIDC identity provider
+type JWTConfig struct {
+	Enabled             bool          `yaml:"enabled"`
+	JWKS                string        `yaml:"jwks" validate:"required_if=Enabled true"` // file path or http(s) URL of the key set
+	Issuer              string        `yaml:"issuer" validate:"required_if=Enabled true"`
+	Audience            string        `yaml:"audience" validate:"required_if=Enabled true"`
+	ScopeClaim          string        `yaml:"scopeClaim"`          // claim holding scopes and roles, defaults to "scope"
+	ClockSkew           time.Duration `yaml:"clockSkew"`           // defaults to 1m
+	JWKSRefreshInterval time.Duration `yaml:"jwksRefreshInterval"` // defaults to 15m, key sets read from files are not refreshed
+}
+
+// HMACConfig configures signed requests, an alternative to sending the api key on every request
+type HMACConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// SecretKey is the base64 encoded AES-256 key sealing the signing secrets stored in the database
+	SecretKey    string        `yaml:"secretKey" validate:"required_if=Enabled true,omitempty,base64"`
+	MaxClockSkew time.Duration `yaml:"maxClockSkew"` // defaults to 5m
 }
 
 type TelemetryConfig struct {
 	Enabled     bool   `yaml:"enabled"`
-	ServiceName string `yaml:"serviceName"`
-	Host        string `yaml:"host"`
-	Port        int    `yaml:"port"`
+	ServiceName string `yaml:"serviceName"` // defaults to "go-template"
+	Environment string `yaml:"environment"` // deployment.environment of the spans, e.g. "k8s"
+	// Exporter sends spans to an OTLP collector over HTTP or gRPC, or writes them to stdout or FilePath for local debugging
+	Exporter string             `yaml:"exporter" validate:"omitempty,oneof=otlp-http otlp-grpc stdout file"` // defaults to "otlp-http"
+	Host     string             `yaml:"host" validate:"required_if=Exporter otlp-http,required_if=Exporter otlp-grpc"`
+	Port     int                `yaml:"port" validate:"required_if=Exporter otlp-http,required_if=Exporter otlp-grpc"`
+	Headers  map[string]string  `yaml:"headers"` // sent with every OTLP export, e.g. the auth header of a hosted collector
+	TLS      TelemetryTLSConfig `yaml:"tls"`
+	FilePath string             `yaml:"filePath" validate:"required_if=Exporter file"`
+	// SampleRatio is the share of traces started here that are sampled, traces continued from a caller follow its decision
+	SampleRatio *float64 `yaml:"sampleRatio" validate:"omitempty,min=0,max=1"` // defaults to 1
+	// ResourceAttributes are added to the service name, version and environment of the spans
+	ResourceAttributes map[string]string `yaml:"resourceAttributes"`
+}
+
+// TelemetryTLSConfig secures the connection to the OTLP collector, without it spans are sent in plain text
+type TelemetryTLSConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	CAFile   string `yaml:"caFile"`                                    // verifies the collector, defaults to the system roots
+	CertFile string `yaml:"certFile" validate:"required_with=KeyFile"` // client certificate for mutual TLS
+	KeyFile  string `yaml:"keyFile" validate:"required_with=CertFile"`
 }
 
 type ServerConfig struct {
@@ -33,7 +76,29 @@ type ServerConfig struct {
 	ReqLimitPerIP          int           `yaml:"reqLimitPerIP" validate:"min=1"`
 	ReqBurstPerIP          int           `yaml:"reqBurstPerIP" validate:"min=1"`
 	ReqRateWindow          time.Duration `yaml:"reqRateWindow" validate:"min=1m"`
+	IdempotencyKeyTTL      time.Duration `yaml:"idempotencyKeyTTL"`  // defaults to 24h
+	HealthCheckTimeout     time.Duration `yaml:"healthCheckTimeout"` // per readiness check, defaults to 2s
 	GracefulTimeout        time.Duration `yaml:"gracefulTimeout" validate:"required"`
+
+	// TrustedProxies are the CIDRs of the proxies whose ClientIPHeader is trusted
+	TrustedProxies []string `yaml:"trustedProxies" validate:"dive,cidr|ip"`
+	// ClientIPHeader is the forwarding header the proxies set the client IP in, the others are ignored
+	ClientIPHeader string `yaml:"clientIPHeader" validate:"omitempty,oneof=Forwarded X-Forwarded-For X-Real-IP"` // defaults to "X-Forwarded-For"
+	// IPAllowlist and IPDenylist restrict the client IPs of all authenticated requests, api keys can restrict them further
+	IPAllowlist []string `yaml:"ipAllowlist" validate:"dive,cidr|ip"`
+	IPDenylist  []string `yaml:"ipDenylist" validate:"dive,cidr|ip"`
+	// RateLimitBackend keeps rate limits per instance in memory, or in the database to share them between replicas
+	RateLimitBackend string `yaml:"rateLimitBackend" validate:"omitempty,oneof=memory database"` // defaults to "memory"
+	// RateLimitTiers are the limits api keys can be assigned by name, keys without a tier get reqLimitPerIP and reqBurstPerIP
+	RateLimitTiers map[string]RateLimitTier `yaml:"rateLimitTiers" validate:"dive"`
+}
+
+// RateLimitTier limits the requests of the api keys of a tier, a quota of 0 is unlimited
+type RateLimitTier struct {
+	RequestsPerSecond int   `yaml:"requestsPerSecond" validate:"min=1"`
+	Burst             int   `yaml:"burst" validate:"min=1"`
+	DailyQuota        int64 `yaml:"dailyQuota" validate:"min=0"`
+	MonthlyQuota      int64 `yaml:"monthlyQuota" validate:"min=0"`
 }
 
 type DatabaseConfig struct {
@@ -53,6 +118,7 @@ type DatabaseConfig struct {
 }
 
 type CouponCodeConfig struct {
+	Backend   string   `yaml:"backend" validate:"omitempty,oneof=file database memory"` // defaults to "file"
 	FilePaths []string `yaml:"filePaths"`
 }
 
@@ -82,5 +148,54 @@ func LoadConfig(path string) (*Config, error) {
 		cfg.Database.SSLMode = "disable"
 	}
 
+	if cfg.Server.IdempotencyKeyTTL == 0 {
+		cfg.Server.IdempotencyKeyTTL = 24 * time.Hour
+	}
+
+	if cfg.Server.HealthCheckTimeout == 0 {
+		cfg.Server.HealthCheckTimeout = 2 * time.Second
+	}
+
+	if cfg.Auth.HMAC.MaxClockSkew == 0 {
+		cfg.Auth.HMAC.MaxClockSkew = 5 * time.Minute
+	}
+
+	if cfg.Auth.JWT.ScopeClaim == "" {
+		cfg.Auth.JWT.ScopeClaim = "scope"
+	}
+
+	if cfg.Auth.JWT.ClockSkew == 0 {
+		cfg.Auth.JWT.ClockSkew = time.Minute
+	}
+
+	if cfg.Auth.JWT.JWKSRefreshInterval == 0 {
+		cfg.Auth.JWT.JWKSRefreshInterval = 15 * time.Minute
+	}
+
+	if cfg.Server.ClientIPHeader == "" {
+		cfg.Server.ClientIPHeader = "X-Forwarded-For"
+	}
+
+	if cfg.Server.RateLimitBackend == "" {
+		cfg.Server.RateLimitBackend = "memory"
+	}
+
+	if cfg.CouponCode.Backend == "" {
+		cfg.CouponCode.Backend = "file"
+	}
+
+	if cfg.Telemetry.ServiceName == "" {
+		cfg.Telemetry.ServiceName = "go-template"
+	}
+
+	if cfg.Telemetry.Exporter == "" {
+		cfg.Telemetry.Exporter = "otlp-http"
+	}
+
+	if cfg.Telemetry.SampleRatio == nil {
+		ratio := 1.0
+		cfg.Telemetry.SampleRatio = &ratio
+	}
+
 	return cfg, nil
 }
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestLoadConfig_ValidFile(t *testing.T) {
@@ -59,6 +60,25 @@ couponCode:
 	if cfg.Server.GracefulTimeout.String() != "30s" {
 		t.Errorf("expected server greacefulTimeout 30s, got %d", cfg.Server.Port)
 	}
+	if cfg.Server.IdempotencyKeyTTL.String() != "24h0m0s" {
+		t.Errorf("expected server idempotencyKeyTTL to default to 24h, got %s", cfg.Server.IdempotencyKeyTTL)
+	}
+	if cfg.Auth.HMAC.MaxClockSkew != 5*time.Minute {
+		t.Errorf("expected hmac maxClockSkew to default to 5m, got %s", cfg.Auth.HMAC.MaxClockSkew)
+	}
+	if cfg.Auth.JWT.ScopeClaim != "scope" || cfg.Auth.JWT.ClockSkew != time.Minute {
+		t.Errorf("expected jwt scopeClaim and clockSkew to default to scope and 1m, got %s and %s", cfg.Auth.JWT.ScopeClaim, cfg.Auth.JWT.ClockSkew)
+	}
+	if cfg.Server.HealthCheckTimeout.String() != "2s" {
+		t.Errorf("expected server healthCheckTimeout to default to 2s, got %s", cfg.Server.HealthCheckTimeout)
+	}
+
+	if cfg.Server.ClientIPHeader != "X-Forwarded-For" {
+		t.Errorf("expected clientIPHeader to default to X-Forwarded-For, got %s", cfg.Server.ClientIPHeader)
+	}
+	if cfg.Server.RateLimitBackend != "memory" {
+		t.Errorf("expected rateLimitBackend to default to memory, got %s", cfg.Server.RateLimitBackend)
+	}
 	if cfg.Database.Type != "postgres" {
 		t.Errorf("expected database type postgres, got %s", cfg.Database.Type)
 	}
@@ -80,6 +100,15 @@ couponCode:
 	if cfg.CouponCode.FilePaths[2] != "file3.gz" {
 		t.Errorf("expected third coupon code file to be 'file3.gz', got %s", cfg.CouponCode.FilePaths[2])
 	}
+	if cfg.CouponCode.Backend != "file" {
+		t.Errorf("expected coupon code backend to default to 'file', got %s", cfg.CouponCode.Backend)
+	}
+	if cfg.Telemetry.ServiceName != "go-template" || cfg.Telemetry.Exporter != "otlp-http" {
+		t.Errorf("expected telemetry serviceName and exporter to default to go-template and otlp-http, got %s and %s", cfg.Telemetry.ServiceName, cfg.Telemetry.Exporter)
+	}
+	if cfg.Telemetry.SampleRatio == nil || *cfg.Telemetry.SampleRatio != 1 {
+		t.Errorf("expected telemetry sampleRatio to default to 1, got %v", cfg.Telemetry.SampleRatio)
+	}
 }
 
 func TestLoadConfig_InvalidFile(t *testing.T) {
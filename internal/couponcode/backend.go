@@ -0,0 +1,169 @@
+package couponcode
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/malakagl/go-template/internal/config"
+	"github.com/malakagl/go-template/pkg/log"
+	"github.com/malakagl/go-template/pkg/otel"
+	"github.com/malakagl/go-template/pkg/repositories"
+	"gorm.io/gorm"
+)
+
+// Supported values for couponCode.backend.
+const (
+	BackendFile     = "file"
+	BackendDatabase = "database"
+	BackendMemory   = "memory"
+)
+
+const (
+	minCodeLength = 8
+	maxCodeLength = 10
+	minCodeFiles  = 2 // a code is valid when it appears in at least this many files
+)
+
+// CouponValidator reports whether a coupon code is valid, i.e. it appears in at least two coupon code files.
+type CouponValidator interface {
+	Validate(ctx context.Context, code string) (bool, error)
+}
+
+// NewValidator creates the CouponValidator selected by cfg.Backend. Defaults to the file backend.
+func NewValidator(ctx context.Context, cfg config.CouponCodeConfig, database *gorm.DB) (CouponValidator, error) {
+	switch cfg.Backend {
+	case BackendDatabase:
+		return NewDatabaseValidator(repositories.NewCouponCodeRepository(database)), nil
+	case BackendMemory:
+		return LoadMemoryValidator(ctx, cfg.FilePaths)
+	case BackendFile, "":
+		return NewFileValidator(cfg.FilePaths), nil
+	default:
+		return nil, fmt.Errorf("couponcode: unknown backend %q", cfg.Backend)
+	}
+}
+
+func validLength(code string) bool {
+	return len(code) >= minCodeLength && len(code) <= maxCodeLength
+}
+
+// FileValidator validates codes against the configured coupon code files, using their indexes once built.
+type FileValidator struct{}
+
+// NewFileValidator registers the coupon code files used by ValidateCouponCode.
+func NewFileValidator(filePaths []string) *FileValidator {
+	SetCouponCodeFiles(filePaths)
+	return &FileValidator{}
+}
+
+func (f *FileValidator) Validate(ctx context.Context, code string) (bool, error) {
+	return ValidateCouponCode(ctx, code)
+}
+
+// DatabaseValidator validates codes against the coupon_codes and files tables.
+type DatabaseValidator struct {
+	repo repositories.CouponCodeRepo
+}
+
+func NewDatabaseValidator(r repositories.CouponCodeRepo) *DatabaseValidator {
+	return &DatabaseValidator{repo: r}
+}
+
+func (d *DatabaseValidator) Validate(ctx context.Context, code string) (bool, error) {
+	if !validLength(code) {
+		log.WithCtx(ctx).Warn().Msgf("invalid coupon code length. code: %s", code)
+		return false, nil
+	}
+
+	count, err := d.repo.CountFilesByCode(ctx, code)
+	if err != nil {
+		return false, err
+	}
+
+	return count >= minCodeFiles, nil
+}
+
+type memoryEntry struct {
+	lastFile int
+	files    int
+}
+
+// MemoryValidator keeps every code and the number of files it appears in in memory.
+// Suited to small coupon bases and tests.
+type MemoryValidator struct {
+	mu    sync.RWMutex
+	codes map[string]memoryEntry
+	files int
+}
+
+// NewMemoryValidator creates a MemoryValidator with one set of codes per coupon code file.
+func NewMemoryValidator(files ...[]string) *MemoryValidator {
+	m := &MemoryValidator{codes: make(map[string]memoryEntry)}
+	for _, codes := range files {
+		m.AddFile(codes)
+	}
+
+	return m
+}
+
+// LoadMemoryValidator reads the given .gz or .txt coupon code files into a MemoryValidator.
+func LoadMemoryValidator(ctx context.Context, filePaths []string) (*MemoryValidator, error) {
+	ctx, span := otel.Tracer(ctx, "loadMemoryValidator")
+	defer span.End()
+
+	m := NewMemoryValidator()
+	for _, path := range filePaths {
+		reader, closeFn, err := openCodeFile(path)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+
+		var codes []string
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			if code := strings.TrimSpace(scanner.Text()); code != "" {
+				codes = append(codes, code)
+			}
+		}
+		closeFn()
+		if err := scanner.Err(); err != nil {
+			span.RecordError(err)
+			return nil, fmt.Errorf("couponcode: scanner error in %s: %w", path, err)
+		}
+
+		m.AddFile(codes)
+		log.WithCtx(ctx).Info().Msgf("loaded %d coupon codes from %s", len(codes), path)
+	}
+
+	return m, nil
+}
+
+// AddFile records the codes of one more coupon code file.
+func (m *MemoryValidator) AddFile(codes []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.files++
+	for _, code := range codes {
+		e := m.codes[code]
+		if e.lastFile != m.files {
+			e.lastFile = m.files
+			e.files++
+			m.codes[code] = e
+		}
+	}
+}
+
+func (m *MemoryValidator) Validate(_ context.Context, code string) (bool, error) {
+	if !validLength(code) {
+		return false, nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.codes[code].files >= minCodeFiles, nil
+}
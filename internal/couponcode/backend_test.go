@@ -0,0 +1,63 @@
+package couponcode
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/malakagl/go-template/internal/config"
+)
+
+func TestMemoryValidator(t *testing.T) {
+	v := NewMemoryValidator(
+		[]string{"ABC12345", "XYZ98765", "ABC12345"},
+		[]string{"ABC12345", "LMN11111"},
+		[]string{"QWE22222"},
+	)
+
+	tests := []struct {
+		code     string
+		expected bool
+	}{
+		{code: "ABC12345", expected: true},
+		{code: "XYZ98765", expected: false}, // repeated in a single file only
+		{code: "QWE22222", expected: false},
+		{code: "NOTFOUND", expected: false},
+		{code: "SHORT", expected: false},
+	}
+	for _, tt := range tests {
+		if isValid, err := v.Validate(t.Context(), tt.code); isValid != tt.expected || err != nil {
+			t.Errorf("Validate(%q) = %v, %v; expected %v", tt.code, isValid, err, tt.expected)
+		}
+	}
+}
+
+func TestNewValidator(t *testing.T) {
+	dir := t.TempDir()
+	file1 := filepath.Join(dir, "base1.txt")
+	file2 := filepath.Join(dir, "base2.gz")
+	writeCodes(t, file1, []string{"ABC12345", "XYZ98765"})
+	writeCodes(t, file2, []string{"ABC12345", "XYZ98765"})
+
+	v, err := NewValidator(t.Context(), config.CouponCodeConfig{Backend: BackendMemory, FilePaths: []string{file1, file2}}, nil)
+	if err != nil {
+		t.Fatalf("NewValidator(memory) failed: %v", err)
+	}
+	if _, ok := v.(*MemoryValidator); !ok {
+		t.Errorf("expected a memory validator, got %T", v)
+	}
+	if isValid, err := v.Validate(t.Context(), "XYZ98765"); !isValid || err != nil {
+		t.Error("Expected true and no error, got ", isValid, err)
+	}
+
+	v, err = NewValidator(t.Context(), config.CouponCodeConfig{FilePaths: []string{file1, file2}}, nil)
+	if err != nil {
+		t.Fatalf("NewValidator(default) failed: %v", err)
+	}
+	if _, ok := v.(*FileValidator); !ok {
+		t.Errorf("expected a file validator, got %T", v)
+	}
+
+	if _, err := NewValidator(t.Context(), config.CouponCodeConfig{Backend: "redis"}, nil); err == nil {
+		t.Error("expected unknown backend to fail")
+	}
+}
@@ -0,0 +1,408 @@
+package couponcode
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"container/heap"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/malakagl/go-template/pkg/log"
+	"github.com/malakagl/go-template/pkg/otel"
+)
+
+// Index file layout:
+//
+//	magic   [4]byte  "CCIX"
+//	version uint8
+//	width   uint8    record width in bytes
+//	_       [2]byte  reserved
+//	count   uint64   number of records (little endian)
+//	records [count][width]byte, sorted ascending, codes right padded with 0x00
+const (
+	indexMagic       = "CCIX"
+	indexVersion     = 1
+	indexHeaderSize  = 16
+	indexRecordSize  = 10 // coupon code is less than or equal 10 bytes
+	indexFileExt     = ".idx"
+	indexWriteBuffer = 1024 * 1024
+)
+
+// indexRunRecords is the number of records sorted in memory before spilling a run to disk (~80MB).
+var indexRunRecords = 8 * 1024 * 1024
+
+// Index is a sorted, fixed width, read only index of the coupon codes in a single file.
+// The backing file is memory mapped where supported so lookups do not copy the file into the heap.
+type Index struct {
+	path    string
+	data    []byte
+	records []byte
+	count   int
+	unmap   func() error
+}
+
+// IndexPath returns the index file path for a coupon code file. e.g. couponbase1.gz -> couponbase1.gz.idx
+// The whole file name is kept, files differing only in their extension get their own index.
+func IndexPath(input string) string {
+	return input + indexFileExt
+}
+
+// OpenIndex maps an index file built by BuildIndex into memory.
+func OpenIndex(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("couponcode: index open error: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("could not stat index %s: %w", path, err)
+	}
+
+	if stat.Size() < indexHeaderSize {
+		return nil, fmt.Errorf("couponcode: index %s is truncated", path)
+	}
+
+	data, unmap, err := mapFile(f, int(stat.Size()))
+	if err != nil {
+		return nil, fmt.Errorf("couponcode: index map error %s: %w", path, err)
+	}
+
+	idx := &Index{path: path, data: data, unmap: unmap}
+	if err := idx.parseHeader(); err != nil {
+		_ = idx.Close()
+		return nil, err
+	}
+
+	return idx, nil
+}
+
+func (idx *Index) parseHeader() error {
+	if string(idx.data[:4]) != indexMagic {
+		return fmt.Errorf("couponcode: %s is not a coupon code index", idx.path)
+	}
+
+	if idx.data[4] != indexVersion || idx.data[5] != indexRecordSize {
+		return fmt.Errorf("couponcode: unsupported index format in %s: version %d, width %d", idx.path, idx.data[4], idx.data[5])
+	}
+
+	count := binary.LittleEndian.Uint64(idx.data[8:indexHeaderSize])
+	if uint64(len(idx.data)-indexHeaderSize) != count*indexRecordSize {
+		return fmt.Errorf("couponcode: index %s size does not match record count %d", idx.path, count)
+	}
+
+	idx.count = int(count)
+	idx.records = idx.data[indexHeaderSize:]
+	return nil
+}
+
+// Len returns the number of distinct codes in the index.
+func (idx *Index) Len() int {
+	return idx.count
+}
+
+// Contains reports whether code is present in the index using a binary search.
+func (idx *Index) Contains(code string) bool {
+	if len(code) == 0 || len(code) > indexRecordSize {
+		return false
+	}
+
+	var key [indexRecordSize]byte
+	copy(key[:], code)
+	i := sort.Search(idx.count, func(i int) bool {
+		return bytes.Compare(idx.record(i), key[:]) >= 0
+	})
+
+	return i < idx.count && bytes.Equal(idx.record(i), key[:])
+}
+
+func (idx *Index) record(i int) []byte {
+	return idx.records[i*indexRecordSize : (i+1)*indexRecordSize]
+}
+
+// Close releases the memory mapping of the index.
+func (idx *Index) Close() error {
+	if idx.unmap == nil {
+		return nil
+	}
+
+	err := idx.unmap()
+	idx.unmap = nil
+	idx.data, idx.records, idx.count = nil, nil, 0
+	return err
+}
+
+// BuildIndex turns a .gz or .txt coupon code file into a sorted index next to it and returns the index path.
+// An existing index newer than the input is reused. Inputs larger than one in-memory run are sorted
+// into temporary run files and merged, so memory usage stays bounded for multi GB coupon bases.
+func BuildIndex(ctx context.Context, input string) (string, error) {
+	ctx, span := otel.Tracer(ctx, "buildIndex:"+filepath.Base(input))
+	defer span.End()
+
+	output := IndexPath(input)
+	if indexUpToDate(input, output) {
+		log.WithCtx(ctx).Info().Msgf("reusing coupon code index %s", output)
+		return output, nil
+	}
+
+	defer func(start time.Time) {
+		log.WithCtx(ctx).Info().Msgf("built coupon code index %s in %s", output, time.Since(start).String())
+	}(time.Now())
+
+	reader, closeFn, err := openCodeFile(input)
+	if err != nil {
+		span.RecordError(err)
+		return "", err
+	}
+	defer closeFn()
+
+	runs, last, err := writeSortedRuns(ctx, reader, filepath.Dir(output))
+	defer func() {
+		for _, r := range runs {
+			_ = os.Remove(r)
+		}
+	}()
+	if err != nil {
+		span.RecordError(err)
+		return "", err
+	}
+
+	tmp := output + ".tmp"
+	if err := writeIndex(tmp, runs, last); err != nil {
+		_ = os.Remove(tmp)
+		span.RecordError(err)
+		return "", err
+	}
+
+	if err := os.Rename(tmp, output); err != nil {
+		_ = os.Remove(tmp)
+		span.RecordError(err)
+		return "", fmt.Errorf("couponcode: could not move index into place %s: %w", output, err)
+	}
+
+	return output, nil
+}
+
+func indexUpToDate(input, output string) bool {
+	in, err := os.Stat(input)
+	if err != nil {
+		return false
+	}
+
+	out, err := os.Stat(output)
+	if err != nil {
+		return false
+	}
+
+	return !out.ModTime().Before(in.ModTime())
+}
+
+func openCodeFile(input string) (io.Reader, func(), error) {
+	ext := strings.ToLower(filepath.Ext(input))
+	if ext != ".gz" && ext != ".txt" {
+		return nil, nil, fmt.Errorf("couponcode: unsupported coupon code file %s", input)
+	}
+
+	f, err := os.Open(input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couponcode: couponcode file open error: %w", err)
+	}
+
+	if ext == ".txt" {
+		return f, func() { _ = f.Close() }, nil
+	}
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, fmt.Errorf("error creating gzip reader: %v", err)
+	}
+
+	return gr, func() { _ = gr.Close(); _ = f.Close() }, nil
+}
+
+// writeSortedRuns reads codes into fixed width records and sorts them in batches of indexRunRecords.
+// Full batches are spilled to run files in dir; the final (possibly only) batch is returned in memory.
+func writeSortedRuns(ctx context.Context, r io.Reader, dir string) ([]string, []byte, error) {
+	var runs []string
+	buf := make([]byte, 0, indexRunRecords*indexRecordSize)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		code := strings.TrimSpace(scanner.Text())
+		if code == "" || len(code) > indexRecordSize {
+			continue
+		}
+
+		var rec [indexRecordSize]byte
+		copy(rec[:], code)
+		buf = append(buf, rec[:]...)
+		if len(buf) < cap(buf) {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return runs, nil, err
+		}
+
+		run, err := spillRun(dir, buf)
+		if run != "" {
+			runs = append(runs, run)
+		}
+		if err != nil {
+			return runs, nil, err
+		}
+
+		buf = buf[:0]
+	}
+	if err := scanner.Err(); err != nil {
+		return runs, nil, fmt.Errorf("couponcode: scanner error: %w", err)
+	}
+
+	sort.Sort(recordSlice(buf))
+	return runs, buf, nil
+}
+
+func spillRun(dir string, buf []byte) (string, error) {
+	sort.Sort(recordSlice(buf))
+	f, err := os.CreateTemp(dir, "coupon-run-*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("couponcode: could not create run file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if _, err := f.Write(buf); err != nil {
+		return f.Name(), fmt.Errorf("couponcode: could not write run file %s: %w", f.Name(), err)
+	}
+
+	return f.Name(), nil
+}
+
+// writeIndex k-way merges the sorted run files and the in-memory tail into a single deduplicated index.
+func writeIndex(output string, runs []string, tail []byte) error {
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("couponcode: could not create index %s: %w", output, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	w := bufio.NewWriterSize(f, indexWriteBuffer)
+	if _, err := w.Write(make([]byte, indexHeaderSize)); err != nil {
+		return err
+	}
+
+	sources := make([]*recordSource, 0, len(runs)+1)
+	for _, run := range runs {
+		rf, err := os.Open(run)
+		if err != nil {
+			return fmt.Errorf("couponcode: could not open run file %s: %w", run, err)
+		}
+		defer func() { _ = rf.Close() }()
+		sources = append(sources, &recordSource{r: bufio.NewReaderSize(rf, indexWriteBuffer)})
+	}
+	sources = append(sources, &recordSource{r: bytes.NewReader(tail)})
+
+	h := make(recordHeap, 0, len(sources))
+	for _, s := range sources {
+		ok, err := s.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			h = append(h, s)
+		}
+	}
+	heap.Init(&h)
+
+	var count uint64
+	var prev [indexRecordSize]byte
+	for h.Len() > 0 {
+		s := h[0]
+		if count == 0 || s.cur != prev {
+			if _, err := w.Write(s.cur[:]); err != nil {
+				return err
+			}
+			prev = s.cur
+			count++
+		}
+
+		ok, err := s.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	header := make([]byte, indexHeaderSize)
+	copy(header, indexMagic)
+	header[4] = indexVersion
+	header[5] = indexRecordSize
+	binary.LittleEndian.PutUint64(header[8:], count)
+	if _, err := f.WriteAt(header, 0); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+// recordSlice sorts fixed width records in place.
+type recordSlice []byte
+
+func (s recordSlice) Len() int { return len(s) / indexRecordSize }
+func (s recordSlice) Less(i, j int) bool {
+	return bytes.Compare(s[i*indexRecordSize:(i+1)*indexRecordSize], s[j*indexRecordSize:(j+1)*indexRecordSize]) < 0
+}
+func (s recordSlice) Swap(i, j int) {
+	var tmp [indexRecordSize]byte
+	a, b := s[i*indexRecordSize:(i+1)*indexRecordSize], s[j*indexRecordSize:(j+1)*indexRecordSize]
+	copy(tmp[:], a)
+	copy(a, b)
+	copy(b, tmp[:])
+}
+
+type recordSource struct {
+	r   io.Reader
+	cur [indexRecordSize]byte
+}
+
+func (s *recordSource) next() (bool, error) {
+	if _, err := io.ReadFull(s.r, s.cur[:]); err != nil {
+		if err == io.EOF {
+			return false, nil
+		}
+		return false, fmt.Errorf("couponcode: could not read run record: %w", err)
+	}
+
+	return true, nil
+}
+
+type recordHeap []*recordSource
+
+func (h recordHeap) Len() int           { return len(h) }
+func (h recordHeap) Less(i, j int) bool { return bytes.Compare(h[i].cur[:], h[j].cur[:]) < 0 }
+func (h recordHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *recordHeap) Push(x any)        { *h = append(*h, x.(*recordSource)) }
+func (h *recordHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
@@ -0,0 +1,177 @@
+package couponcode
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeCodes(t *testing.T, path string, lines []string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create %s: %v", path, err)
+	}
+	defer f.Close()
+
+	var w interface{ Write([]byte) (int, error) } = f
+	if filepath.Ext(path) == ".gz" {
+		gw := gzip.NewWriter(f)
+		defer gw.Close()
+		w = gw
+	}
+
+	for _, line := range lines {
+		if _, err := w.Write([]byte(line + "\n")); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+}
+
+func TestBuildIndex(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		file string
+	}{
+		{name: "text file", file: "codes.txt"},
+		{name: "gzip file", file: "codes.gz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := filepath.Join(dir, tt.file)
+			writeCodes(t, input, []string{"XYZ98765", " ABC12345 ", "", "ABC12345", "TOOLONGCODE1", "ABC1234"})
+
+			output, err := BuildIndex(t.Context(), input)
+			if err != nil {
+				t.Fatalf("BuildIndex(%q) failed: %v", input, err)
+			}
+			if output != input+".idx" {
+				t.Errorf("unexpected index path %q", output)
+			}
+
+			idx, err := OpenIndex(output)
+			if err != nil {
+				t.Fatalf("OpenIndex(%q) failed: %v", output, err)
+			}
+			defer idx.Close()
+
+			if idx.Len() != 3 {
+				t.Errorf("expected 3 distinct codes, got %d", idx.Len())
+			}
+			for _, code := range []string{"ABC12345", "XYZ98765", "ABC1234"} {
+				if !idx.Contains(code) {
+					t.Errorf("expected index to contain %q", code)
+				}
+			}
+			for _, code := range []string{"ABC123", "ABC123456", "TOOLONGCODE1", "NOTFOUND", ""} {
+				if idx.Contains(code) {
+					t.Errorf("expected index not to contain %q", code)
+				}
+			}
+			_ = os.Remove(output)
+		})
+	}
+}
+
+func TestBuildIndex_MergesRuns(t *testing.T) {
+	defer func(n int) { indexRunRecords = n }(indexRunRecords)
+	indexRunRecords = 2
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "codes.txt")
+	writeCodes(t, input, []string{"CODE0005", "CODE0001", "CODE0004", "CODE0001", "CODE0003", "CODE0002", "CODE0005"})
+
+	output, err := BuildIndex(t.Context(), input)
+	if err != nil {
+		t.Fatalf("BuildIndex(%q) failed: %v", input, err)
+	}
+
+	idx, err := OpenIndex(output)
+	if err != nil {
+		t.Fatalf("OpenIndex(%q) failed: %v", output, err)
+	}
+	defer idx.Close()
+
+	if idx.Len() != 5 {
+		t.Errorf("expected 5 distinct codes, got %d", idx.Len())
+	}
+	for i := 1; i < idx.Len(); i++ {
+		if string(idx.record(i-1)) >= string(idx.record(i)) {
+			t.Errorf("records %d and %d are not strictly ascending", i-1, i)
+		}
+	}
+
+	runs, _ := filepath.Glob(filepath.Join(dir, "coupon-run-*"))
+	if len(runs) != 0 {
+		t.Errorf("expected run files to be removed, found %v", runs)
+	}
+}
+
+func TestSetupCouponCodeFiles_SameBaseName(t *testing.T) {
+	dir := t.TempDir()
+	file1 := filepath.Join(dir, "codes.gz")
+	file2 := filepath.Join(dir, "codes.txt")
+	writeCodes(t, file1, []string{"ABC12345", "XYZ98765"})
+	writeCodes(t, file2, []string{"ABC12345", "LMN11111"})
+
+	SetCouponCodeFiles([]string{file1, file2})
+	InitCache(10)
+	if err := SetupCouponCodeFiles(t.Context(), []string{file1, file2}); err != nil {
+		t.Fatalf("SetupCouponCodeFiles failed: %v", err)
+	}
+
+	if couponCodeFiles[0] == couponCodeFiles[1] {
+		t.Fatalf("expected each file to get its own index, got %v", couponCodeFiles)
+	}
+	if isValid, err := ValidateCouponCode(t.Context(), "ABC12345"); !isValid || err != nil {
+		t.Error("Expected true and no error, got ", isValid, err)
+	}
+	if isValid, err := ValidateCouponCode(t.Context(), "XYZ98765"); isValid || err != nil {
+		t.Error("Expected false and no error, got ", isValid, err)
+	}
+}
+
+func TestOpenIndex_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.idx")
+	if err := os.WriteFile(path, []byte("NOTANINDEXFILE!!"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenIndex(path); err == nil {
+		t.Errorf("expected OpenIndex(%q) to fail", path)
+	}
+}
+
+func TestSetupCouponCodeFiles_UsesIndexes(t *testing.T) {
+	dir := t.TempDir()
+	file1 := filepath.Join(dir, "base1.gz")
+	file2 := filepath.Join(dir, "base2.txt")
+	writeCodes(t, file1, []string{"ABC12345", "XYZ98765"})
+	writeCodes(t, file2, []string{"ABC12345", "LMN11111"})
+
+	configured := []string{file1, file2}
+	SetCouponCodeFiles(configured)
+	InitCache(10)
+	if err := SetupCouponCodeFiles(t.Context(), []string{file1, file2}); err != nil {
+		t.Fatalf("SetupCouponCodeFiles failed: %v", err)
+	}
+
+	if configured[0] != file1 || configured[1] != file2 {
+		t.Errorf("expected the configured files to be kept, got %v", configured)
+	}
+
+	for _, f := range couponCodeFiles {
+		if filepath.Ext(f) != indexFileExt {
+			t.Errorf("expected coupon code file %s to be replaced by its index", f)
+		}
+	}
+
+	if isValid, err := ValidateCouponCode(t.Context(), "ABC12345"); !isValid || err != nil {
+		t.Error("Expected true and no error, got ", isValid, err)
+	}
+	if isValid, err := ValidateCouponCode(t.Context(), "LMN11111"); isValid || err != nil {
+		t.Error("Expected false and no error, got ", isValid, err)
+	}
+}
@@ -3,6 +3,8 @@ package couponcode
 import (
 	"compress/gzip"
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -14,10 +16,19 @@ import (
 	"github.com/malakagl/go-template/pkg/otel"
 )
 
-// SetupCouponCodeFiles processes a list of gzip files, unzipping each one into a corresponding text file.
-// experimental
-func SetupCouponCodeFiles(ctx context.Context, filePaths []string) error {
+var (
+	setupMu   sync.Mutex
+	setupDone bool
+	setupErr  error
+)
+
+// SetupCouponCodeFiles builds a sorted index for each coupon code file and switches validation over to it
+// once ready. Until then, ValidateCouponCode keeps scanning the original files.
+func SetupCouponCodeFiles(ctx context.Context, filePaths []string) (err error) {
 	defer func(start time.Time) {
+		setupMu.Lock()
+		setupDone, setupErr = true, err
+		setupMu.Unlock()
 		log.Info().Msgf("Coupon code files setup completed in %s", time.Since(start).String())
 	}(time.Now())
 
@@ -29,8 +40,12 @@ func SetupCouponCodeFiles(ctx context.Context, filePaths []string) error {
 			defer wg.Done()
 			spanCtx, span := otel.Tracer(ctx, filepath.Base(path))
 			defer span.End()
-			if err := UnZipGzipFile(spanCtx, path); err != nil {
-				log.Error().Msgf("error unzipping file %s: %v", path, err)
+			indexPath, err := BuildIndex(spanCtx, path)
+			if err == nil {
+				err = useIndex(path, indexPath)
+			}
+			if err != nil {
+				log.Error().Msgf("error indexing file %s: %v", path, err)
 				span.RecordError(err)
 				errCh <- err
 			}
@@ -48,6 +63,20 @@ func SetupCouponCodeFiles(ctx context.Context, filePaths []string) error {
 	return nil
 }
 
+// FilesReady reports whether SetupCouponCodeFiles completed. Validation works before, scanning the files is slow.
+func FilesReady(context.Context) error {
+	setupMu.Lock()
+	defer setupMu.Unlock()
+	switch {
+	case !setupDone:
+		return errors.New("coupon code files are being indexed")
+	case setupErr != nil:
+		return fmt.Errorf("indexing coupon code files failed: %w", setupErr)
+	}
+
+	return nil
+}
+
 func UnZipGzipFile(ctx context.Context, input string) error {
 	if !strings.HasSuffix(input, ".gz") {
 		log.Warn().Msgf("file %s does not end with .gz", input)
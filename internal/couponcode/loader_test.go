@@ -3,6 +3,7 @@ package couponcode
 import (
 	"compress/gzip"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -36,3 +37,29 @@ func TestUnZipGzipFile(t *testing.T) {
 		os.Remove(outputFile) // clean up
 	}
 }
+
+func TestFilesReady(t *testing.T) {
+	setupDone, setupErr = false, nil
+	defer func() { setupDone, setupErr = false, nil }()
+	if err := FilesReady(t.Context()); err == nil {
+		t.Error("expected files not to be ready before setup")
+	}
+
+	file := filepath.Join(t.TempDir(), "base1.txt")
+	writeCodes(t, file, []string{"ABC12345", "XYZ98765"})
+	defer SetCouponCodeFiles(nil)
+	SetCouponCodeFiles([]string{file})
+	if err := SetupCouponCodeFiles(t.Context(), []string{file}); err != nil {
+		t.Fatal(err)
+	}
+	if err := FilesReady(t.Context()); err != nil {
+		t.Errorf("expected files to be ready, got %v", err)
+	}
+
+	if err := SetupCouponCodeFiles(t.Context(), []string{filepath.Join(t.TempDir(), "missing.txt")}); err == nil {
+		t.Fatal("expected setup of a missing file to fail")
+	}
+	if err := FilesReady(t.Context()); err == nil {
+		t.Error("expected failed setup not to be ready")
+	}
+}
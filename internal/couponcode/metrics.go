@@ -0,0 +1,39 @@
+package couponcode
+
+import (
+	"context"
+	"time"
+
+	"github.com/malakagl/go-template/pkg/metrics"
+)
+
+// timedValidator records the duration of the validations of a CouponValidator by backend and result
+type timedValidator struct {
+	CouponValidator
+	backend string
+}
+
+// WithMetrics records the duration of the validations of v, see metrics.CouponValidationDuration
+func WithMetrics(v CouponValidator, backend string) CouponValidator {
+	if backend == "" {
+		backend = BackendFile
+	}
+
+	return timedValidator{CouponValidator: v, backend: backend}
+}
+
+func (t timedValidator) Validate(ctx context.Context, code string) (bool, error) {
+	start := time.Now()
+	valid, err := t.CouponValidator.Validate(ctx, code)
+
+	result := "invalid"
+	switch {
+	case err != nil:
+		result = "error"
+	case valid:
+		result = "valid"
+	}
+	metrics.CouponValidationDuration.WithLabelValues(t.backend, result).Observe(time.Since(start).Seconds())
+
+	return valid, err
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -16,6 +17,7 @@ import (
 	"github.com/malakagl/go-template/pkg/cache"
 	"github.com/malakagl/go-template/pkg/errors"
 	"github.com/malakagl/go-template/pkg/log"
+	"github.com/malakagl/go-template/pkg/metrics"
 	"github.com/malakagl/go-template/pkg/otel"
 )
 
@@ -23,16 +25,58 @@ var (
 	couponCodeFiles []string
 	rwMutex         sync.RWMutex
 	couponCodeCache *cache.LRUCache[bool]
+
+	indexMutex sync.Mutex
+	indexes    = make(map[string]*Index)
 )
 
 func InitCache(maxSize int) {
 	couponCodeCache = cache.NewLRUCache[bool](maxSize, time.Hour)
+	metrics.RegisterCache("coupon_code", couponCodeCache)
 }
 
+// SetCouponCodeFiles sets the files codes are validated against. f is copied, files replaced by their
+// indexes do not change the caller's slice, e.g. the loaded config.
 func SetCouponCodeFiles(f []string) {
 	rwMutex.Lock()
 	defer rwMutex.Unlock()
-	couponCodeFiles = f
+	couponCodeFiles = slices.Clone(f)
+}
+
+// loadIndex returns the mapped index for path, opening it on first use.
+func loadIndex(path string) (*Index, error) {
+	indexMutex.Lock()
+	defer indexMutex.Unlock()
+	if idx, ok := indexes[path]; ok {
+		return idx, nil
+	}
+
+	idx, err := OpenIndex(path)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes[path] = idx
+	return idx, nil
+}
+
+// useIndex loads the index built for input and swaps it in place of input in the coupon code files,
+// so subsequent validations use index lookups instead of scanning input.
+func useIndex(input, indexPath string) error {
+	if _, err := loadIndex(indexPath); err != nil {
+		return err
+	}
+
+	rwMutex.Lock()
+	for i, file := range couponCodeFiles {
+		if input == file {
+			couponCodeFiles[i] = indexPath
+			break
+		}
+	}
+	rwMutex.Unlock()
+
+	return nil
 }
 
 func worker(ctx context.Context, path, code string, count *atomic.Int32, wg *sync.WaitGroup, cancel context.CancelFunc, errCh chan error) {
@@ -40,7 +84,17 @@ func worker(ctx context.Context, path, code string, count *atomic.Int32, wg *syn
 	ctx, span := otel.Tracer(ctx, "worker:"+path+":"+code)
 	defer span.End()
 
-	if strings.HasSuffix(strings.ToLower(filepath.Ext(path)), ".gz") {
+	if strings.ToLower(filepath.Ext(path)) == indexFileExt {
+		idx, err := loadIndex(path)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		if idx.Contains(code) && count.Add(1) >= 2 { // found in 2 files
+			cancel() // stop all other workers
+		}
+	} else if strings.HasSuffix(strings.ToLower(filepath.Ext(path)), ".gz") {
 		f, err := os.Open(path)
 		if err != nil {
 			errCh <- fmt.Errorf("couponcode: couponcode file open error: %w", err)
@@ -172,7 +226,7 @@ func ValidateCouponCode(ctx context.Context, code string) (bool, error) {
 		log.WithCtx(ctx).Debug().Msgf("validated coupon code in %s: %v", time.Since(start).String(), isValid)
 	}(time.Now())
 
-	if len(code) < 8 || len(code) > 10 {
+	if !validLength(code) {
 		log.WithCtx(ctx).Warn().Msgf("invalid coupon code length. code: %s", code)
 		return false, nil
 	}
@@ -186,10 +240,15 @@ func ValidateCouponCode(ctx context.Context, code string) (bool, error) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	rwMutex.RLock()
+	files := make([]string, len(couponCodeFiles))
+	copy(files, couponCodeFiles)
+	rwMutex.RUnlock()
+
 	var wg sync.WaitGroup
 	var count atomic.Int32
-	errChan := make(chan error, len(couponCodeFiles))
-	for _, f := range couponCodeFiles {
+	errChan := make(chan error, len(files))
+	for _, f := range files {
 		log.WithCtx(ctx).Debug().Msgf("checking file %s", f)
 		wg.Add(1)
 		go worker(ctx, f, code, &count, &wg, cancel, errChan)
@@ -23,11 +23,7 @@ func Connect(ctx context.Context, cfg *config.DatabaseConfig) (*gorm.DB, error)
 		return dbInstance, nil
 	}
 
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
-		cfg.Host, cfg.User, cfg.Password, cfg.Name, cfg.Port, cfg.SSLMode,
-	)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn(cfg)), &gorm.Config{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -51,3 +47,10 @@ func Connect(ctx context.Context, cfg *config.DatabaseConfig) (*gorm.DB, error)
 
 	return dbInstance, nil
 }
+
+func dsn(cfg *config.DatabaseConfig) string {
+	return fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
+		cfg.Host, cfg.User, cfg.Password, cfg.Name, cfg.Port, cfg.SSLMode,
+	)
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/malakagl/go-template/internal/config"
+	"github.com/malakagl/go-template/pkg/health"
+	"gorm.io/gorm"
+)
+
+// PingCheck reports whether the database accepts connections
+func PingCheck(db *gorm.DB) health.Check {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// MigrationsCheck reports whether the schema is at the latest migration of the migrations folder and not dirty
+func MigrationsCheck(db *gorm.DB, cfg *config.DatabaseConfig) health.Check {
+	return func(ctx context.Context) error {
+		latest, err := latestMigration(filepath.Join(cfg.MigrationsFolderPath, "migrations"))
+		if err != nil {
+			return err
+		}
+
+		var state struct {
+			Version uint
+			Dirty   bool
+		}
+		if err := db.WithContext(ctx).Raw("SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&state).Error; err != nil {
+			return err
+		}
+
+		switch {
+		case state.Dirty:
+			return fmt.Errorf("migration %d failed and left the schema dirty", state.Version)
+		case state.Version < latest:
+			return fmt.Errorf("schema at migration %d, expected %d", state.Version, latest)
+		}
+
+		return nil
+	}
+}
+
+// latestMigration is the highest version of the up migrations in dir, named <version>_<name>.up.sql
+func latestMigration(dir string) (uint, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint
+	for _, e := range entries {
+		version, _, found := strings.Cut(e.Name(), "_")
+		if !found || !strings.HasSuffix(e.Name(), ".up.sql") {
+			continue
+		}
+
+		if v, err := strconv.ParseUint(version, 10, 64); err == nil {
+			latest = max(latest, uint(v))
+		}
+	}
+
+	return latest, nil
+}
@@ -0,0 +1,30 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLatestMigration(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"000001_create_products.up.sql",
+		"000001_create_products.down.sql",
+		"000012_add_api_key_lifecycle.up.sql",
+		"000013_add_scopes.down.sql", // without up migration
+		"README.md",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if latest, err := latestMigration(dir); err != nil || latest != 12 {
+		t.Errorf("expected latest migration 12, got %d, %v", latest, err)
+	}
+
+	if _, err := latestMigration(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected a missing folder to fail")
+	}
+}
@@ -3,7 +3,6 @@ package database
 import (
 	"context"
 	"fmt"
-	"regexp"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
@@ -22,9 +21,7 @@ func RunMigrations(ctx context.Context, cfg *config.DatabaseConfig) error {
 		"postgres://%s:%s@%s:%d/%s?sslmode=%s",
 		cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.Name, cfg.SSLMode,
 	)
-	re := regexp.MustCompile(`:(.*?)@`)
-	safeDsn := re.ReplaceAllString(dsn, ":*****@")
-	log.WithCtx(ctx).Debug().Msgf("database migrations started: %v", safeDsn)
+	log.WithCtx(ctx).Debug().Msgf("database migrations started: %v", dsn) // the password is masked by the logger
 	migrationSource := fmt.Sprintf("file://%s/migrations", cfg.MigrationsFolderPath)
 	m, err := migrate.New(migrationSource, dsn)
 	if err != nil {
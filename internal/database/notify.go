@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/malakagl/go-template/internal/config"
+	"gorm.io/gorm"
+)
+
+// Notifier sends messages to all instances of the service with postgres NOTIFY
+type Notifier struct {
+	db  *gorm.DB
+	cfg *config.DatabaseConfig
+}
+
+func NewNotifier(db *gorm.DB, cfg *config.DatabaseConfig) *Notifier {
+	return &Notifier{db: db, cfg: cfg}
+}
+
+// Publish sends payload to the listeners of channel, including the ones of this instance.
+// Messages published in a transaction are only sent once it commits.
+func (n *Notifier) Publish(ctx context.Context, channel, payload string) error {
+	return n.db.WithContext(ctx).Exec("SELECT pg_notify(?, ?)", channel, payload).Error
+}
+
+// Listen calls notify with the payloads sent to channel until ctx is done or the connection fails.
+// ready is called once listening, payloads sent before that are missed. It holds a connection of its own,
+// so it does not take one of the pool of Connect for good.
+func (n *Notifier) Listen(ctx context.Context, channel string, ready func(), notify func(payload string)) error {
+	conn, err := pgx.Connect(ctx, dsn(n.cfg))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		_ = conn.Close(closeCtx)
+	}()
+
+	if _, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return err
+	}
+
+	ready()
+	for {
+		msg, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		notify(msg.Payload)
+	}
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/malakagl/go-template/pkg/models/db"
+)
+
+const (
+	authFailureThreshold = 5               // failed authentications of a client IP that are audited
+	authFailureWindow    = 5 * time.Minute // within which failures of a client IP are counted together
+)
+
+// auditLogger is the part of services.IAuditLogger the middleware records auth failures with
+type auditLogger interface {
+	Log(ctx context.Context, e *db.AuditEvent)
+}
+
+// authFailures counts the failed authentications of a client IP since the start of its window
+type authFailures struct {
+	count int
+	since time.Time
+}
+
+var (
+	audit          auditLogger
+	authFailuresMu sync.Mutex
+	authFailuresBy = make(map[string]*authFailures)
+)
+
+// InitAudit records clients failing to authenticate repeatedly, and forbidden requests, in the audit log
+// of a. Expired failure counts of client IPs are removed until ctx is done.
+func InitAudit(ctx context.Context, a auditLogger) {
+	audit = a
+	go cleanupAuthFailures(ctx)
+}
+
+func cleanupAuthFailures(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		authFailuresMu.Lock()
+		now := time.Now()
+		for ip, f := range authFailuresBy {
+			if now.Sub(f.since) >= authFailureWindow {
+				delete(authFailuresBy, ip)
+			}
+		}
+		authFailuresMu.Unlock()
+	}
+}
+
+// auditAuthFailure counts a request rejected with status against its client IP. Reaching the threshold
+// within the window is audited once, so a client guessing keys does not flood the audit log.
+func auditAuthFailure(r *http.Request, status int, now time.Time) {
+	if audit == nil {
+		return
+	}
+
+	ip := clientIP(r)
+	authFailuresMu.Lock()
+	f, ok := authFailuresBy[ip]
+	if !ok || now.Sub(f.since) >= authFailureWindow {
+		f = &authFailures{since: now}
+		authFailuresBy[ip] = f
+	}
+	f.count++
+	count := f.count
+	authFailuresMu.Unlock()
+
+	if count != authFailureThreshold {
+		return
+	}
+
+	audit.Log(r.Context(), &db.AuditEvent{
+		OccurredAt: now,
+		Actor:      claimedClientID(r),
+		Action:     db.AuditAuthRepeatedFailures,
+		Target:     r.Method + " " + r.URL.Path,
+		Outcome:    db.AuditDenied,
+		Detail:     fmt.Sprintf("%d failed authentications within %s, the last with status %d", count, authFailureWindow, status),
+		IP:         ip,
+	})
+}
+
+// auditForbidden records the request of clientID to route that its key is not authorized for
+func auditForbidden(r *http.Request, clientID, route string, now time.Time) {
+	if audit == nil {
+		return
+	}
+
+	audit.Log(r.Context(), &db.AuditEvent{
+		OccurredAt: now,
+		Actor:      clientID,
+		Action:     db.AuditAuthForbidden,
+		Target:     route,
+		Outcome:    db.AuditDenied,
+		Detail:     "no scope or grant of the route",
+		IP:         clientIP(r),
+	})
+}
+
+// claimedClientID is the client ID an unauthenticated request claims, without its secret
+func claimedClientID(r *http.Request) string {
+	if clientID := r.Header.Get(keyIDHeader); clientID != "" {
+		return clientID
+	}
+
+	clientID, _, _ := strings.Cut(r.Header.Get("x-api-key"), ".")
+	return clientID
+}
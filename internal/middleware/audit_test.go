@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/malakagl/go-template/pkg/models/db"
+)
+
+type fakeAuditLogger struct {
+	events []*db.AuditEvent
+}
+
+func (f *fakeAuditLogger) Log(_ context.Context, e *db.AuditEvent) {
+	f.events = append(f.events, e)
+}
+
+func TestAuditAuthFailure(t *testing.T) {
+	logger := &fakeAuditLogger{}
+	audit = logger
+	defer func() { audit, authFailuresBy = nil, make(map[string]*authFailures) }()
+
+	fail := func(remoteAddr string, at time.Time) {
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("x-api-key", "guessed.wrong-secret")
+		auditAuthFailure(req, http.StatusUnauthorized, at)
+	}
+
+	now := time.Now()
+	for i := range authFailureThreshold + 3 {
+		fail("203.0.113.7:1234", now.Add(time.Duration(i)*time.Second))
+	}
+	fail("203.0.113.8:1234", now)
+	if len(logger.events) != 1 {
+		t.Fatalf("expected 1 audit event, got %d", len(logger.events))
+	}
+
+	e := logger.events[0]
+	if e.Action != db.AuditAuthRepeatedFailures || e.Outcome != db.AuditDenied || e.Actor != "guessed" ||
+		e.IP != "203.0.113.7" || e.Target != "GET /orders" {
+		t.Errorf("unexpected audit event %+v", e)
+	}
+
+	// a new window is audited again
+	for i := range authFailureThreshold {
+		fail("203.0.113.7:1234", now.Add(authFailureWindow+time.Duration(i)*time.Second))
+	}
+	if len(logger.events) != 2 {
+		t.Errorf("expected 2 audit events, got %d", len(logger.events))
+	}
+}
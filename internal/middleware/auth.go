@@ -1,13 +1,18 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
-	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/malakagl/go-template/pkg/cache"
+	"github.com/malakagl/go-template/pkg/constants"
 	"github.com/malakagl/go-template/pkg/log"
+	"github.com/malakagl/go-template/pkg/metrics"
 	"github.com/malakagl/go-template/pkg/models/db"
 	"github.com/malakagl/go-template/pkg/models/dto/response"
 	"github.com/malakagl/go-template/pkg/repositories"
@@ -15,91 +20,317 @@ import (
 	"gorm.io/gorm"
 )
 
+// unauthenticatedPaths are served without credentials, for probes and metrics scrapers
+var unauthenticatedPaths = map[string]bool{
+	"/health":       true,
+	"/health/live":  true,
+	"/health/ready": true,
+	"/metrics":      true,
+}
+
+// Reasons of auth failures, see metrics.AuthFailures. Invalid credentials fail with "invalid_" and their kind.
+const (
+	authMissingCredentials = "missing_credentials"
+	authIPDenied           = "ip_denied"
+	authForbidden          = "forbidden"
+	authBadRequest         = "bad_request"
+	authError              = "error"
+)
+
+// Authentication resolves the request to its key, from a signed request (see authenticateSignature),
+// a bearer token (see authenticateBearer) or the x-api-key header. Routes authorize the key with Authorize.
+// Requests from client IPs the global or key ip rules do not permit are rejected with 403.
+// Clients failing to authenticate repeatedly are recorded in the audit log, see auditAuthFailure.
 func Authentication(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/health" { // skip auth
+		if unauthenticatedPaths[r.URL.Path] {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		apiKey := r.Header.Get("x-api-key")
-		if apiKey == "" {
-			log.WithCtx(r.Context()).Debug().Msgf("empty api key for %s %s", r.Method, r.RequestURI)
-			response.Error(w, http.StatusUnauthorized, "AuthError", http.StatusText(http.StatusUnauthorized))
+		if !permitGlobalIP(r) {
+			authFailed(r, authIPDenied, http.StatusForbidden)
+			response.Error(w, http.StatusForbidden, "AuthError", http.StatusText(http.StatusForbidden))
 			return
 		}
 
-		apiKeyCached, found := apiKeyCache.Get(apiKey)
-		if found {
-			for _, ep := range apiKeyCached.Endpoints {
-				if matchURI(ep.HTTPEndpoint, r.RequestURI) && ep.HTTPMethod == r.Method {
-					next.ServeHTTP(w, r)
-					return
-				}
-			}
-
-			log.WithCtx(r.Context()).Debug().Msgf("forbidden access with api key %s for %s %s", apiKey, r.Method, r.RequestURI)
-			response.Error(w, http.StatusUnauthorized, "AuthError", http.StatusText(http.StatusUnauthorized))
-			return
+		var key *db.APIKey
+		var status int
+		credentials := "api_key"
+		switch {
+		case r.Header.Get(signatureHeader) != "":
+			credentials = "signature"
+			key, status = authenticateSignature(r, time.Now())
+		case bearerToken(r) != "":
+			credentials = "bearer_token"
+			key, status = authenticateBearer(r, time.Now())
+		default:
+			key, status = authenticateAPIKey(r, time.Now())
 		}
 
-		parts := strings.SplitN(apiKey, ".", 2)
-		if len(parts) != 2 {
-			log.WithCtx(r.Context()).Debug().Msgf("tampered api key %s for %s %s", apiKey, r.Method, r.RequestURI)
-			response.Error(w, http.StatusUnauthorized, "AuthError", http.StatusText(http.StatusUnauthorized))
+		if key == nil {
+			authFailed(r, failureReason(r, credentials, status), status)
+			response.Error(w, status, "AuthError", http.StatusText(status))
 			return
 		}
 
-		clientID := parts[0]
-		apiKeyDetails, err := apiKeyRepo.GetEndPoints(clientID)
-		if err != nil {
-			log.WithCtx(r.Context()).Error().Err(err).Msgf("internal server error %s for %s %s", apiKey, r.Method, r.RequestURI)
-			response.Error(w, http.StatusInternalServerError, "InternalError", http.StatusText(http.StatusInternalServerError))
+		if !permitKeyIP(r, key) {
+			authFailed(r, authIPDenied, http.StatusForbidden)
+			response.Error(w, http.StatusForbidden, "AuthError", http.StatusText(http.StatusForbidden))
 			return
 		}
 
-		if err := bcrypt.CompareHashAndPassword([]byte(apiKeyDetails.APIKey), []byte(parts[1])); err != nil {
-			log.WithCtx(r.Context()).Debug().Msgf("incorrect api key %s for %s %s", apiKey, r.Method, r.RequestURI)
-			response.Error(w, http.StatusUnauthorized, "AuthError", http.StatusText(http.StatusUnauthorized))
-			return
-		}
+		next.ServeHTTP(w, withAPIKey(r, key))
+	})
+}
 
-		for _, ep := range apiKeyDetails.Endpoints {
-			if ep.HTTPEndpoint == r.RequestURI && ep.HTTPMethod == r.Method {
-				apiKeyCache.Put(apiKey, apiKeyDetails)
-				next.ServeHTTP(w, r)
-				return
-			}
-		}
+// authFailed counts a request rejected with status for reason, rejections of credentials are audited
+func authFailed(r *http.Request, reason string, status int) {
+	metrics.AuthFailures.WithLabelValues(reason).Inc()
+	if status == http.StatusUnauthorized || status == http.StatusForbidden {
+		auditAuthFailure(r, status, time.Now())
+	}
+}
 
-		log.WithCtx(r.Context()).Debug().Msgf("invalid api key %s for %s %s", apiKey, r.Method, r.RequestURI)
-		response.Error(w, http.StatusUnauthorized, "AuthError", http.StatusText(http.StatusUnauthorized))
-	})
+// failureReason is the reason a request with credentials of kind failed to authenticate with status
+func failureReason(r *http.Request, credentials string, status int) string {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return authError
+	case status != http.StatusUnauthorized:
+		return authBadRequest
+	case credentials == "api_key" && r.Header.Get("x-api-key") == "":
+		return authMissingCredentials
+	default:
+		return "invalid_" + credentials
+	}
+}
+
+// authenticateAPIKey resolves the x-api-key header to its key, or returns the status to fail the request with
+func authenticateAPIKey(r *http.Request, now time.Time) (*db.APIKey, int) {
+	apiKey := r.Header.Get("x-api-key")
+	if apiKey == "" {
+		log.WithCtx(r.Context()).Debug().Msgf("empty api key for %s %s", r.Method, r.RequestURI)
+		return nil, http.StatusUnauthorized
+	}
+
+	if key, found := cachedKey(apiKeyCache, apiKey, now); found {
+		return key, http.StatusOK
+	}
+
+	parts := strings.SplitN(apiKey, ".", 2)
+	if len(parts) != 2 {
+		log.WithCtx(r.Context()).Debug().Msgf("tampered api key for %s %s", r.Method, r.RequestURI)
+		return nil, http.StatusUnauthorized
+	}
+
+	generation := keyCacheGeneration()
+	apiKeyDetails, status := findAPIKey(r, parts[0])
+	if apiKeyDetails == nil {
+		return nil, status
+	}
+
+	validUntil, ok := authenticate(apiKeyDetails, parts[1], now)
+	if !ok {
+		log.WithCtx(r.Context()).Debug().Msgf("incorrect, revoked or expired api key of client %s for %s %s", parts[0], r.Method, r.RequestURI)
+		return nil, http.StatusUnauthorized
+	}
+
+	cacheKey(apiKeyCache, apiKey, &cachedAPIKey{key: apiKeyDetails, validUntil: validUntil}, generation)
+	return apiKeyDetails, http.StatusOK
 }
 
-func matchURI(ep, req string) bool {
-	re, err := regexp.Compile(ep)
+// findAPIKey loads the key of clientID with its active grants, or returns the status to fail the request with
+func findAPIKey(r *http.Request, clientID string) (*db.APIKey, int) {
+	key, err := apiKeyRepo.GetEndPoints(clientID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		log.WithCtx(r.Context()).Debug().Msgf("unknown client %s for %s %s", clientID, r.Method, r.RequestURI)
+		return nil, http.StatusUnauthorized
+	}
+
 	if err != nil {
-		return false
+		log.WithCtx(r.Context()).Error().Err(err).Msgf("internal server error for client %s for %s %s", clientID, r.Method, r.RequestURI)
+		return nil, http.StatusInternalServerError
+	}
+
+	return key, http.StatusOK
+}
+
+// authenticate checks secret against the current secret of key, and against the previous one during
+// a rotation grace period. It returns until when the secret stays valid, nil when it does not expire.
+func authenticate(key *db.APIKey, secret string, now time.Time) (*time.Time, bool) {
+	if !key.Active(now) {
+		return nil, false
 	}
 
-	return re.MatchString(req)
+	if bcrypt.CompareHashAndPassword([]byte(key.APIKey), []byte(secret)) == nil {
+		return key.ExpiresAt, true
+	}
+
+	if key.InGracePeriod(now) && bcrypt.CompareHashAndPassword([]byte(*key.PreviousAPIKey), []byte(secret)) == nil {
+		if key.ExpiresAt != nil && key.ExpiresAt.Before(*key.PreviousExpiresAt) {
+			return key.ExpiresAt, true
+		}
+
+		return key.PreviousExpiresAt, true
+	}
+
+	return nil, false
+}
+
+// withAPIKey stores the authenticated key and its client ID in the request context
+func withAPIKey(r *http.Request, key *db.APIKey) *http.Request {
+	ctx := context.WithValue(r.Context(), constants.APIKey, key)
+	return r.WithContext(context.WithValue(ctx, constants.ClientID, key.ClientID))
+}
+
+// cachedAPIKey is an authenticated key, validUntil is nil when the secret does not expire
+type cachedAPIKey struct {
+	key        *db.APIKey
+	validUntil *time.Time
 }
 
 var (
 	apiKeyRepo  *repositories.ApiKeyRepository
-	apiKeyCache *cache.LRUCache[*db.APIKey]
+	apiKeyCache *cache.LRUCache[*cachedAPIKey]
+	// signingKeyCache holds keys of signed requests by client ID, apart from apiKeyCache which is keyed by secret
+	signingKeyCache *cache.LRUCache[*cachedAPIKey]
 )
 
 func InitAuth(database *gorm.DB, cacheSize int, cacheTTL time.Duration) {
 	apiKeyRepo = repositories.NewApiKeyRepository(database)
-	apiKeyCache = cache.NewLRUCache[*db.APIKey](cacheSize, cacheTTL)
+	apiKeyCache = cache.NewLRUCache[*cachedAPIKey](cacheSize, cacheTTL)
+	signingKeyCache = cache.NewLRUCache[*cachedAPIKey](cacheSize, cacheTTL)
+	metrics.RegisterCache("api_key", apiKeyCache)
+	metrics.RegisterCache("signing_key", signingKeyCache)
 	go cleanupExpiredAPIKeys()
 }
 
+// apiKeyChangesChannel tells all instances which keys changed, the payload is the client ID
+const apiKeyChangesChannel = "api_key_changes"
+
+// apiKeyChanges sends and receives messages of all instances, see database.Notifier
+type apiKeyChanges interface {
+	Publish(ctx context.Context, channel, payload string) error
+	Listen(ctx context.Context, channel string, ready func(), notify func(payload string)) error
+}
+
+var (
+	keyChanges apiKeyChanges
+	// listeningForKeyChanges is set while changes of other instances are received, cached keys are not used otherwise
+	listeningForKeyChanges atomic.Bool
+
+	// keyCacheMu orders caching keys and dropping them, keyGeneration counts the drops
+	keyCacheMu    sync.Mutex
+	keyGeneration uint64
+)
+
+// WatchAPIKeyChanges drops the cached secrets of keys changed on any instance until ctx is done.
+// Caches are flushed whenever listening starts, so changes made while not listening are not missed.
+func WatchAPIKeyChanges(ctx context.Context, changes apiKeyChanges) {
+	keyChanges = changes
+	go func() {
+		for {
+			err := changes.Listen(ctx, apiKeyChangesChannel, func() {
+				flushAPIKeyCaches()
+				listeningForKeyChanges.Store(true)
+			}, dropCachedAPIKey)
+			listeningForKeyChanges.Store(false)
+			if ctx.Err() != nil {
+				return
+			}
+
+			log.Error().Err(err).Msg("listening for api key changes failed, cached keys are not used until it recovers")
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+		}
+	}()
+}
+
+// InvalidateAPIKey drops the cached secrets of clientID on all instances, so the next request is checked
+// against the database.
+func InvalidateAPIKey(clientID string) {
+	dropCachedAPIKey(clientID)
+	if keyChanges == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := keyChanges.Publish(ctx, apiKeyChangesChannel, clientID); err != nil {
+		log.Error().Err(err).Msgf("failed to publish the change of api key %s, other instances drop it once their cache TTL runs out", clientID)
+	}
+}
+
+// cachedKey returns the key cached as id, unless its secret expired or changes of other instances are not received
+func cachedKey(c *cache.LRUCache[*cachedAPIKey], id string, now time.Time) (*db.APIKey, bool) {
+	if keyChanges != nil && !listeningForKeyChanges.Load() {
+		return nil, false
+	}
+
+	cached, found := c.Get(id)
+	if !found {
+		return nil, false
+	}
+
+	if cached.validUntil != nil && !now.Before(*cached.validUntil) {
+		c.Remove(id)
+		return nil, false
+	}
+
+	return cached.key, true
+}
+
+// keyCacheGeneration is passed to cacheKey by requests loading a key from the database
+func keyCacheGeneration() uint64 {
+	keyCacheMu.Lock()
+	defer keyCacheMu.Unlock()
+	return keyGeneration
+}
+
+// cacheKey caches a key loaded at generation, unless keys were dropped since. The key may have been
+// read before a change that was dropped from the cache while it was loaded.
+func cacheKey(c *cache.LRUCache[*cachedAPIKey], id string, cached *cachedAPIKey, generation uint64) {
+	keyCacheMu.Lock()
+	defer keyCacheMu.Unlock()
+	if generation == keyGeneration {
+		c.Put(id, cached)
+	}
+}
+
+func dropCachedAPIKey(clientID string) {
+	if apiKeyCache == nil {
+		return
+	}
+
+	keyCacheMu.Lock()
+	defer keyCacheMu.Unlock()
+	keyGeneration++
+	apiKeyCache.RemoveFunc(func(_ string, cached *cachedAPIKey) bool {
+		return cached.key.ClientID == clientID
+	})
+	signingKeyCache.Remove(clientID)
+}
+
+func flushAPIKeyCaches() {
+	if apiKeyCache == nil {
+		return
+	}
+
+	keyCacheMu.Lock()
+	defer keyCacheMu.Unlock()
+	keyGeneration++
+	apiKeyCache.RemoveFunc(func(string, *cachedAPIKey) bool { return true })
+	signingKeyCache.RemoveFunc(func(string, *cachedAPIKey) bool { return true })
+}
+
 func cleanupExpiredAPIKeys() {
 	for {
 		time.Sleep(time.Minute)
 		apiKeyCache.RemoveExpired()
+		signingKeyCache.RemoveExpired()
 	}
 }
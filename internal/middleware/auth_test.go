@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/malakagl/go-template/pkg/cache"
+	"github.com/malakagl/go-template/pkg/models/db"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func hashSecret(t *testing.T, secret string) string {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(hash)
+}
+
+func TestAuthenticate(t *testing.T) {
+	now := time.Now()
+	past, soon, later := now.Add(-time.Minute), now.Add(time.Minute), now.Add(time.Hour)
+	previous := hashSecret(t, "old")
+	current := hashSecret(t, "new")
+
+	tests := []struct {
+		name       string
+		key        db.APIKey
+		secret     string
+		ok         bool
+		validUntil *time.Time
+	}{
+		{name: "current secret", key: db.APIKey{APIKey: current}, secret: "new", ok: true},
+		{name: "wrong secret", key: db.APIKey{APIKey: current}, secret: "old"},
+		{name: "revoked", key: db.APIKey{APIKey: current, RevokedAt: &past}, secret: "new"},
+		{name: "expired", key: db.APIKey{APIKey: current, ExpiresAt: &past}, secret: "new"},
+		{name: "expiring", key: db.APIKey{APIKey: current, ExpiresAt: &later}, secret: "new", ok: true, validUntil: &later},
+		{
+			name:       "previous secret in grace period",
+			key:        db.APIKey{APIKey: current, PreviousAPIKey: &previous, PreviousExpiresAt: &soon},
+			secret:     "old",
+			ok:         true,
+			validUntil: &soon,
+		},
+		{
+			name:   "previous secret after grace period",
+			key:    db.APIKey{APIKey: current, PreviousAPIKey: &previous, PreviousExpiresAt: &past},
+			secret: "old",
+		},
+		{
+			name:       "key expiring during grace period",
+			key:        db.APIKey{APIKey: current, ExpiresAt: &soon, PreviousAPIKey: &previous, PreviousExpiresAt: &later},
+			secret:     "old",
+			ok:         true,
+			validUntil: &soon,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validUntil, ok := authenticate(&tt.key, tt.secret, now)
+			if ok != tt.ok {
+				t.Fatalf("expected ok %v, got %v", tt.ok, ok)
+			}
+			if validUntil != tt.validUntil {
+				t.Errorf("expected valid until %v, got %v", tt.validUntil, validUntil)
+			}
+		})
+	}
+}
+
+func TestInvalidateAPIKey(t *testing.T) {
+	apiKeyCache = cache.NewLRUCache[*cachedAPIKey](10, time.Hour)
+	signingKeyCache = cache.NewLRUCache[*cachedAPIKey](10, time.Hour)
+	defer func() { apiKeyCache, signingKeyCache = nil, nil }()
+
+	apiKeyCache.Put("client-a.old", &cachedAPIKey{key: &db.APIKey{ClientID: "client-a"}})
+	apiKeyCache.Put("client-a.new", &cachedAPIKey{key: &db.APIKey{ClientID: "client-a"}})
+	apiKeyCache.Put("client-b.secret", &cachedAPIKey{key: &db.APIKey{ClientID: "client-b"}})
+	signingKeyCache.Put("client-a", &cachedAPIKey{key: &db.APIKey{ClientID: "client-a"}})
+
+	InvalidateAPIKey("client-a")
+	for _, k := range []string{"client-a.old", "client-a.new"} {
+		if _, found := apiKeyCache.Get(k); found {
+			t.Errorf("expected %s to be invalidated", k)
+		}
+	}
+	if _, found := signingKeyCache.Get("client-a"); found {
+		t.Error("expected signing key to be invalidated")
+	}
+	if _, found := apiKeyCache.Get("client-b.secret"); !found {
+		t.Error("expected other clients to stay cached")
+	}
+}
+
+// keyChangesBus implements apiKeyChanges for testing, like postgres it sends published payloads to the listener
+type keyChangesBus struct {
+	payloads  chan string
+	fail      chan error
+	published chan string
+}
+
+func (b *keyChangesBus) Publish(_ context.Context, _, payload string) error {
+	b.published <- payload
+	b.payloads <- payload
+	return nil
+}
+
+func (b *keyChangesBus) Listen(ctx context.Context, _ string, ready func(), notify func(string)) error {
+	ready()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-b.fail:
+			return err
+		case payload := <-b.payloads:
+			notify(payload)
+		}
+	}
+}
+
+func TestWatchAPIKeyChanges(t *testing.T) {
+	apiKeyCache = cache.NewLRUCache[*cachedAPIKey](10, time.Hour)
+	signingKeyCache = cache.NewLRUCache[*cachedAPIKey](10, time.Hour)
+	ctx, cancel := context.WithCancel(t.Context())
+	defer func() {
+		cancel()
+		apiKeyCache, signingKeyCache, keyChanges = nil, nil, nil
+		listeningForKeyChanges.Store(false)
+	}()
+
+	eventually := func(cond func() bool, msg string) {
+		t.Helper()
+		for deadline := time.Now().Add(time.Second); !cond(); time.Sleep(time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatal(msg)
+			}
+		}
+	}
+	cached := func(id string) bool {
+		_, found := cachedKey(apiKeyCache, id, time.Now())
+		return found
+	}
+
+	// cached before listening, the key may have changed meanwhile
+	apiKeyCache.Put("client-a.secret", &cachedAPIKey{key: &db.APIKey{ClientID: "client-a"}})
+	bus := &keyChangesBus{payloads: make(chan string, 10), fail: make(chan error), published: make(chan string, 10)}
+	WatchAPIKeyChanges(ctx, bus)
+	eventually(listeningForKeyChanges.Load, "expected to listen for key changes")
+	if _, found := apiKeyCache.Get("client-a.secret"); found {
+		t.Error("expected the caches to be flushed when listening starts")
+	}
+
+	generation := keyCacheGeneration()
+	cacheKey(apiKeyCache, "client-b.secret", &cachedAPIKey{key: &db.APIKey{ClientID: "client-b"}}, generation)
+	if !cached("client-b.secret") {
+		t.Fatal("expected key to be cached")
+	}
+
+	// changed on another instance
+	bus.payloads <- "client-b"
+	eventually(func() bool { return !cached("client-b.secret") }, "expected key changed on another instance to be dropped")
+
+	cacheKey(apiKeyCache, "client-b.secret", &cachedAPIKey{key: &db.APIKey{ClientID: "client-b"}}, generation)
+	if cached("client-b.secret") {
+		t.Error("expected key loaded before the change not to be cached")
+	}
+
+	InvalidateAPIKey("client-c")
+	if payload := <-bus.published; payload != "client-c" {
+		t.Errorf("expected the change of client-c to be published, got %s", payload)
+	}
+
+	cacheKey(apiKeyCache, "client-d.secret", &cachedAPIKey{key: &db.APIKey{ClientID: "client-d"}}, keyCacheGeneration())
+	bus.fail <- errors.New("connection reset")
+	eventually(func() bool { return !listeningForKeyChanges.Load() }, "expected to stop listening")
+	if cached("client-d.secret") {
+		t.Error("expected cached keys not to be used while changes are not received")
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/malakagl/go-template/pkg/constants"
+	"github.com/malakagl/go-template/pkg/log"
+	"github.com/malakagl/go-template/pkg/metrics"
+	"github.com/malakagl/go-template/pkg/models/db"
+	"github.com/malakagl/go-template/pkg/models/dto/response"
+	"github.com/malakagl/go-template/pkg/scopes"
+)
+
+var (
+	routeScopesMu sync.RWMutex
+	routeScopes   = make(map[string]string) // "METHOD pattern" to scope
+)
+
+// BindScope binds scope to the route registered for method and pattern
+func BindScope(method, pattern, scope string) {
+	routeScopesMu.Lock()
+	defer routeScopesMu.Unlock()
+	routeScopes[method+" "+pattern] = scope
+}
+
+// RouteScope returns the scope bound to the route of method and pattern, "" when it has none
+func RouteScope(method, pattern string) string {
+	routeScopesMu.RLock()
+	defer routeScopesMu.RUnlock()
+	return routeScopes[method+" "+pattern]
+}
+
+// Authorize lets the request through when its key holds the scope bound to the matched chi route,
+// or has an active grant of the route. It must run after routing, it is added to each route.
+// Forbidden requests are recorded in the audit log.
+func Authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, _ := r.Context().Value(constants.APIKey).(*db.APIKey)
+		if key == nil {
+			response.Error(w, http.StatusUnauthorized, "AuthError", http.StatusText(http.StatusUnauthorized))
+			return
+		}
+
+		pattern := chi.RouteContext(r.Context()).RoutePattern()
+		if !authorized(key, r.Method, pattern) {
+			log.WithCtx(r.Context()).Debug().Msgf("forbidden access of client %s to %s %s", key.ClientID, r.Method, pattern)
+			metrics.AuthFailures.WithLabelValues(authForbidden).Inc()
+			auditForbidden(r, key.ClientID, r.Method+" "+pattern, time.Now())
+			response.Error(w, http.StatusForbidden, "AuthError", http.StatusText(http.StatusForbidden))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorized reports whether key may call the route of method and pattern
+func authorized(key *db.APIKey, method, pattern string) bool {
+	if scope := RouteScope(method, pattern); scope != "" && scopes.Expand(scopes.Parse(key.Scopes)...).Has(scope) {
+		return true
+	}
+
+	// Endpoints only holds active grants, see ApiKeyRepository.GetEndPoints
+	for _, ep := range key.Endpoints {
+		if ep.HTTPMethod == method && ep.HTTPEndpoint == pattern {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/malakagl/go-template/pkg/models/db"
+	"github.com/malakagl/go-template/pkg/scopes"
+)
+
+func TestAuthorize(t *testing.T) {
+	ok := func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }
+	r := chi.NewRouter()
+	BindScope(http.MethodGet, "/products/{productID}", scopes.ProductsRead)
+	BindScope(http.MethodPost, "/orders/{orderID}/pay", scopes.OrdersWrite)
+	r.With(Authorize).Get("/products/{productID}", ok)
+	r.With(Authorize).Post("/orders/{orderID}/pay", ok)
+	r.With(Authorize).Get("/unscoped", ok)
+
+	logger := &fakeAuditLogger{}
+	audit = logger
+	defer func() { audit = nil }()
+
+	grant := db.Endpoint{HTTPMethod: http.MethodGet, HTTPEndpoint: "/products/{productID}"}
+	tests := []struct {
+		name   string
+		key    *db.APIKey
+		method string
+		path   string
+		status int
+		route  string // audited when forbidden
+	}{
+		{name: "no key", method: http.MethodGet, path: "/products/1", status: http.StatusUnauthorized},
+		{name: "scope", key: &db.APIKey{Scopes: "products:read"}, method: http.MethodGet, path: "/products/1", status: http.StatusOK},
+		{name: "role", key: &db.APIKey{Scopes: "customer"}, method: http.MethodPost, path: "/orders/abc/pay", status: http.StatusOK},
+		{name: "other scope", key: &db.APIKey{Scopes: "viewer"}, method: http.MethodPost, path: "/orders/abc/pay", status: http.StatusForbidden, route: "POST /orders/{orderID}/pay"},
+		{name: "grant", key: &db.APIKey{Endpoints: []db.Endpoint{grant}}, method: http.MethodGet, path: "/products/1", status: http.StatusOK},
+		{name: "grant of other method", key: &db.APIKey{Endpoints: []db.Endpoint{grant}}, method: http.MethodPost, path: "/orders/abc/pay", status: http.StatusForbidden, route: "POST /orders/{orderID}/pay"},
+		{name: "query string", key: &db.APIKey{Scopes: "products:read"}, method: http.MethodGet, path: "/products/1?fields=name", status: http.StatusOK},
+		{name: "route without scope", key: &db.APIKey{Scopes: "operator"}, method: http.MethodGet, path: "/unscoped", status: http.StatusForbidden, route: "GET /unscoped"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger.events = nil
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.key != nil {
+				tt.key.ClientID = "client"
+				req = withAPIKey(req, tt.key)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, w.Code)
+			}
+
+			if tt.status != http.StatusForbidden {
+				if len(logger.events) != 0 {
+					t.Errorf("expected no audit events, got %+v", logger.events)
+				}
+				return
+			}
+			if len(logger.events) != 1 {
+				t.Fatalf("expected the forbidden request to be audited, got %d events", len(logger.events))
+			}
+			if e := logger.events[0]; e.Action != db.AuditAuthForbidden || e.Outcome != db.AuditDenied || e.Actor != "client" ||
+				e.Target != tt.route {
+				t.Errorf("unexpected audit event %+v", e)
+			}
+		})
+	}
+}
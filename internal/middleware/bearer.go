@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/malakagl/go-template/pkg/jwt"
+	"github.com/malakagl/go-template/pkg/log"
+	"github.com/malakagl/go-template/pkg/models/db"
+	"github.com/malakagl/go-template/pkg/scopes"
+)
+
+// tokenClientPrefix marks the client IDs of token subjects, so they never collide with api key client IDs
+const tokenClientPrefix = "jwt:"
+
+var (
+	tokenVerifier   *jwt.Verifier // nil when bearer tokens are not accepted
+	tokenScopeClaim string
+)
+
+// InitBearerAuth accepts bearer tokens of issuer for audience, verified with the JWKS read from source.
+// Key sets fetched from a URL are refreshed every refreshInterval until ctx is done.
+func InitBearerAuth(ctx context.Context, source, issuer, audience, scopeClaim string, clockSkew, refreshInterval time.Duration) error {
+	keys, err := jwt.LoadKeySet(ctx, source)
+	if err != nil {
+		return fmt.Errorf("loading jwks from %s: %w", source, err)
+	}
+
+	tokenVerifier = jwt.NewVerifier(keys, issuer, audience, clockSkew)
+	tokenScopeClaim = scopeClaim
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		go refreshKeySet(ctx, tokenVerifier, source, refreshInterval)
+	}
+
+	return nil
+}
+
+func refreshKeySet(ctx context.Context, verifier *jwt.Verifier, source string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		keys, err := jwt.LoadKeySet(ctx, source)
+		if err != nil {
+			log.Error().Err(err).Msgf("refreshing jwks from %s failed, keeping the current keys", source)
+			continue
+		}
+
+		verifier.SetKeys(keys)
+	}
+}
+
+// bearerToken returns the token of the Authorization header, "" when the request has none
+func bearerToken(r *http.Request) string {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
+
+// authenticateBearer resolves a bearer token to a key of its subject holding the scopes and roles of the
+// scope claim, or returns the status to fail the request with. Tokens carry no endpoint grants.
+func authenticateBearer(r *http.Request, now time.Time) (*db.APIKey, int) {
+	if tokenVerifier == nil {
+		log.WithCtx(r.Context()).Debug().Msgf("bearer token while tokens are not accepted for %s %s", r.Method, r.RequestURI)
+		return nil, http.StatusUnauthorized
+	}
+
+	claims, err := tokenVerifier.Verify(bearerToken(r), now)
+	if err != nil {
+		log.WithCtx(r.Context()).Debug().Msgf("invalid bearer token for %s %s: %v", r.Method, r.RequestURI, err)
+		return nil, http.StatusUnauthorized
+	}
+
+	if claims.Subject == "" {
+		log.WithCtx(r.Context()).Debug().Msgf("bearer token without subject for %s %s", r.Method, r.RequestURI)
+		return nil, http.StatusUnauthorized
+	}
+
+	return &db.APIKey{
+		ClientID: tokenClientPrefix + claims.Subject,
+		Scopes:   scopes.Format(claims.Strings(tokenScopeClaim)),
+	}, http.StatusOK
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/malakagl/go-template/pkg/scopes"
+	"github.com/malakagl/go-template/pkg/util"
+)
+
+// es256Token signs claims with key, the way the identity provider does
+func es256Token(t *testing.T, key *ecdsa.PrivateKey, claims map[string]any) string {
+	t.Helper()
+	enc := base64.RawURLEncoding.EncodeToString
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "kid": "test"})
+	payload, _ := json.Marshal(claims)
+	signingInput := enc(header) + "." + enc(payload)
+	hash := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signingInput + "." + enc(append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...))
+}
+
+func TestAuthenticateBearer(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc := base64.RawURLEncoding.EncodeToString
+	jwks, _ := json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kty": "EC", "kid": "test", "crv": "P-256", "x": enc(key.X.FillBytes(make([]byte, 32))), "y": enc(key.Y.FillBytes(make([]byte, 32))),
+	}}})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwks, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := InitBearerAuth(context.Background(), path, "https://idp.example.com", "go-template", "scope", time.Minute, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { tokenVerifier = nil }()
+
+	var clientID string
+	r := chi.NewRouter()
+	r.Use(Authentication)
+	BindScope(http.MethodPost, "/orders", scopes.OrdersWrite)
+	r.With(Authorize).Post("/orders", func(w http.ResponseWriter, r *http.Request) {
+		clientID = util.ClientIDFromContext(r.Context())
+	})
+
+	claims := func(scope string, exp time.Time) map[string]any {
+		return map[string]any{"iss": "https://idp.example.com", "aud": "go-template", "sub": "billing", "exp": exp.Unix(), "scope": scope}
+	}
+	tests := []struct {
+		name   string
+		auth   string
+		status int
+	}{
+		{name: "role in scope claim", auth: "Bearer " + es256Token(t, key, claims("customer", time.Now().Add(time.Hour))), status: http.StatusOK},
+		{name: "scope", auth: "bearer " + es256Token(t, key, claims("orders:write", time.Now().Add(time.Hour))), status: http.StatusOK},
+		{name: "missing scope", auth: "Bearer " + es256Token(t, key, claims("orders:read", time.Now().Add(time.Hour))), status: http.StatusForbidden},
+		{name: "expired", auth: "Bearer " + es256Token(t, key, claims("customer", time.Now().Add(-time.Hour))), status: http.StatusUnauthorized},
+		{name: "garbage", auth: "Bearer abc", status: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientID = ""
+			req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+			req.Header.Set("Authorization", tt.auth)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, w.Code)
+			}
+			if tt.status == http.StatusOK && clientID != "jwt:billing" {
+				t.Errorf("expected client jwt:billing, got %q", clientID)
+			}
+		})
+	}
+}
+
+func TestRefreshKeySetStops(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc := base64.RawURLEncoding.EncodeToString
+	jwks, _ := json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kty": "EC", "kid": "test", "crv": "P-256", "x": enc(key.X.FillBytes(make([]byte, 32))), "y": enc(key.Y.FillBytes(make([]byte, 32))),
+	}}})
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fetches.Add(1)
+		_, _ = w.Write(jwks)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := InitBearerAuth(ctx, srv.URL, "https://idp.example.com", "go-template", "scope", time.Minute, 5*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { tokenVerifier = nil }()
+
+	for fetches.Load() < 3 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	stopped := fetches.Load()
+	time.Sleep(50 * time.Millisecond)
+	if n := fetches.Load(); n != stopped {
+		t.Errorf("expected no refreshes after the context is done, got %d more", n-stopped)
+	}
+}
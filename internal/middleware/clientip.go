@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/malakagl/go-template/pkg/constants"
+	"github.com/malakagl/go-template/pkg/iprules"
+	"github.com/malakagl/go-template/pkg/util"
+)
+
+// Forwarding headers the client IP can be read from
+const (
+	HeaderForwarded     = "Forwarded" // RFC 7239
+	HeaderXForwardedFor = "X-Forwarded-For"
+	HeaderXRealIP       = "X-Real-IP"
+)
+
+var (
+	trustedProxies  iprules.List
+	forwardedHeader string
+)
+
+// InitClientIP trusts header when it is set by proxies in cidrs, single addresses are accepted as well.
+// Other forwarding headers are ignored, proxies usually pass them through from the client unchanged.
+func InitClientIP(cidrs []string, header string) error {
+	proxies, err := iprules.Parse(cidrs)
+	if err != nil {
+		return fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+
+	switch {
+	case strings.EqualFold(header, HeaderForwarded):
+		forwardedHeader = HeaderForwarded
+	case strings.EqualFold(header, HeaderXForwardedFor):
+		forwardedHeader = HeaderXForwardedFor
+	case strings.EqualFold(header, HeaderXRealIP):
+		forwardedHeader = HeaderXRealIP
+	default:
+		return fmt.Errorf("unknown client IP header %q", header)
+	}
+
+	trustedProxies = proxies
+	return nil
+}
+
+// ClientIP resolves the IP of the client and stores it in the request context, see util.ClientIPFromContext.
+// The configured forwarding header is only read when the request comes from a trusted proxy, and only as far
+// back as the chain of trusted proxies goes.
+func ClientIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), constants.ClientIP, resolveClientIP(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func resolveClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	current, err := netip.ParseAddr(host)
+	if err != nil {
+		return host
+	}
+
+	var hops []string
+	switch forwardedHeader {
+	case HeaderForwarded:
+		hops = forwardedFor(r.Header.Values(HeaderForwarded))
+	case HeaderXForwardedFor:
+		if values := r.Header.Values(HeaderXForwardedFor); len(values) > 0 {
+			hops = strings.Split(strings.Join(values, ","), ",")
+		}
+	case HeaderXRealIP:
+		if ip := r.Header.Get(HeaderXRealIP); ip != "" {
+			hops = []string{ip}
+		}
+	}
+
+	// walk back from the proxy closest to us while the hops are trusted proxies
+	for i := len(hops) - 1; i >= 0 && trustedProxies.Contains(current); i-- {
+		addr, ok := parseHop(hops[i])
+		if !ok {
+			break
+		}
+		current = addr
+	}
+
+	return current.Unmap().String()
+}
+
+// forwardedFor returns the for parameters of the elements of Forwarded headers, in order
+func forwardedFor(values []string) []string {
+	var hops []string
+	for _, v := range values {
+		for _, element := range strings.Split(v, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(name, "for") {
+					hops = append(hops, value)
+				}
+			}
+		}
+	}
+
+	return hops
+}
+
+// parseHop parses an address of a forwarding header: an IP, optionally quoted, with a port or in brackets.
+// Obfuscated identifiers like "unknown" or "_hidden" of RFC 7239 are not addresses.
+func parseHop(hop string) (netip.Addr, bool) {
+	hop = strings.Trim(strings.TrimSpace(hop), `"`)
+	if addrPort, err := netip.ParseAddrPort(hop); err == nil {
+		return addrPort.Addr(), true
+	}
+
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(hop, "["), "]"))
+	return addr, err == nil
+}
+
+// clientIP is the IP ClientIP resolved, or the remote address of requests that did not pass through it
+func clientIP(r *http.Request) string {
+	if ip := util.ClientIPFromContext(r.Context()); ip != "" {
+		return ip
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/malakagl/go-template/pkg/util"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies := []string{"10.0.0.0/8", "192.168.1.1", "2001:db8::/32"}
+	defer func() { trustedProxies, forwardedHeader = nil, "" }()
+
+	tests := []struct {
+		name       string
+		header     string // trusted forwarding header, defaults to X-Forwarded-For
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{name: "direct", remoteAddr: "203.0.113.7:4711", want: "203.0.113.7"},
+		{name: "spoofed by untrusted client", remoteAddr: "203.0.113.7:4711", headers: map[string]string{"X-Forwarded-For": "1.2.3.4"}, want: "203.0.113.7"},
+		{name: "x-forwarded-for", remoteAddr: "10.0.0.2:80", headers: map[string]string{"X-Forwarded-For": "203.0.113.7"}, want: "203.0.113.7"},
+		{name: "x-forwarded-for chain", remoteAddr: "10.0.0.2:80", headers: map[string]string{"X-Forwarded-For": "1.2.3.4, 203.0.113.7, 192.168.1.1"}, want: "203.0.113.7"},
+		{name: "all hops trusted", remoteAddr: "10.0.0.2:80", headers: map[string]string{"X-Forwarded-For": "10.1.1.1, 192.168.1.1"}, want: "10.1.1.1"},
+		{name: "x-real-ip", header: HeaderXRealIP, remoteAddr: "10.0.0.2:80", headers: map[string]string{"X-Real-IP": "203.0.113.7"}, want: "203.0.113.7"},
+		{name: "forwarded", header: HeaderForwarded, remoteAddr: "10.0.0.2:80", headers: map[string]string{"Forwarded": `for=1.2.3.4, for="203.0.113.7:1234";proto=https, for=10.0.0.3`}, want: "203.0.113.7"},
+		{name: "forwarded ipv6", header: HeaderForwarded, remoteAddr: "[2001:db8::1]:443", headers: map[string]string{"Forwarded": `For="[2001:db9::17]:4711"`}, want: "2001:db9::17"},
+		{name: "unconfigured header ignored", remoteAddr: "10.0.0.2:80", headers: map[string]string{"Forwarded": "for=1.2.3.4", "X-Forwarded-For": "203.0.113.7"}, want: "203.0.113.7"},
+		{name: "only unconfigured header", remoteAddr: "10.0.0.2:80", headers: map[string]string{"Forwarded": "for=1.2.3.4", "X-Real-IP": "1.2.3.4"}, want: "10.0.0.2"},
+		{name: "obfuscated hop", header: HeaderForwarded, remoteAddr: "10.0.0.2:80", headers: map[string]string{"Forwarded": "for=203.0.113.7, for=_hidden"}, want: "10.0.0.2"},
+		{name: "ipv4 mapped proxy", remoteAddr: "[::ffff:10.0.0.2]:80", headers: map[string]string{"X-Forwarded-For": "203.0.113.7"}, want: "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := tt.header
+			if header == "" {
+				header = HeaderXForwardedFor
+			}
+			if err := InitClientIP(proxies, header); err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			var got string
+			ClientIP(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				got = util.ClientIPFromContext(r.Context())
+			})).ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("expected client IP %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	if err := InitClientIP([]string{"not-a-cidr"}, HeaderXForwardedFor); err == nil {
+		t.Error("expected invalid trusted proxy to be rejected")
+	}
+	if err := InitClientIP(proxies, "X-Client-IP"); err == nil {
+		t.Error("expected unknown client IP header to be rejected")
+	}
+}
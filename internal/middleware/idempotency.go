@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/malakagl/go-template/pkg/log"
+	"github.com/malakagl/go-template/pkg/models/db"
+	"github.com/malakagl/go-template/pkg/models/dto/response"
+	"github.com/malakagl/go-template/pkg/repositories"
+	"github.com/malakagl/go-template/pkg/util"
+	"gorm.io/gorm"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	defaultIdempotencyKeysTTL = 24 * time.Hour
+	// idempotencyLease is how long a request holds its key, a key still in flight after it can be taken over
+	idempotencyLease = time.Minute
+)
+
+// idempotencyStore persists idempotency keys and the responses they produced.
+type idempotencyStore interface {
+	Reserve(ctx context.Context, rec *db.IdempotencyKey, lease time.Duration) (*db.IdempotencyKey, bool, error)
+	Complete(ctx context.Context, rec *db.IdempotencyKey, statusCode int, contentType string, body []byte) error
+	Release(ctx context.Context, rec *db.IdempotencyKey) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+var (
+	idempotencyKeys   idempotencyStore
+	idempotencyKeyTTL = defaultIdempotencyKeysTTL
+)
+
+// InitIdempotency stores idempotency keys in database, expired keys are deleted until ctx is done
+func InitIdempotency(ctx context.Context, database *gorm.DB, ttl time.Duration) {
+	idempotencyKeys = repositories.NewIdempotencyRepo(database)
+	if ttl > 0 {
+		idempotencyKeyTTL = ttl
+	}
+	go cleanupExpiredIdempotencyKeys(ctx)
+}
+
+func cleanupExpiredIdempotencyKeys(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if n, err := idempotencyKeys.DeleteExpired(ctx); err != nil {
+			log.Error().Err(err).Msg("failed to delete expired idempotency keys")
+		} else if n > 0 {
+			log.Debug().Msgf("deleted %d expired idempotency keys", n)
+		}
+	}
+}
+
+// recordingWriter keeps a copy of the status and body written by the handler.
+type recordingWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(code int) {
+	rw.statusCode = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+// Idempotency replays the stored response when a client repeats a request with the same Idempotency-Key.
+// Keys are scoped by the authenticated client ID. Reusing a key with a different request is rejected with 422,
+// and repeating a key while the first request is still in flight is rejected with 409, until its lease runs out.
+// Server errors and panics are not stored so the client can retry them with the same key.
+func Idempotency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || idempotencyKeys == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := r.Context()
+		if len(key) > maxIdempotencyKeyLength {
+			response.Error(w, http.StatusBadRequest, "IdempotencyError", "Idempotency-Key is too long")
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		clientID := util.ClientIDFromContext(ctx)
+		hash := requestHash(r, body)
+		rec, reserved, err := idempotencyKeys.Reserve(ctx, &db.IdempotencyKey{
+			ClientID:    clientID,
+			Key:         key,
+			RequestHash: hash,
+			ExpiresAt:   time.Now().Add(idempotencyKeyTTL),
+		}, idempotencyLease)
+		if err != nil {
+			log.WithCtx(ctx).Error().Err(err).Msgf("failed to reserve idempotency key for client %s", clientID)
+			response.Error(w, http.StatusInternalServerError, "InternalError", http.StatusText(http.StatusInternalServerError))
+			return
+		}
+
+		if !reserved {
+			replayIdempotent(w, r, rec, hash)
+			return
+		}
+
+		// the client may have gone away, the outcome still has to be stored
+		storeCtx := context.WithoutCancel(ctx)
+		defer func() {
+			if p := recover(); p != nil {
+				// release the key, retries would be rejected as in flight until the lease runs out otherwise
+				if errRelease := idempotencyKeys.Release(storeCtx, rec); errRelease != nil {
+					log.WithCtx(ctx).Error().Err(errRelease).Msgf("failed to release idempotency key for client %s", clientID)
+				}
+				panic(p)
+			}
+		}()
+
+		rw := &recordingWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rw, r)
+
+		if rw.statusCode >= http.StatusInternalServerError {
+			err = idempotencyKeys.Release(storeCtx, rec)
+		} else {
+			err = idempotencyKeys.Complete(storeCtx, rec, rw.statusCode, rw.Header().Get("Content-Type"), rw.body.Bytes())
+		}
+		if err != nil {
+			log.WithCtx(ctx).Error().Err(err).Msgf("failed to store idempotency key for client %s", clientID)
+		}
+	})
+}
+
+func replayIdempotent(w http.ResponseWriter, r *http.Request, rec *db.IdempotencyKey, hash string) {
+	switch {
+	case rec.RequestHash != hash:
+		log.WithCtx(r.Context()).Warn().Msgf("idempotency key reused with a different request by client %s", rec.ClientID)
+		response.Error(w, http.StatusUnprocessableEntity, "IdempotencyError", "Idempotency-Key was used with a different request")
+	case rec.StatusCode == 0:
+		response.Error(w, http.StatusConflict, "IdempotencyError", "A request with this Idempotency-Key is still being processed")
+	default:
+		log.WithCtx(r.Context()).Debug().Msgf("replaying idempotent response for client %s", rec.ClientID)
+		if rec.ContentType != "" {
+			w.Header().Set("Content-Type", rec.ContentType)
+		}
+		w.Header().Set(IdempotentReplayedHeader, "true")
+		w.WriteHeader(rec.StatusCode)
+		_, _ = w.Write(rec.Response)
+	}
+}
+
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/malakagl/go-template/pkg/constants"
+	"github.com/malakagl/go-template/pkg/models/db"
+)
+
+// memoryIdempotencyStore implements idempotencyStore for testing
+type memoryIdempotencyStore struct {
+	mu   sync.Mutex
+	keys map[string]*db.IdempotencyKey
+}
+
+func (m *memoryIdempotencyStore) Reserve(_ context.Context, rec *db.IdempotencyKey, lease time.Duration) (*db.IdempotencyKey, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec.CreatedAt = time.Now()
+	if existing, ok := m.keys[rec.ClientID+"/"+rec.Key]; ok && (existing.StatusCode != 0 || time.Since(existing.CreatedAt) < lease) {
+		c := *existing
+		return &c, false, nil
+	}
+
+	m.keys[rec.ClientID+"/"+rec.Key] = rec
+	return rec, true, nil
+}
+
+func (m *memoryIdempotencyStore) Complete(_ context.Context, rec *db.IdempotencyKey, statusCode int, contentType string, body []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if stored := m.keys[rec.ClientID+"/"+rec.Key]; stored == rec {
+		rec.StatusCode, rec.ContentType, rec.Response = statusCode, contentType, append([]byte(nil), body...)
+	}
+	return nil
+}
+
+func (m *memoryIdempotencyStore) Release(_ context.Context, rec *db.IdempotencyKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if stored := m.keys[rec.ClientID+"/"+rec.Key]; stored == rec {
+		delete(m.keys, rec.ClientID+"/"+rec.Key)
+	}
+	return nil
+}
+
+func (m *memoryIdempotencyStore) DeleteExpired(_ context.Context) (int64, error) {
+	return 0, nil
+}
+
+func TestIdempotency(t *testing.T) {
+	store := &memoryIdempotencyStore{keys: make(map[string]*db.IdempotencyKey)}
+	idempotencyKeys = store
+	defer func() { idempotencyKeys = nil }()
+
+	calls := 0
+	status := http.StatusCreated
+	handler := Idempotency(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"id":"order-1"}`))
+	}))
+
+	do := func(clientID, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), constants.ClientID, clientID))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	first := do("client-a", "key-1", `{"items":[]}`)
+	if first.Code != http.StatusCreated || calls != 1 {
+		t.Fatalf("expected first request to be processed, got %d after %d calls", first.Code, calls)
+	}
+
+	replay := do("client-a", "key-1", `{"items":[]}`)
+	if replay.Code != http.StatusCreated || calls != 1 {
+		t.Errorf("expected replay without calling handler, got %d after %d calls", replay.Code, calls)
+	}
+	if replay.Body.String() != `{"id":"order-1"}` || replay.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("unexpected replayed response %q, headers %v", replay.Body.String(), replay.Header())
+	}
+
+	if w := do("client-a", "key-1", `{"items":[1]}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected key reuse with a different body to be rejected, got %d", w.Code)
+	}
+
+	if w := do("client-b", "key-1", `{"items":[]}`); w.Code != http.StatusCreated || calls != 2 {
+		t.Errorf("expected keys to be scoped by client, got %d after %d calls", w.Code, calls)
+	}
+
+	if w := do("client-a", "", `{"items":[]}`); w.Code != http.StatusCreated || calls != 3 {
+		t.Errorf("expected requests without a key to pass through, got %d after %d calls", w.Code, calls)
+	}
+
+	store.keys["client-a/in-flight"] = &db.IdempotencyKey{ClientID: "client-a", Key: "in-flight", CreatedAt: time.Now(), RequestHash: requestHash(
+		httptest.NewRequest(http.MethodPost, "/orders", nil), []byte(`{}`))}
+	if w := do("client-a", "in-flight", `{}`); w.Code != http.StatusConflict {
+		t.Errorf("expected in flight key to be rejected, got %d", w.Code)
+	}
+
+	store.keys["client-a/in-flight"].CreatedAt = time.Now().Add(-idempotencyLease - time.Second)
+	if w := do("client-a", "in-flight", `{}`); w.Code != http.StatusCreated || calls != 4 {
+		t.Errorf("expected key past its lease to be taken over, got %d after %d calls", w.Code, calls)
+	}
+
+	status = http.StatusInternalServerError
+	do("client-a", "key-2", `{}`)
+	status = http.StatusCreated
+	if w := do("client-a", "key-2", `{}`); w.Code != http.StatusCreated || calls != 6 {
+		t.Errorf("expected server errors to be retryable, got %d after %d calls", w.Code, calls)
+	}
+}
+
+func TestIdempotencyPanic(t *testing.T) {
+	store := &memoryIdempotencyStore{keys: make(map[string]*db.IdempotencyKey)}
+	idempotencyKeys = store
+	defer func() { idempotencyKeys = nil }()
+
+	panicking := Idempotency(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic("boom") }))
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{}`))
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("expected the panic to be passed on, got %v", p)
+			}
+		}()
+		panicking.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+
+	if len(store.keys) != 0 {
+		t.Errorf("expected the key to be released after a panic, got %v", store.keys)
+	}
+}
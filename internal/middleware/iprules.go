@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+
+	"github.com/malakagl/go-template/pkg/iprules"
+	"github.com/malakagl/go-template/pkg/log"
+	"github.com/malakagl/go-template/pkg/models/db"
+)
+
+var globalIPRules iprules.Rules
+
+// InitIPRules restricts the client IPs of all authenticated requests, see iprules.Rules
+func InitIPRules(allow, deny []string) error {
+	var err error
+	var rules iprules.Rules
+	if rules.Allow, err = iprules.Parse(allow); err != nil {
+		return fmt.Errorf("invalid ip allowlist: %w", err)
+	}
+	if rules.Deny, err = iprules.Parse(deny); err != nil {
+		return fmt.Errorf("invalid ip denylist: %w", err)
+	}
+
+	globalIPRules = rules
+	return nil
+}
+
+// permitGlobalIP checks the client IP against the global rules. Rejections are logged apart from
+// bad credentials, as a warning naming the IP.
+func permitGlobalIP(r *http.Request) bool {
+	if globalIPRules.Empty() {
+		return true
+	}
+
+	ip := clientIP(r)
+	if addr, err := netip.ParseAddr(ip); err == nil && globalIPRules.Permits(addr) {
+		return true
+	}
+
+	log.WithCtx(r.Context()).Warn().Msgf("ip %s rejected by the global ip rules for %s %s", ip, r.Method, r.RequestURI)
+	return false
+}
+
+// permitKeyIP checks the client IP against the rules of key
+func permitKeyIP(r *http.Request, key *db.APIKey) bool {
+	if key.IPAllowlist == "" && key.IPDenylist == "" {
+		return true
+	}
+
+	ip := clientIP(r)
+	rules, err := iprules.ParseRules(key.IPAllowlist, key.IPDenylist)
+	if err != nil {
+		log.WithCtx(r.Context()).Error().Err(err).Msgf("invalid ip rules of client %s", key.ClientID)
+		return false
+	}
+
+	if addr, err := netip.ParseAddr(ip); err == nil && rules.Permits(addr) {
+		return true
+	}
+
+	log.WithCtx(r.Context()).Warn().Msgf("ip %s rejected by the ip rules of client %s for %s %s", ip, key.ClientID, r.Method, r.RequestURI)
+	return false
+}
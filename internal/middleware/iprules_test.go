@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/malakagl/go-template/pkg/cache"
+	"github.com/malakagl/go-template/pkg/iprules"
+	"github.com/malakagl/go-template/pkg/models/db"
+)
+
+func TestAuthenticationIPRules(t *testing.T) {
+	if err := InitIPRules(nil, []string{"198.51.100.0/24"}); err != nil {
+		t.Fatal(err)
+	}
+	apiKeyCache = cache.NewLRUCache[*cachedAPIKey](10, time.Hour)
+	defer func() { globalIPRules, apiKeyCache = iprules.Rules{}, nil }()
+
+	apiKeyCache.Put("office.secret", &cachedAPIKey{key: &db.APIKey{ClientID: "office", IPAllowlist: "10.0.0.0/8 203.0.113.7", IPDenylist: "10.0.66.0/24"}})
+	apiKeyCache.Put("open.secret", &cachedAPIKey{key: &db.APIKey{ClientID: "open"}})
+
+	h := Authentication(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	tests := []struct {
+		name       string
+		apiKey     string
+		remoteAddr string
+		status     int
+	}{
+		{name: "allowed network", apiKey: "office.secret", remoteAddr: "10.1.2.3:1234", status: http.StatusOK},
+		{name: "allowed ip", apiKey: "office.secret", remoteAddr: "203.0.113.7:1234", status: http.StatusOK},
+		{name: "outside allowlist", apiKey: "office.secret", remoteAddr: "203.0.113.8:1234", status: http.StatusForbidden},
+		{name: "denied inside allowlist", apiKey: "office.secret", remoteAddr: "10.0.66.1:1234", status: http.StatusForbidden},
+		{name: "key without rules", apiKey: "open.secret", remoteAddr: "203.0.113.8:1234", status: http.StatusOK},
+		{name: "globally denied", apiKey: "open.secret", remoteAddr: "198.51.100.1:1234", status: http.StatusForbidden},
+		{name: "globally denied without credentials", remoteAddr: "198.51.100.1:1234", status: http.StatusForbidden},
+		{name: "no credentials", remoteAddr: "203.0.113.8:1234", status: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.apiKey != "" {
+				req.Header.Set("x-api-key", tt.apiKey)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, w.Code)
+			}
+		})
+	}
+}
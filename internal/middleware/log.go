@@ -27,11 +27,7 @@ func Logging(next http.Handler) http.Handler {
 		next.ServeHTTP(rw, r)
 
 		duration := time.Since(start)
-		clientIP := r.RemoteAddr
-		if ip := r.Header.Get("X-Forwarded-For"); ip != "" {
-			clientIP = ip
-		}
 		logger.Info().Msgf("Request %s %s from %s -> %d processed in %s",
-			r.Method, r.URL.Path, clientIP, rw.statusCode, duration)
+			r.Method, r.URL.Path, clientIP(r), rw.statusCode, duration)
 	})
 }
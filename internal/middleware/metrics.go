@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/malakagl/go-template/pkg/metrics"
+)
+
+// Metrics counts requests and their latency by method, chi route pattern and status. It has to be the
+// first middleware so requests rejected before routing, by authentication or rate limits, are counted too.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rw, r)
+
+		method, route, status := r.Method, routePattern(r), strconv.Itoa(rw.statusCode)
+		metrics.HTTPRequests.WithLabelValues(method, route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+// routePattern is the chi route pattern of r, matched again for requests rejected before routing. Paths of
+// no route are labelled "unmatched", raw paths would give every scanned URL its own series.
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return "unmatched"
+	}
+
+	if pattern := rctx.RoutePattern(); pattern != "" {
+		return pattern
+	}
+
+	match := chi.NewRouteContext()
+	if rctx.Routes != nil && rctx.Routes.Match(match, r.Method, r.URL.Path) {
+		return match.RoutePattern()
+	}
+
+	return "unmatched"
+}
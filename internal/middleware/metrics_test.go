@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/malakagl/go-template/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Metrics, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("x-api-key") == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	r.Get("/orders/{orderID}", func(http.ResponseWriter, *http.Request) {})
+
+	tests := []struct {
+		name   string
+		path   string
+		apiKey string
+		route  string
+		status string
+	}{
+		{name: "routed", path: "/orders/1", apiKey: "key", route: "/orders/{orderID}", status: "200"},
+		{name: "rejected before routing", path: "/orders/2", route: "/orders/{orderID}", status: "401"},
+		{name: "no route", path: "/wp-login.php", apiKey: "key", route: "unmatched", status: "404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := metrics.HTTPRequests.WithLabelValues(http.MethodGet, tt.route, tt.status)
+			before := testutil.ToFloat64(counter)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.apiKey != "" {
+				req.Header.Set("x-api-key", tt.apiKey)
+			}
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got := testutil.ToFloat64(counter) - before; got != 1 {
+				t.Errorf("expected 1 request counted for %s %s, got %v", tt.route, tt.status, got)
+			}
+		})
+	}
+}
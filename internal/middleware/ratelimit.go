@@ -1,77 +1,80 @@
 package middleware
 
 import (
-	"net"
+	"context"
+	"math"
 	"net/http"
-	"strings"
-	"sync"
+	"strconv"
 	"time"
 
+	"github.com/malakagl/go-template/pkg/constants"
 	"github.com/malakagl/go-template/pkg/log"
+	"github.com/malakagl/go-template/pkg/metrics"
+	"github.com/malakagl/go-template/pkg/models/db"
 	"github.com/malakagl/go-template/pkg/models/dto/response"
-	"golang.org/x/time/rate"
+	"github.com/malakagl/go-template/pkg/ratelimit"
 )
 
-type visitor struct {
-	limiter  *rate.Limiter
-	lastSeen time.Time
-}
-
 var (
-	visitors   = make(map[string]*visitor)
-	mu         sync.RWMutex
-	rateLimit  rate.Limit = 5
-	burstLimit            = 10
-	rateWindow            = time.Minute // default 1 minute
+	limiter         ratelimit.Limiter
+	rateLimitPolicy ratelimit.Policy
+	rateWindow      = time.Minute // default 1 minute
 )
 
-func cleanupVisitors() {
-	for {
-		time.Sleep(time.Minute)
+// InitRateLimit limits requests by the limits policy resolves for their key, see ratelimit.Policy.
+// Requests without a key, like health checks, are limited by client IP with the default limits.
+// Idle clients are removed from l until ctx is done.
+func InitRateLimit(ctx context.Context, policy ratelimit.Policy, l ratelimit.Limiter, rw time.Duration) {
+	rateLimitPolicy = policy
+	limiter = l
+	rateWindow = rw
+	go cleanupRateLimits(ctx)
+}
 
-		mu.Lock()
-		for ip, v := range visitors {
-			if time.Since(v.lastSeen) > rateWindow {
-				delete(visitors, ip)
-			}
+func cleanupRateLimits(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
 		}
-		mu.Unlock()
-	}
-}
 
-func getVisitor(ip string) *rate.Limiter {
-	mu.RLock()
-	v, exists := visitors[ip]
-	mu.RUnlock()
-	if !exists {
-		limiter := rate.NewLimiter(rateLimit, burstLimit)
-		mu.Lock()
-		visitors[ip] = &visitor{limiter, time.Now()}
-		mu.Unlock()
-		return limiter
+		if n, err := limiter.RemoveIdle(ctx, rateWindow, time.Now()); err != nil {
+			log.Error().Err(err).Msg("failed to remove idle rate limits")
+		} else if n > 0 {
+			log.Debug().Msgf("removed %d idle rate limits", n)
+		}
 	}
-
-	mu.Lock()
-	v.lastSeen = time.Now()
-	mu.Unlock()
-	return v.limiter
 }
 
+// RateLimit counts requests against the rate limit and quotas of their client. Responses carry
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset, rejected requests also Retry-After.
+// Requests are let through when the limiter fails, an unavailable store must not take the API down.
 func RateLimit(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		clientIP, _, err := net.SplitHostPort(r.RemoteAddr)
-		if err != nil {
-			http.Error(w, "Unable to determine IP", http.StatusInternalServerError)
-			return
+		id, limits := "", rateLimitPolicy.Default
+		if key, ok := r.Context().Value(constants.APIKey).(*db.APIKey); ok {
+			id, limits = key.ClientID, rateLimitPolicy.For(key)
+		} else {
+			id = "ip:" + clientIP(r)
 		}
 
-		if ip := r.Header.Get("X-Forwarded-For"); ip != "" {
-			clientIP = strings.Split(ip, ",")[0]
+		res, err := limiter.Allow(r.Context(), id, limits, time.Now())
+		if err != nil {
+			log.WithCtx(r.Context()).Error().Err(err).Msgf("rate limiting %s failed", id)
+			next.ServeHTTP(w, r)
+			return
 		}
 
-		limiter := getVisitor(clientIP)
-		if !limiter.Allow() {
-			log.WithCtx(r.Context()).Warn().Msgf("Rate limit exceeded for IP %s", clientIP)
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		w.Header().Set("X-RateLimit-Reset", seconds(res.Reset))
+		if !res.Allowed {
+			log.WithCtx(r.Context()).Warn().Msgf("Rate limit exceeded for %s", id)
+			metrics.RateLimitRejections.WithLabelValues(tierLabel(limits.Tier)).Inc()
+			w.Header().Set("Retry-After", seconds(res.RetryAfter))
 			response.Error(w, http.StatusTooManyRequests, "Too Many Requests", "Please slow down.")
 			return
 		}
@@ -80,12 +83,26 @@ func RateLimit(next http.Handler) http.Handler {
 	})
 }
 
-func init() {
-	go cleanupVisitors()
+// RateLimitUsage reports the current usage of the limits of key
+func RateLimitUsage(ctx context.Context, key *db.APIKey) (ratelimit.Usage, error) {
+	return limiter.Usage(ctx, key.ClientID, rateLimitPolicy.For(key), time.Now())
 }
 
-func SetRateLimits(r, b int, rw time.Duration) {
-	rateLimit = rate.Limit(r)
-	burstLimit = b
-	rateWindow = rw
+// RateLimitTierExists reports whether keys can be assigned the rate limit tier name
+func RateLimitTierExists(name string) bool {
+	return rateLimitPolicy.HasTier(name)
+}
+
+// tierLabel names the default limits "default" in metrics
+func tierLabel(tier string) string {
+	if tier == "" {
+		return "default"
+	}
+
+	return tier
+}
+
+// seconds rounds d up to whole seconds, as rate limit headers count in seconds
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
 }
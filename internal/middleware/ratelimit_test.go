@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/malakagl/go-template/pkg/constants"
+	"github.com/malakagl/go-template/pkg/models/db"
+	"github.com/malakagl/go-template/pkg/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	limiter = ratelimit.NewMemoryLimiter()
+	rateLimitPolicy = ratelimit.Policy{
+		Default: ratelimit.Limits{Rate: 1, Burst: 1},
+		Tiers:   map[string]ratelimit.Limits{"standard": {Rate: 1, Burst: 2}},
+	}
+	defer func() { limiter, rateLimitPolicy = nil, ratelimit.Policy{} }()
+
+	h := RateLimit(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	standard := "standard"
+	request := func(key *db.APIKey, remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		r.RemoteAddr = remoteAddr
+		if key != nil {
+			r = r.WithContext(context.WithValue(r.Context(), constants.APIKey, key))
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	tests := []struct {
+		name       string
+		key        *db.APIKey
+		remoteAddr string
+		status     int
+		remaining  string
+	}{
+		{name: "tier", key: &db.APIKey{ClientID: "a", RateLimitTier: &standard}, remoteAddr: "10.0.0.1:1234", status: http.StatusOK, remaining: "1"},
+		{name: "tier burst", key: &db.APIKey{ClientID: "a", RateLimitTier: &standard}, remoteAddr: "10.0.0.2:1234", status: http.StatusOK, remaining: "0"},
+		{name: "tier exhausted", key: &db.APIKey{ClientID: "a", RateLimitTier: &standard}, remoteAddr: "10.0.0.3:1234", status: http.StatusTooManyRequests, remaining: "0"},
+		{name: "other key from the same IP", key: &db.APIKey{ClientID: "b"}, remoteAddr: "10.0.0.1:1234", status: http.StatusOK, remaining: "0"},
+		{name: "no key", remoteAddr: "10.0.0.1:1234", status: http.StatusOK, remaining: "0"},
+		{name: "no key exhausted", remoteAddr: "10.0.0.1:4321", status: http.StatusTooManyRequests, remaining: "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(tt.key, tt.remoteAddr)
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, w.Code)
+			}
+			if got := w.Header().Get("X-RateLimit-Remaining"); got != tt.remaining {
+				t.Errorf("expected %s remaining, got %s", tt.remaining, got)
+			}
+			if w.Header().Get("X-RateLimit-Limit") == "" || w.Header().Get("X-RateLimit-Reset") == "" {
+				t.Errorf("expected rate limit headers, got %v", w.Header())
+			}
+			if retryAfter := w.Header().Get("Retry-After"); (tt.status == http.StatusTooManyRequests) != (retryAfter == "1") {
+				t.Errorf("unexpected Retry-After %q", retryAfter)
+			}
+		})
+	}
+
+	t.Run("usage", func(t *testing.T) {
+		if u, _ := RateLimitUsage(context.Background(), &db.APIKey{ClientID: "a", RateLimitTier: &standard}); u.Tier != standard || u.Daily.Used != 2 {
+			t.Errorf("unexpected usage %+v", u)
+		}
+	})
+}
@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	pkgerrors "github.com/malakagl/go-template/pkg/errors"
+	"github.com/malakagl/go-template/pkg/log"
+	"github.com/malakagl/go-template/pkg/models/db"
+	"github.com/malakagl/go-template/pkg/repositories"
+	"github.com/malakagl/go-template/pkg/util"
+	"gorm.io/gorm"
+)
+
+// Signed requests carry these headers instead of x-api-key. The signature is the hex encoded
+// HMAC-SHA256, keyed with the signing secret of the key, of the canonical request:
+//
+//	METHOD \n PATH?QUERY \n TIMESTAMP \n NONCE \n hex(SHA256(BODY))
+const (
+	keyIDHeader     = "X-Key-Id"    // client ID of the key
+	timestampHeader = "X-Timestamp" // unix seconds
+	nonceHeader     = "X-Nonce"     // unique per request of the key
+	signatureHeader = "X-Signature"
+
+	maxNonceLength    = 128
+	maxSignedBodySize = 10 << 20
+)
+
+var (
+	errClockSkew   = errors.New("timestamp outside the allowed clock skew")
+	errBadSigned   = errors.New("malformed signed request")
+	errSignature   = errors.New("signature mismatch")
+	errNonceReused = errors.New("nonce already used")
+)
+
+var (
+	signingSecretKey []byte // seals signing secrets at rest, nil when request signing is disabled
+	maxClockSkew     time.Duration
+	usedNonces       nonceStore
+)
+
+// InitRequestSigning enables signed requests. secretKey is the base64 encoded AES-256 key sealing signing secrets.
+// Used nonces are kept in database, expired ones are deleted until ctx is done.
+func InitRequestSigning(ctx context.Context, database *gorm.DB, secretKey string, clockSkew time.Duration) error {
+	key, err := base64.StdEncoding.DecodeString(secretKey)
+	if err != nil || len(key) != 32 {
+		return fmt.Errorf("request signing secret key must be 32 base64 encoded bytes: %v", err)
+	}
+
+	signingSecretKey = key
+	maxClockSkew = clockSkew
+	usedNonces = repositories.NewNonceRepo(database)
+	go cleanupUsedNonces(ctx)
+	return nil
+}
+
+// SealSigningSecret seals a signing secret for storage, it fails when request signing is disabled
+func SealSigningSecret(secret string) (string, error) {
+	if signingSecretKey == nil {
+		return "", pkgerrors.ErrSigningDisabled
+	}
+
+	return util.Seal(signingSecretKey, secret)
+}
+
+// authenticateSignature resolves a signed request to its key, or returns the status to fail the request with.
+// The body is read to be hashed and replaced, so handlers can still read it.
+func authenticateSignature(r *http.Request, now time.Time) (*db.APIKey, int) {
+	if signingSecretKey == nil {
+		log.WithCtx(r.Context()).Debug().Msgf("signed request while signing is disabled for %s %s", r.Method, r.RequestURI)
+		return nil, http.StatusUnauthorized
+	}
+
+	clientID := r.Header.Get(keyIDHeader)
+	if clientID == "" {
+		log.WithCtx(r.Context()).Debug().Msgf("signed request without key id for %s %s", r.Method, r.RequestURI)
+		return nil, http.StatusUnauthorized
+	}
+
+	key, status := findSigningKey(r, clientID, now)
+	if key == nil {
+		return nil, status
+	}
+
+	secret, err := util.Open(signingSecretKey, *key.SigningSecret)
+	if err != nil {
+		log.WithCtx(r.Context()).Error().Err(err).Msgf("cannot open signing secret of client %s", clientID)
+		return nil, http.StatusInternalServerError
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSignedBodySize+1))
+	if err != nil {
+		log.WithCtx(r.Context()).Debug().Msgf("cannot read signed body for %s %s: %v", r.Method, r.RequestURI, err)
+		return nil, http.StatusBadRequest
+	}
+
+	if len(body) > maxSignedBodySize {
+		return nil, http.StatusRequestEntityTooLarge
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err := verifySignature(r, body, []byte(secret), now); err != nil {
+		log.WithCtx(r.Context()).Debug().Msgf("invalid signed request of client %s for %s %s: %v", clientID, r.Method, r.RequestURI, err)
+		return nil, http.StatusUnauthorized
+	}
+
+	// only requests with a valid signature use up their nonce, others cannot burn nonces of the key
+	fresh, err := usedNonces.Use(r.Context(), clientID, r.Header.Get(nonceHeader), now.Add(2*maxClockSkew))
+	if err != nil {
+		log.WithCtx(r.Context()).Error().Err(err).Msgf("failed to record nonce of client %s for %s %s", clientID, r.Method, r.RequestURI)
+		return nil, http.StatusInternalServerError
+	}
+
+	if !fresh {
+		log.WithCtx(r.Context()).Debug().Msgf("replayed request of client %s for %s %s: %v", clientID, r.Method, r.RequestURI, errNonceReused)
+		return nil, http.StatusUnauthorized
+	}
+
+	return key, http.StatusOK
+}
+
+// findSigningKey returns the active key of clientID when it has a signing secret
+func findSigningKey(r *http.Request, clientID string, now time.Time) (*db.APIKey, int) {
+	if key, found := cachedKey(signingKeyCache, clientID, now); found {
+		return key, http.StatusOK
+	}
+
+	generation := keyCacheGeneration()
+	key, status := findAPIKey(r, clientID)
+	if key == nil {
+		return nil, status
+	}
+
+	if !key.Active(now) || key.SigningSecret == nil {
+		log.WithCtx(r.Context()).Debug().Msgf("revoked, expired or non signing client %s for %s %s", clientID, r.Method, r.RequestURI)
+		return nil, http.StatusUnauthorized
+	}
+
+	cacheKey(signingKeyCache, clientID, &cachedAPIKey{key: key, validUntil: key.ExpiresAt}, generation)
+	return key, http.StatusOK
+}
+
+// verifySignature checks the timestamp of a signed request against the clock skew window, and its signature
+func verifySignature(r *http.Request, body, secret []byte, now time.Time) error {
+	ts, err := strconv.ParseInt(r.Header.Get(timestampHeader), 10, 64)
+	if err != nil {
+		return errBadSigned
+	}
+
+	if skew := now.Sub(time.Unix(ts, 0)); skew > maxClockSkew || skew < -maxClockSkew {
+		return errClockSkew
+	}
+
+	nonce := r.Header.Get(nonceHeader)
+	if nonce == "" || len(nonce) > maxNonceLength {
+		return errBadSigned
+	}
+
+	signature, err := hex.DecodeString(r.Header.Get(signatureHeader))
+	if err != nil {
+		return errBadSigned
+	}
+
+	if !hmac.Equal(signature, sign(secret, r.Method, r.URL.RequestURI(), r.Header.Get(timestampHeader), nonce, body)) {
+		return errSignature
+	}
+
+	return nil
+}
+
+// sign returns the HMAC-SHA256 of the canonical request
+func sign(secret []byte, method, uri, timestamp, nonce string, body []byte) []byte {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%s", method, uri, timestamp, nonce, hex.EncodeToString(bodyHash[:]))
+	return mac.Sum(nil)
+}
+
+// nonceStore remembers the used nonces of all instances, see repositories.NonceRepo
+type nonceStore interface {
+	Use(ctx context.Context, clientID, nonce string, expires time.Time) (bool, error)
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+func cleanupUsedNonces(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if n, err := usedNonces.DeleteExpired(ctx); err != nil {
+			log.Error().Err(err).Msg("failed to delete expired nonces")
+		} else if n > 0 {
+			log.Debug().Msgf("deleted %d expired nonces", n)
+		}
+	}
+}
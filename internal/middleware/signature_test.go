@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/malakagl/go-template/pkg/cache"
+	"github.com/malakagl/go-template/pkg/models/db"
+	"github.com/malakagl/go-template/pkg/repositories"
+	"github.com/malakagl/go-template/pkg/util"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func signedRequest(secret, method, target, body, nonce string, ts time.Time) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	r.Header.Set(keyIDHeader, "client-a")
+	r.Header.Set(timestampHeader, timestamp)
+	r.Header.Set(nonceHeader, nonce)
+	r.Header.Set(signatureHeader, hex.EncodeToString(sign([]byte(secret), method, r.URL.RequestURI(), timestamp, nonce, []byte(body))))
+	return r
+}
+
+// nonceDB opens the database at path holding the used nonces, each call opens a connection of its own like an instance
+func nonceDB(t *testing.T, path string) *gorm.DB {
+	t.Helper()
+	database, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = database.AutoMigrate(&db.UsedNonce{}); err != nil {
+		t.Fatal(err)
+	}
+
+	return database
+}
+
+func TestAuthenticateSignature(t *testing.T) {
+	noncesPath := filepath.Join(t.TempDir(), "nonces.db")
+	signingSecretKey = make([]byte, 32)
+	maxClockSkew = 5 * time.Minute
+	usedNonces = repositories.NewNonceRepo(nonceDB(t, noncesPath))
+	signingKeyCache = cache.NewLRUCache[*cachedAPIKey](10, time.Hour)
+	defer func() { signingSecretKey, usedNonces, signingKeyCache = nil, nil, nil }()
+
+	sealed, err := util.Seal(signingSecretKey, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingKeyCache.Put("client-a", &cachedAPIKey{key: &db.APIKey{ClientID: "client-a", SigningSecret: &sealed}})
+
+	now := time.Now()
+	tampered := signedRequest("secret", http.MethodPost, "/orders", `{"items":[]}`, "n-tampered", now)
+	tampered.Body = io.NopCloser(strings.NewReader(`{"items":[1]}`))
+	otherQuery := signedRequest("secret", http.MethodGet, "/orders?status=paid", "", "n-query", now)
+	otherQuery.URL.RawQuery = "status=refunded"
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+	}{
+		{name: "valid", req: signedRequest("secret", http.MethodPost, "/orders", `{"items":[]}`, "n1", now), status: http.StatusOK},
+		{name: "valid with query", req: signedRequest("secret", http.MethodGet, "/orders?status=paid", "", "n2", now), status: http.StatusOK},
+		{name: "replayed nonce", req: signedRequest("secret", http.MethodPost, "/orders", `{"items":[]}`, "n1", now), status: http.StatusUnauthorized},
+		{name: "wrong secret", req: signedRequest("other", http.MethodPost, "/orders", "", "n3", now), status: http.StatusUnauthorized},
+		{name: "tampered body", req: tampered, status: http.StatusUnauthorized},
+		{name: "tampered query", req: otherQuery, status: http.StatusUnauthorized},
+		{name: "too old", req: signedRequest("secret", http.MethodGet, "/orders", "", "n4", now.Add(-6*time.Minute)), status: http.StatusUnauthorized},
+		{name: "from the future", req: signedRequest("secret", http.MethodGet, "/orders", "", "n5", now.Add(6*time.Minute)), status: http.StatusUnauthorized},
+		{name: "no nonce", req: signedRequest("secret", http.MethodGet, "/orders", "", "", now), status: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, status := authenticateSignature(tt.req, now)
+			if status != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, status)
+			}
+			if tt.status == http.StatusOK && key.ClientID != "client-a" {
+				t.Errorf("expected client-a, got %s", key.ClientID)
+			}
+		})
+	}
+
+	t.Run("body stays readable", func(t *testing.T) {
+		r := signedRequest("secret", http.MethodPost, "/orders", `{"items":[]}`, "n6", now)
+		if _, status := authenticateSignature(r, now); status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		if body, _ := io.ReadAll(r.Body); string(body) != `{"items":[]}` {
+			t.Errorf("unexpected body %q", body)
+		}
+	})
+
+	t.Run("replayed on another instance", func(t *testing.T) {
+		usedNonces = repositories.NewNonceRepo(nonceDB(t, noncesPath))
+		signingKeyCache = cache.NewLRUCache[*cachedAPIKey](10, time.Hour)
+		signingKeyCache.Put("client-a", &cachedAPIKey{key: &db.APIKey{ClientID: "client-a", SigningSecret: &sealed}})
+
+		r := signedRequest("secret", http.MethodPost, "/orders", `{"items":[]}`, "n1", now)
+		if _, status := authenticateSignature(r, now); status != http.StatusUnauthorized {
+			t.Errorf("expected the nonce used on the first instance to be rejected, got %d", status)
+		}
+		r = signedRequest("secret", http.MethodPost, "/orders", `{"items":[]}`, "n7", now)
+		if _, status := authenticateSignature(r, now); status != http.StatusOK {
+			t.Errorf("expected a new nonce to be accepted, got %d", status)
+		}
+	})
+}
+
+func TestAuthenticateSignatureDisabled(t *testing.T) {
+	r := signedRequest("secret", http.MethodGet, "/orders", "", "n1", time.Now())
+	if _, status := authenticateSignature(r, time.Now()); status != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", status)
+	}
+}
@@ -25,7 +25,7 @@ func Trace(next http.Handler) http.Handler {
 		span.SetAttributes(
 			attribute.String("http.method", r.Method),
 			attribute.String("http.url", r.URL.Path),
-			attribute.String("http.client_ip", r.RemoteAddr),
+			attribute.String("http.client_ip", clientIP(r)),
 			attribute.String("http.user_agent", r.UserAgent()),
 		)
 
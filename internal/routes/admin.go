@@ -1,9 +1,13 @@
 package routes
 
 import (
+	"net/http"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/malakagl/go-template/internal/api/handlers"
+	"github.com/malakagl/go-template/internal/middleware"
 	"github.com/malakagl/go-template/pkg/repositories"
+	"github.com/malakagl/go-template/pkg/scopes"
 	"github.com/malakagl/go-template/pkg/services"
 	"gorm.io/gorm"
 )
@@ -11,9 +15,32 @@ import (
 func AddAdminRoutes(r *chi.Mux, db *gorm.DB) {
 	apiKeyRepo := repositories.NewApiKeyRepository(db)
 	adminService := services.NewEndpointService(apiKeyRepo)
-	apiKeyService := services.NewAPIKeyService(apiKeyRepo)
+	auditLogger := services.NewAuditLogger(repositories.NewAuditRepo(db))
+	apiKeyService := services.NewAPIKeyService(
+		apiKeyRepo,
+		middleware.InvalidateAPIKey,
+		middleware.SealSigningSecret,
+		middleware.RateLimitUsage,
+		middleware.RateLimitTierExists,
+		&auditLogger,
+	)
 	adminHandler := handlers.NewAdminHandler(&adminService, &apiKeyService)
+	auditHandler := handlers.NewAuditHandler(&auditLogger)
 
-	r.Get("/admin/endpoints", adminHandler.GetEndpoints)
-	r.Post("/admin/apikeys", adminHandler.CreateAPIKeys)
+	route(r, http.MethodGet, "/admin/endpoints", scopes.Admin, adminHandler.GetEndpoints)
+	route(r, http.MethodPost, "/admin/apikeys", scopes.Admin, adminHandler.CreateAPIKeys)
+	route(r, http.MethodGet, "/admin/apikeys", scopes.Admin, adminHandler.ListAPIKeys)
+	route(r, http.MethodPost, "/admin/apikeys/{clientID}/revoke", scopes.Admin, adminHandler.RevokeAPIKey)
+	route(r, http.MethodPost, "/admin/apikeys/{clientID}/rotate", scopes.Admin, adminHandler.RotateAPIKey)
+	route(r, http.MethodPut, "/admin/apikeys/{clientID}/expiry", scopes.Admin, adminHandler.SetAPIKeyExpiry)
+	route(r, http.MethodPut, "/admin/apikeys/{clientID}/scopes", scopes.Admin, adminHandler.SetAPIKeyScopes)
+	route(r, http.MethodPost, "/admin/apikeys/{clientID}/signing-secret", scopes.Admin, adminHandler.IssueSigningSecret)
+	route(r, http.MethodPut, "/admin/apikeys/{clientID}/ip-rules", scopes.Admin, adminHandler.SetAPIKeyIPRules)
+	route(r, http.MethodPut, "/admin/apikeys/{clientID}/rate-limits", scopes.Admin, adminHandler.SetAPIKeyRateLimits)
+	route(r, http.MethodGet, "/admin/apikeys/{clientID}/usage", scopes.Admin, adminHandler.GetAPIKeyUsage)
+	route(r, http.MethodPost, "/admin/apikeys/{clientID}/endpoints", scopes.Admin, adminHandler.AddAPIKeyEndpoints)
+	route(r, http.MethodDelete, "/admin/apikeys/{clientID}/endpoints/{endpointID}", scopes.Admin, adminHandler.RemoveAPIKeyEndpoint)
+	route(r, http.MethodPost, "/admin/apikeys/{clientID}/endpoints/{endpointID}/activate", scopes.Admin, adminHandler.SetAPIKeyEndpointActive(true))
+	route(r, http.MethodPost, "/admin/apikeys/{clientID}/endpoints/{endpointID}/deactivate", scopes.Admin, adminHandler.SetAPIKeyEndpointActive(false))
+	route(r, http.MethodGet, "/admin/audit", scopes.Admin, auditHandler.ListAuditEvents)
 }
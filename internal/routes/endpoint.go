@@ -0,0 +1,45 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/malakagl/go-template/internal/middleware"
+	"github.com/malakagl/go-template/pkg/log"
+	dbmodels "github.com/malakagl/go-template/pkg/models/db"
+	"github.com/malakagl/go-template/pkg/repositories"
+	"gorm.io/gorm"
+)
+
+// SyncEndpoints records every route registered on r in the endpoints table, with the scope bound to it,
+// and retires the endpoints of routes no longer registered.
+func SyncEndpoints(ctx context.Context, r chi.Routes, db *gorm.DB) error {
+	endpoints, err := registeredEndpoints(r)
+	if err != nil {
+		return err
+	}
+
+	retired, err := repositories.NewApiKeyRepository(db).SyncEndpoints(ctx, endpoints)
+	if err != nil {
+		return err
+	}
+
+	log.WithCtx(ctx).Info().Msgf("synced %d endpoints, retired %d", len(endpoints), retired)
+	return nil
+}
+
+// registeredEndpoints returns an endpoint per method and pattern registered on r
+func registeredEndpoints(r chi.Routes) ([]dbmodels.Endpoint, error) {
+	var endpoints []dbmodels.Endpoint
+	err := chi.Walk(r, func(method, pattern string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		endpoints = append(endpoints, dbmodels.Endpoint{
+			HTTPMethod:   method,
+			HTTPEndpoint: pattern,
+			Scope:        middleware.RouteScope(method, pattern),
+		})
+		return nil
+	})
+
+	return endpoints, err
+}
@@ -0,0 +1,42 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/malakagl/go-template/internal/middleware"
+	"github.com/malakagl/go-template/pkg/scopes"
+)
+
+func TestRegisteredEndpoints(t *testing.T) {
+	ok := func(w http.ResponseWriter, _ *http.Request) {}
+	r := chi.NewRouter()
+	r.Get("/health", ok)
+	route(r, http.MethodGet, "/products/{productID}", scopes.ProductsRead, ok)
+	route(r, http.MethodPost, "/orders", scopes.OrdersWrite, ok, middleware.Idempotency)
+
+	endpoints, err := registeredEndpoints(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]string)
+	for _, e := range endpoints {
+		got[e.HTTPMethod+" "+e.HTTPEndpoint] = e.Scope
+	}
+
+	expected := map[string]string{
+		"GET /health":               "",
+		"GET /products/{productID}": scopes.ProductsRead,
+		"POST /orders":              scopes.OrdersWrite,
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("expected endpoints %v, got %v", expected, got)
+	}
+	for ep, scope := range expected {
+		if s, found := got[ep]; !found || s != scope {
+			t.Errorf("expected %s with scope %q, got %q (found %v)", ep, scope, s, found)
+		}
+	}
+}
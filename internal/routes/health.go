@@ -4,11 +4,31 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/malakagl/go-template/pkg/health"
 	"github.com/malakagl/go-template/pkg/models/dto/response"
 )
 
-func AddHealthCheckRoutes(r *chi.Mux) {
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+// AddHealthCheckRoutes serves the liveness probe on /health/live, and on /health for existing probes,
+// and the readiness probe on /health/ready. Readiness runs checks and reports the result of each.
+func AddHealthCheckRoutes(r *chi.Mux, checks *health.Checks) {
+	live := func(w http.ResponseWriter, r *http.Request) {
 		response.Success(w, http.StatusOK, "ok")
+	}
+	r.Get("/health", live)
+	r.Get("/health/live", live)
+
+	r.Get("/health/ready", func(w http.ResponseWriter, r *http.Request) {
+		report := checks.Run(r.Context())
+		if report.Status == health.StatusUnavailable {
+			response.JSON(w, http.StatusServiceUnavailable, response.APIResponse{
+				Code:    http.StatusServiceUnavailable,
+				Type:    "Unavailable",
+				Message: http.StatusText(http.StatusServiceUnavailable),
+				Data:    report,
+			})
+			return
+		}
+
+		response.Success(w, http.StatusOK, report)
 	})
 }
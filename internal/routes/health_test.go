@@ -0,0 +1,57 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/malakagl/go-template/pkg/health"
+)
+
+func TestHealthCheckRoutes(t *testing.T) {
+	dbErr := errors.New("connection refused")
+	tests := []struct {
+		name   string
+		path   string
+		dbErr  error
+		status int
+		ready  health.Status
+	}{
+		{name: "live", path: "/health/live", dbErr: dbErr, status: http.StatusOK},
+		{name: "legacy", path: "/health", dbErr: dbErr, status: http.StatusOK},
+		{name: "ready", path: "/health/ready", status: http.StatusOK, ready: health.StatusOK},
+		{name: "not ready", path: "/health/ready", dbErr: dbErr, status: http.StatusServiceUnavailable, ready: health.StatusUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks := health.New(time.Second)
+			checks.Register("database", func(context.Context) error { return tt.dbErr })
+			r := chi.NewRouter()
+			AddHealthCheckRoutes(r, checks)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, w.Code)
+			}
+			if tt.ready == "" {
+				return
+			}
+
+			var res struct {
+				Data health.Report `json:"data"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+				t.Fatal(err)
+			}
+			if res.Data.Status != tt.ready || res.Data.Checks["database"].Status == "" {
+				t.Errorf("expected %s with the database check, got %+v", tt.ready, res.Data)
+			}
+		})
+	}
+}
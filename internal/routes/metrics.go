@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/malakagl/go-template/pkg/metrics"
+)
+
+// AddMetricsRoutes serves the Prometheus metrics, like health checks without authentication
+func AddMetricsRoutes(r *chi.Mux) {
+	r.Method(http.MethodGet, "/metrics", metrics.Handler())
+}
@@ -1,19 +1,32 @@
 package routes
 
 import (
+	"net/http"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/malakagl/go-template/internal/api/handlers"
+	"github.com/malakagl/go-template/internal/couponcode"
+	"github.com/malakagl/go-template/internal/middleware"
+	dbmodels "github.com/malakagl/go-template/pkg/models/db"
 	"github.com/malakagl/go-template/pkg/repositories"
+	"github.com/malakagl/go-template/pkg/scopes"
 	"github.com/malakagl/go-template/pkg/services"
 	"gorm.io/gorm"
 )
 
-func AddOrderRoutes(r *chi.Mux, db *gorm.DB) {
+func AddOrderRoutes(r *chi.Mux, db *gorm.DB, couponValidator couponcode.CouponValidator) {
 	productRepo := repositories.NewProductRepo(db)
 	orderRepo := repositories.NewOrderRepo(db)
-	couponCodeRepo := repositories.NewCouponCodeRepository(db)
-	orderService := services.NewOrderService(orderRepo, couponCodeRepo, productRepo)
+	promotionRepo := repositories.NewPromotionRepo(db)
+	auditLogger := services.NewAuditLogger(repositories.NewAuditRepo(db))
+	orderService := services.NewOrderService(orderRepo, couponValidator, productRepo, promotionRepo, &auditLogger)
 	orderHandler := handlers.NewOrderHandler(&orderService)
 
-	r.Post("/orders", orderHandler.CreateOrder)
+	route(r, http.MethodPost, "/orders", scopes.OrdersWrite, orderHandler.CreateOrder, middleware.Idempotency)
+	route(r, http.MethodGet, "/orders", scopes.OrdersRead, orderHandler.ListOrders)
+	route(r, http.MethodGet, "/orders/{orderID}", scopes.OrdersRead, orderHandler.GetOrder)
+	route(r, http.MethodPost, "/orders/{orderID}/pay", scopes.OrdersWrite, orderHandler.UpdateStatus(dbmodels.OrderStatusPaid))
+	route(r, http.MethodPost, "/orders/{orderID}/fulfil", scopes.OrdersWrite, orderHandler.UpdateStatus(dbmodels.OrderStatusFulfilled))
+	route(r, http.MethodPost, "/orders/{orderID}/cancel", scopes.OrdersWrite, orderHandler.UpdateStatus(dbmodels.OrderStatusCancelled))
+	route(r, http.MethodPost, "/orders/{orderID}/refund", scopes.OrdersWrite, orderHandler.UpdateStatus(dbmodels.OrderStatusRefunded))
 }
@@ -1,17 +1,26 @@
 package routes
 
 import (
+	"net/http"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/malakagl/go-template/internal/api/handlers"
 	"github.com/malakagl/go-template/pkg/repositories"
+	"github.com/malakagl/go-template/pkg/scopes"
 	"github.com/malakagl/go-template/pkg/services"
 	"gorm.io/gorm"
 )
 
 func AddProductRoutes(r *chi.Mux, db *gorm.DB) {
 	productRepo := repositories.NewProductRepo(db)
-	productService := services.NewProductService(productRepo)
+	auditLogger := services.NewAuditLogger(repositories.NewAuditRepo(db))
+	productService := services.NewProductService(productRepo, &auditLogger)
 	productHandler := handlers.NewProductHandler(&productService)
-	r.Get("/products", productHandler.ListProducts)
-	r.Get("/products/{productID}", productHandler.GetProductByID)
+	route(r, http.MethodGet, "/products", scopes.ProductsRead, productHandler.ListProducts)
+	route(r, http.MethodGet, "/products/{productID}", scopes.ProductsRead, productHandler.GetProductByID)
+
+	route(r, http.MethodPost, "/admin/products", scopes.ProductsWrite, productHandler.CreateProduct)
+	route(r, http.MethodPut, "/admin/products/{productID}", scopes.ProductsWrite, productHandler.UpdateProduct)
+	route(r, http.MethodDelete, "/admin/products/{productID}", scopes.ProductsWrite, productHandler.DeleteProduct)
+	route(r, http.MethodPost, "/admin/products/{productID}/restore", scopes.ProductsWrite, productHandler.RestoreProduct)
 }
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/malakagl/go-template/internal/middleware"
+)
+
+// route registers h for method and pattern and binds scope to the route. Only keys holding scope,
+// or granted the route, get past middleware.Authorize to the route middlewares mws and h.
+func route(r chi.Router, method, pattern, scope string, h http.HandlerFunc, mws ...func(http.Handler) http.Handler) {
+	middleware.BindScope(method, pattern, scope)
+	r.With(middleware.Authorize).With(mws...).Method(method, pattern, h)
+}
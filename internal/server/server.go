@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/malakagl/go-template/internal/config"
@@ -12,8 +13,15 @@ import (
 	"github.com/malakagl/go-template/internal/middleware"
 	"github.com/malakagl/go-template/internal/routes"
 	"github.com/malakagl/go-template/pkg/errors"
+	"github.com/malakagl/go-template/pkg/health"
 	"github.com/malakagl/go-template/pkg/log"
+	"github.com/malakagl/go-template/pkg/meta"
+	"github.com/malakagl/go-template/pkg/metrics"
 	"github.com/malakagl/go-template/pkg/otel"
+	"github.com/malakagl/go-template/pkg/ratelimit"
+	"github.com/malakagl/go-template/pkg/repositories"
+	"github.com/malakagl/go-template/pkg/services"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"gorm.io/gorm"
 )
 
@@ -22,6 +30,8 @@ type Server struct {
 	ErrChan    chan error
 	db         *gorm.DB
 	cfg        *config.Config
+	tp         *sdktrace.TracerProvider
+	cancel     context.CancelFunc // stops the background work started with the server
 }
 
 func NewServer(c *config.Config) *Server {
@@ -32,14 +42,24 @@ func NewServer(c *config.Config) *Server {
 }
 
 // Start sets up the database, coupon codes, routes, and starts the HTTP server.
-// Returns the server instance for later shutdown.
-func (s *Server) Start() error {
-	ctx := context.Background()
+// Returns the server instance for later shutdown. When a step fails, what was set up before it is stopped.
+func (s *Server) Start() (err error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	defer func() {
+		if err != nil {
+			stopCtx, stop := context.WithTimeout(context.Background(), s.cfg.Server.GracefulTimeout)
+			defer stop()
+			_ = s.Stop(stopCtx)
+		}
+	}()
+
 	if s.cfg.Telemetry.Enabled {
-		_, err := otel.InitTracer("go-template", fmt.Sprintf("%s:%d", s.cfg.Telemetry.Host, s.cfg.Telemetry.Port))
+		tp, err := otel.InitTracer(ctx, s.cfg.Telemetry, meta.Get())
 		if err != nil {
 			return err
 		}
+		s.tp = tp
 	}
 
 	if err := database.RunMigrations(ctx, &s.cfg.Database); err != nil {
@@ -47,36 +67,88 @@ func (s *Server) Start() error {
 		return err
 	}
 
-	couponcode.SetCouponCodeFiles(s.cfg.CouponCode.FilePaths)
-	go func(ctx context.Context) {
-		ctx, span := otel.Tracer(ctx, "decompress-coupon-files")
-		defer span.End()
-
-		log.Info().Msg("Started decompressing coupon code files in background")
-		if errD := couponcode.SetupCouponCodeFiles(ctx, s.cfg.CouponCode.FilePaths); errD != nil {
-			span.RecordError(errD)
-			log.Error().Err(errD).Msg("coupon code setup failed.")
-		}
-	}(ctx)
-
 	couponcode.InitCache(s.cfg.Server.MaxCouponCodeCacheSize)
 	log.Info().Msgf("connecting to database")
-	var err error
 	s.db, err = database.Connect(ctx, &s.cfg.Database)
 	if err != nil {
 		log.Error().Err(err).Msg("failed to connect to database.")
 		return err
 	}
 
+	if sqlDB, errDB := s.db.DB(); errDB == nil {
+		if errDB = metrics.RegisterDB(s.cfg.Database.Name, sqlDB); errDB != nil {
+			log.Warn().Err(errDB).Msg("failed to register database metrics")
+		}
+	}
+
+	log.Info().Msgf("setting up %q coupon code validator", s.cfg.CouponCode.Backend)
+	couponValidator, err := couponcode.NewValidator(ctx, s.cfg.CouponCode, s.db)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to set up coupon code validator.")
+		return err
+	}
+	couponValidator = couponcode.WithMetrics(couponValidator, s.cfg.CouponCode.Backend)
+
+	if s.cfg.CouponCode.Backend == couponcode.BackendFile {
+		go func(ctx context.Context) {
+			ctx, span := otel.Tracer(ctx, "index-coupon-files")
+			defer span.End()
+
+			log.Info().Msg("Started indexing coupon code files in background")
+			if errD := couponcode.SetupCouponCodeFiles(ctx, s.cfg.CouponCode.FilePaths); errD != nil {
+				span.RecordError(errD)
+				log.Error().Err(errD).Msg("coupon code setup failed.")
+			}
+		}(ctx)
+	}
+
 	log.Info().Msgf("creating routes")
-	middleware.SetRateLimits(s.cfg.Server.ReqLimitPerIP, s.cfg.Server.ReqBurstPerIP, s.cfg.Server.ReqRateWindow)
+	if err = middleware.InitClientIP(s.cfg.Server.TrustedProxies, s.cfg.Server.ClientIPHeader); err != nil {
+		log.Error().Err(err).Msg("failed to set up trusted proxies.")
+		return err
+	}
+
+	if err = middleware.InitIPRules(s.cfg.Server.IPAllowlist, s.cfg.Server.IPDenylist); err != nil {
+		log.Error().Err(err).Msg("failed to set up ip rules.")
+		return err
+	}
+
+	log.Info().Msgf("setting up %q rate limiter", s.cfg.Server.RateLimitBackend)
+	var limiter ratelimit.Limiter = ratelimit.NewMemoryLimiter()
+	if s.cfg.Server.RateLimitBackend == ratelimit.BackendDatabase {
+		limiter = repositories.NewRateLimitRepo(s.db)
+	}
+	middleware.InitRateLimit(ctx, s.rateLimitPolicy(), limiter, s.cfg.Server.ReqRateWindow)
 	middleware.InitAuth(s.db, s.cfg.Server.MaxAPIKeyCacheSize, s.cfg.Server.MaxAPIKeyCacheTTL)
+	middleware.WatchAPIKeyChanges(ctx, database.NewNotifier(s.db, &s.cfg.Database))
+	auditLogger := services.NewAuditLogger(repositories.NewAuditRepo(s.db))
+	middleware.InitAudit(ctx, &auditLogger)
+	if s.cfg.Auth.HMAC.Enabled {
+		if err = middleware.InitRequestSigning(ctx, s.db, s.cfg.Auth.HMAC.SecretKey, s.cfg.Auth.HMAC.MaxClockSkew); err != nil {
+			log.Error().Err(err).Msg("failed to set up request signing.")
+			return err
+		}
+	}
+
+	if jwtCfg := s.cfg.Auth.JWT; jwtCfg.Enabled {
+		err = middleware.InitBearerAuth(ctx, jwtCfg.JWKS, jwtCfg.Issuer, jwtCfg.Audience, jwtCfg.ScopeClaim, jwtCfg.ClockSkew, jwtCfg.JWKSRefreshInterval)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to set up bearer token authentication.")
+			return err
+		}
+	}
+	middleware.InitIdempotency(ctx, s.db, s.cfg.Server.IdempotencyKeyTTL)
 	r := chi.NewRouter()
-	r.Use(middleware.Trace, middleware.Logging, middleware.Authentication, middleware.RateLimit)
-	routes.AddHealthCheckRoutes(r)
+	r.Use(middleware.Metrics, middleware.ClientIP, middleware.Trace, middleware.Logging, middleware.Authentication, middleware.RateLimit)
+	routes.AddHealthCheckRoutes(r, s.healthChecks())
+	routes.AddMetricsRoutes(r)
 	routes.AddProductRoutes(r, s.db)
-	routes.AddOrderRoutes(r, s.db)
+	routes.AddOrderRoutes(r, s.db, couponValidator)
 	routes.AddAdminRoutes(r, s.db)
+	if err = routes.SyncEndpoints(ctx, r, s.db); err != nil {
+		log.Error().Err(err).Msg("failed to sync endpoints.")
+		return err
+	}
 
 	serverAddr := fmt.Sprintf("%s:%d", s.cfg.Server.Host, s.cfg.Server.Port)
 	s.httpServer = &http.Server{
@@ -95,6 +167,40 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// healthChecks are the dependencies the service is not ready without, the tracer exporter is only reported
+func (s *Server) healthChecks() *health.Checks {
+	checks := health.New(s.cfg.Server.HealthCheckTimeout)
+	checks.Register("database", database.PingCheck(s.db))
+	checks.Register("migrations", database.MigrationsCheck(s.db, &s.cfg.Database))
+	if s.cfg.CouponCode.Backend == couponcode.BackendFile {
+		checks.Register("couponCodeFiles", couponcode.FilesReady)
+	}
+
+	if s.cfg.Telemetry.Enabled && strings.HasPrefix(s.cfg.Telemetry.Exporter, "otlp") {
+		checks.RegisterOptional("tracer", otel.ExporterReachable(otel.Endpoint(s.cfg.Telemetry)))
+	}
+
+	return checks
+}
+
+// rateLimitPolicy gives keys without a tier the limits of requests by IP
+func (s *Server) rateLimitPolicy() ratelimit.Policy {
+	policy := ratelimit.Policy{
+		Default: ratelimit.Limits{Rate: s.cfg.Server.ReqLimitPerIP, Burst: s.cfg.Server.ReqBurstPerIP},
+		Tiers:   make(map[string]ratelimit.Limits, len(s.cfg.Server.RateLimitTiers)),
+	}
+	for name, tier := range s.cfg.Server.RateLimitTiers {
+		policy.Tiers[name] = ratelimit.Limits{
+			Rate:         tier.RequestsPerSecond,
+			Burst:        tier.Burst,
+			DailyQuota:   tier.DailyQuota,
+			MonthlyQuota: tier.MonthlyQuota,
+		}
+	}
+
+	return policy
+}
+
 // Stop gracefully shuts down the server with a context timeout.
 func (s *Server) Stop(ctx context.Context) error {
 	if s.httpServer != nil {
@@ -104,6 +210,10 @@ func (s *Server) Stop(ctx context.Context) error {
 		}
 	}
 
+	if s.cancel != nil {
+		s.cancel()
+	}
+
 	if s.db != nil {
 		db, err := s.db.DB()
 		if err == nil {
@@ -117,6 +227,15 @@ func (s *Server) Stop(ctx context.Context) error {
 		}
 	}
 
+	// flush the spans of the last requests
+	if s.tp != nil {
+		if err := s.tp.Shutdown(ctx); err != nil {
+			log.Error().Err(err).Msg("failed to shut down tracer provider")
+		} else {
+			log.Info().Msg("Tracer provider shut down successfully")
+		}
+	}
+
 	log.Info().Msg("Host stopped successfully")
 	return nil
 }
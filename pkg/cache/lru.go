@@ -13,6 +13,16 @@ type LRUCache[V any] struct {
 	cacheTTL  time.Duration
 	items     map[string]*list.Element
 	evictList *list.List // most recent → the least recent
+	stats     Stats
+}
+
+// Stats counts the lookups and evictions of a cache since it was created
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64 // least recently used items dropped to make room
+	Expired   uint64 // items dropped by RemoveExpired
+	Size      int
 }
 
 type entry[V any] struct {
@@ -37,10 +47,12 @@ func (c *LRUCache[V]) Get(key string) (V, bool) {
 	defer c.mu.Unlock()
 
 	if ele, ok := c.items[key]; ok {
+		c.stats.Hits++
 		c.evictList.MoveToFront(ele)
 		return ele.Value.(*entry[V]).value, true
 	}
 
+	c.stats.Misses++
 	var zero V
 	return zero, false
 }
@@ -72,15 +84,54 @@ func (c *LRUCache[V]) removeOldest() {
 		c.evictList.Remove(ele)
 		ent := ele.Value.(*entry[V])
 		delete(c.items, ent.key)
+		c.stats.Evictions++
 	}
 }
 
 func (c *LRUCache[V]) RemoveExpired() {
-	for k, v := range c.items {
-		if v.Value.(*entry[V]).expiresAt.Before(time.Now()) {
-			c.mu.Lock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for k, ele := range c.items {
+		if ele.Value.(*entry[V]).expiresAt.Before(now) {
+			c.evictList.Remove(ele)
+			delete(c.items, k)
+			c.stats.Expired++
+		}
+	}
+}
+
+// Remove deletes key from the cache.
+func (c *LRUCache[V]) Remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ele, ok := c.items[key]; ok {
+		c.evictList.Remove(ele)
+		delete(c.items, key)
+	}
+}
+
+// RemoveFunc deletes every item match returns true for. match runs with the cache locked.
+func (c *LRUCache[V]) RemoveFunc(match func(key string, value V) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, ele := range c.items {
+		if match(k, ele.Value.(*entry[V]).value) {
+			c.evictList.Remove(ele)
 			delete(c.items, k)
-			c.mu.Unlock()
 		}
 	}
 }
+
+// Stats returns the lookups and evictions counted so far and the current number of items
+func (c *LRUCache[V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.stats
+	s.Size = len(c.items)
+	return s
+}
@@ -3,3 +3,12 @@ package constants
 type contextKey string
 
 const ParentSpanId contextKey = "parentSpanId"
+
+// ClientID is the client ID of the API key that authenticated the request
+const ClientID contextKey = "clientId"
+
+// ClientIP is the IP of the client, resolved from forwarding headers of trusted proxies
+const ClientIP contextKey = "clientIp"
+
+// APIKey is the *db.APIKey that authenticated the request, routes authorize against its scopes and grants
+const APIKey contextKey = "apiKey"
@@ -0,0 +1,143 @@
+package discount
+
+import (
+	"math"
+	"strings"
+
+	"github.com/malakagl/go-template/pkg/models/db"
+	"github.com/malakagl/go-template/pkg/money"
+)
+
+// Promotion types
+const (
+	TypePercentage       = "percentage"
+	TypeFixedAmount      = "fixed_amount"
+	TypeBuyXGetY         = "buy_x_get_y"
+	TypeCheapestItemFree = "cheapest_item_free"
+)
+
+// Line is an order line the promotion is applied to.
+type Line struct {
+	ProductID string
+	Category  string
+	UnitPrice money.Money
+	Quantity  int
+}
+
+// Result holds the discounts of an order. LineDiscounts is aligned with the lines passed to Apply.
+type Result struct {
+	Subtotal      money.Money
+	LineDiscounts []money.Money
+	OrderDiscount money.Money
+	Discounts     money.Money
+	Total         money.Money
+}
+
+// Apply computes the discounts promotion p grants on lines priced in currency. A nil promotion grants no discount.
+// Discounts never exceed the price of what they apply to and are rounded to the minor unit.
+// Lines, or a fixed amount promotion, in another currency fail with ErrCurrencyMismatch.
+func Apply(p *db.Promotion, currency string, lines []Line) (Result, error) {
+	res := Result{
+		Subtotal:      money.Zero(currency),
+		LineDiscounts: make([]money.Money, len(lines)),
+		OrderDiscount: money.Zero(currency),
+	}
+	for i, l := range lines {
+		var err error
+		if res.Subtotal, err = res.Subtotal.Add(l.UnitPrice.Mul(l.Quantity)); err != nil {
+			return Result{}, err
+		}
+		res.LineDiscounts[i] = money.Zero(currency)
+	}
+
+	if p != nil {
+		switch p.Type {
+		case TypePercentage:
+			applyPercentage(p, lines, res.LineDiscounts)
+		case TypeFixedAmount:
+			var err error
+			if res.OrderDiscount, err = applyFixedAmount(p, currency, lines); err != nil {
+				return Result{}, err
+			}
+		case TypeBuyXGetY:
+			applyBuyXGetY(p, lines, res.LineDiscounts)
+		case TypeCheapestItemFree:
+			applyCheapestItemFree(p, lines, res.LineDiscounts)
+		}
+	}
+
+	discounts, err := money.Sum(currency, append(res.LineDiscounts, res.OrderDiscount)...)
+	if err != nil {
+		return Result{}, err
+	}
+
+	res.Discounts = discounts
+	res.Total, err = res.Subtotal.Sub(discounts)
+	return res, err
+}
+
+func eligible(p *db.Promotion, l Line) bool {
+	return l.Quantity > 0 && (p.Category == "" || strings.EqualFold(p.Category, l.Category))
+}
+
+func applyPercentage(p *db.Promotion, lines []Line, discounts []money.Money) {
+	pct := math.Min(math.Max(p.Value, 0), 100)
+	for i, l := range lines {
+		if eligible(p, l) {
+			discounts[i] = l.UnitPrice.Mul(l.Quantity).Percent(pct)
+		}
+	}
+}
+
+func applyFixedAmount(p *db.Promotion, currency string, lines []Line) (money.Money, error) {
+	eligibleTotal := money.Zero(currency)
+	for _, l := range lines {
+		if eligible(p, l) {
+			eligibleTotal.Amount += l.UnitPrice.Amount * int64(l.Quantity)
+		}
+	}
+
+	off, err := money.Zero(currency).Add(p.AmountOff)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	off.Amount = min(max(off.Amount, 0), eligibleTotal.Amount)
+	return off, nil
+}
+
+// applyBuyXGetY makes Y of every X+Y units of each eligible line free.
+func applyBuyXGetY(p *db.Promotion, lines []Line, discounts []money.Money) {
+	if p.BuyQuantity <= 0 || p.GetQuantity <= 0 {
+		return
+	}
+
+	for i, l := range lines {
+		if eligible(p, l) {
+			free := l.Quantity / (p.BuyQuantity + p.GetQuantity) * p.GetQuantity
+			discounts[i] = l.UnitPrice.Mul(free)
+		}
+	}
+}
+
+// applyCheapestItemFree makes one unit of the cheapest eligible line free,
+// once the order holds at least BuyQuantity eligible units.
+func applyCheapestItemFree(p *db.Promotion, lines []Line, discounts []money.Money) {
+	cheapest, units := -1, 0
+	for i, l := range lines {
+		if !eligible(p, l) {
+			continue
+		}
+
+		units += l.Quantity
+		if cheapest == -1 || l.UnitPrice.Amount < lines[cheapest].UnitPrice.Amount {
+			cheapest = i
+		}
+	}
+
+	if cheapest == -1 || units < p.BuyQuantity {
+		return
+	}
+
+	discounts[cheapest] = lines[cheapest].UnitPrice
+}
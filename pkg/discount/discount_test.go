@@ -0,0 +1,114 @@
+package discount
+
+import (
+	"testing"
+
+	"github.com/malakagl/go-template/pkg/errors"
+	"github.com/malakagl/go-template/pkg/models/db"
+	"github.com/malakagl/go-template/pkg/money"
+)
+
+func TestApply(t *testing.T) {
+	lines := []Line{
+		{ProductID: "1", Category: "Waffle", UnitPrice: money.New(1000, "USD"), Quantity: 3},
+		{ProductID: "2", Category: "Cake", UnitPrice: money.New(450, "USD"), Quantity: 2},
+	}
+
+	tests := []struct {
+		name          string
+		promotion     *db.Promotion
+		lineDiscounts []int64
+		orderDiscount int64
+		total         int64
+	}{
+		{
+			name:          "no promotion",
+			lineDiscounts: []int64{0, 0},
+			total:         3900,
+		},
+		{
+			name:          "percentage off",
+			promotion:     &db.Promotion{Type: TypePercentage, Value: 10},
+			lineDiscounts: []int64{300, 90},
+			total:         3510,
+		},
+		{
+			name:          "category scoped percentage",
+			promotion:     &db.Promotion{Type: TypePercentage, Value: 50, Category: "cake"},
+			lineDiscounts: []int64{0, 450},
+			total:         3450,
+		},
+		{
+			name:          "fixed amount",
+			promotion:     &db.Promotion{Type: TypeFixedAmount, AmountOff: money.New(500, "USD")},
+			lineDiscounts: []int64{0, 0},
+			orderDiscount: 500,
+			total:         3400,
+		},
+		{
+			name:          "fixed amount capped at eligible total",
+			promotion:     &db.Promotion{Type: TypeFixedAmount, AmountOff: money.New(2000, "USD"), Category: "Cake"},
+			lineDiscounts: []int64{0, 0},
+			orderDiscount: 900,
+			total:         3000,
+		},
+		{
+			name:          "buy 2 get 1",
+			promotion:     &db.Promotion{Type: TypeBuyXGetY, BuyQuantity: 2, GetQuantity: 1},
+			lineDiscounts: []int64{1000, 0},
+			total:         2900,
+		},
+		{
+			name:          "cheapest item free",
+			promotion:     &db.Promotion{Type: TypeCheapestItemFree, BuyQuantity: 3},
+			lineDiscounts: []int64{0, 450},
+			total:         3450,
+		},
+		{
+			name:          "cheapest item free below minimum items",
+			promotion:     &db.Promotion{Type: TypeCheapestItemFree, BuyQuantity: 6},
+			lineDiscounts: []int64{0, 0},
+			total:         3900,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Apply(tt.promotion, "USD", lines)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res.Subtotal != money.New(3900, "USD") {
+				t.Errorf("expected subtotal 39.00 USD, got %v", res.Subtotal)
+			}
+			for i, d := range tt.lineDiscounts {
+				if res.LineDiscounts[i] != money.New(d, "USD") {
+					t.Errorf("expected line %d discount %d, got %v", i, d, res.LineDiscounts[i])
+				}
+			}
+			if res.OrderDiscount != money.New(tt.orderDiscount, "USD") {
+				t.Errorf("expected order discount %d, got %v", tt.orderDiscount, res.OrderDiscount)
+			}
+			if res.Total != money.New(tt.total, "USD") {
+				t.Errorf("expected total %d, got %v", tt.total, res.Total)
+			}
+			if res.Discounts.Amount != res.Subtotal.Amount-res.Total.Amount {
+				t.Errorf("expected discounts %d, got %v", res.Subtotal.Amount-res.Total.Amount, res.Discounts)
+			}
+		})
+	}
+}
+
+func TestApplyRejectsMixedCurrencies(t *testing.T) {
+	lines := []Line{
+		{ProductID: "1", UnitPrice: money.New(1000, "USD"), Quantity: 1},
+		{ProductID: "2", UnitPrice: money.New(1000, "EUR"), Quantity: 1},
+	}
+	if _, err := Apply(nil, "USD", lines); !errors.Is(err, errors.ErrCurrencyMismatch) {
+		t.Errorf("expected currency mismatch for mixed lines, got %v", err)
+	}
+
+	promotion := &db.Promotion{Type: TypeFixedAmount, AmountOff: money.New(500, "EUR")}
+	if _, err := Apply(promotion, "USD", lines[:1]); !errors.Is(err, errors.ErrCurrencyMismatch) {
+		t.Errorf("expected currency mismatch for fixed amount promotion, got %v", err)
+	}
+}
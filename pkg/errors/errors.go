@@ -8,8 +8,21 @@ var (
 	ErrInvalidProductID    = errors.New("invalid product ID")
 	ErrInternalServerError = errors.New("internal server error")
 	ErrDatabaseError       = errors.New("database query returned error")
+	ErrPromotionNotFound   = errors.New("promotion not found")
+	ErrOrderNotFound       = errors.New("order not found")
+	ErrInvalidOrderID      = errors.New("invalid order ID")
+	ErrInvalidTransition   = errors.New("invalid order status transition")
+	ErrInvalidCursor       = errors.New("invalid pagination cursor")
+	ErrInsufficientStock   = errors.New("insufficient stock")
+	ErrCurrencyMismatch    = errors.New("currency mismatch")
 
 	ErrEndpointsNotFound = errors.New("endpoints not found")
+	ErrAPIKeyNotFound    = errors.New("api key not found")
+	ErrAPIKeyRevoked     = errors.New("api key is revoked")
+	ErrGrantNotFound     = errors.New("endpoint is not granted to the api key")
+	ErrUnknownScope      = errors.New("unknown scope or role")
+	ErrUnknownTier       = errors.New("unknown rate limit tier")
+	ErrSigningDisabled   = errors.New("request signing is disabled")
 	ErrBadRequest        = errors.New("bad request")
 )
 
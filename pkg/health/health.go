@@ -0,0 +1,115 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Status of a check or of the service
+type Status string
+
+const (
+	StatusOK          Status = "ok"
+	StatusDegraded    Status = "degraded"    // optional checks failed, the service is still ready
+	StatusUnavailable Status = "unavailable" // required checks failed
+)
+
+// Check reports whether a dependency is usable, the error says why it is not
+type Check func(ctx context.Context) error
+
+type checker struct {
+	name     string
+	check    Check
+	optional bool
+}
+
+// Checks are the dependency checks of the readiness probe
+type Checks struct {
+	mu       sync.RWMutex
+	checkers []checker
+	timeout  time.Duration
+}
+
+// New returns Checks that give each check timeout to complete
+func New(timeout time.Duration) *Checks {
+	return &Checks{timeout: timeout}
+}
+
+// Register adds a check the service is not ready without
+func (c *Checks) Register(name string, check Check) {
+	c.add(checker{name: name, check: check})
+}
+
+// RegisterOptional adds a check that is reported, but that does not make the service unready when it fails
+func (c *Checks) RegisterOptional(name string, check Check) {
+	c.add(checker{name: name, check: check, optional: true})
+}
+
+func (c *Checks) add(ch checker) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checkers = append(c.checkers, ch)
+}
+
+// Result is the outcome of a check
+type Result struct {
+	Status   Status `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Optional bool   `json:"optional,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report is the outcome of all checks by name
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Run runs the checks concurrently. The service is unavailable when a required check fails.
+func (c *Checks) Run(ctx context.Context) Report {
+	c.mu.RLock()
+	checkers := c.checkers
+	c.mu.RUnlock()
+
+	results := make([]Result, len(checkers))
+	var wg sync.WaitGroup
+	for i, ch := range checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, ch)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(checkers))}
+	for i, ch := range checkers {
+		report.Checks[ch.name] = results[i]
+		if results[i].Status == StatusOK {
+			continue
+		}
+
+		if !ch.optional {
+			report.Status = StatusUnavailable
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+
+	return report
+}
+
+func (c *Checks) run(ctx context.Context, ch checker) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := ch.check(ctx)
+	res := Result{Status: StatusOK, Optional: ch.optional, Duration: time.Since(start).String()}
+	if err != nil {
+		res.Status, res.Error = StatusUnavailable, err.Error()
+	}
+
+	return res
+}
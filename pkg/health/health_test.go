@@ -0,0 +1,55 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	failing := func(context.Context) error { return errors.New("connection refused") }
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name     string
+		required map[string]Check
+		optional map[string]Check
+		status   Status
+		failed   []string
+	}{
+		{name: "all ok", required: map[string]Check{"database": ok}, optional: map[string]Check{"tracer": ok}, status: StatusOK},
+		{name: "optional failing", required: map[string]Check{"database": ok}, optional: map[string]Check{"tracer": failing}, status: StatusDegraded, failed: []string{"tracer"}},
+		{name: "required failing", required: map[string]Check{"database": failing}, optional: map[string]Check{"tracer": failing}, status: StatusUnavailable, failed: []string{"database", "tracer"}},
+		{name: "timed out", required: map[string]Check{"database": slow}, status: StatusUnavailable, failed: []string{"database"}},
+		{name: "no checks", status: StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(10 * time.Millisecond)
+			for name, check := range tt.required {
+				c.Register(name, check)
+			}
+			for name, check := range tt.optional {
+				c.RegisterOptional(name, check)
+			}
+
+			report := c.Run(t.Context())
+			if report.Status != tt.status {
+				t.Errorf("expected status %s, got %s", tt.status, report.Status)
+			}
+			if len(report.Checks) != len(tt.required)+len(tt.optional) {
+				t.Errorf("expected a result per check, got %v", report.Checks)
+			}
+			for _, name := range tt.failed {
+				if res := report.Checks[name]; res.Status != StatusUnavailable || res.Error == "" {
+					t.Errorf("expected %s to fail with an error, got %+v", name, res)
+				}
+			}
+		})
+	}
+}
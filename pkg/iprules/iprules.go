@@ -0,0 +1,80 @@
+package iprules
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// List is a list of networks, a single IP is a network of one address
+type List []netip.Prefix
+
+// Parse parses CIDRs and IPs
+func Parse(cidrs []string) (List, error) {
+	l := make(List, 0, len(cidrs))
+	for _, c := range cidrs {
+		prefix, err := netip.ParsePrefix(c)
+		if err != nil {
+			addr, errAddr := netip.ParseAddr(c)
+			if errAddr != nil {
+				return nil, fmt.Errorf("invalid CIDR or IP %q", c)
+			}
+
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		l = append(l, prefix.Masked())
+	}
+
+	return l, nil
+}
+
+// Contains reports whether addr is in one of the networks, IPv4-mapped IPv6 addresses match as IPv4
+func (l List) Contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range l {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Rules permit the addresses of Allow, or any address when Allow is empty, unless they are in Deny
+type Rules struct {
+	Allow List
+	Deny  List
+}
+
+// ParseRules parses the space separated lists stored with api keys, see Format
+func ParseRules(allow, deny string) (Rules, error) {
+	var r Rules
+	var err error
+	if r.Allow, err = Parse(strings.Fields(allow)); err != nil {
+		return Rules{}, err
+	}
+	if r.Deny, err = Parse(strings.Fields(deny)); err != nil {
+		return Rules{}, err
+	}
+
+	return r, nil
+}
+
+// Empty reports whether the rules permit every address
+func (r Rules) Empty() bool {
+	return len(r.Allow) == 0 && len(r.Deny) == 0
+}
+
+// Permits reports whether the rules permit addr
+func (r Rules) Permits(addr netip.Addr) bool {
+	if r.Deny.Contains(addr) {
+		return false
+	}
+
+	return len(r.Allow) == 0 || r.Allow.Contains(addr)
+}
+
+// Format joins cidrs into the space separated form read by ParseRules
+func Format(cidrs []string) string {
+	return strings.Join(cidrs, " ")
+}
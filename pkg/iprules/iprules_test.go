@@ -0,0 +1,50 @@
+package iprules
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestRulesPermits(t *testing.T) {
+	tests := []struct {
+		name  string
+		allow string
+		deny  string
+		addr  string
+		want  bool
+	}{
+		{name: "no rules", addr: "203.0.113.7", want: true},
+		{name: "allowed", allow: "10.0.0.0/8 203.0.113.0/24", addr: "203.0.113.7", want: true},
+		{name: "not allowed", allow: "10.0.0.0/8", addr: "203.0.113.7", want: false},
+		{name: "single ip", allow: "203.0.113.7", addr: "203.0.113.7", want: true},
+		{name: "denied", deny: "203.0.113.0/24", addr: "203.0.113.7", want: false},
+		{name: "deny wins", allow: "203.0.113.0/24", deny: "203.0.113.7/32", addr: "203.0.113.7", want: false},
+		{name: "ipv4 mapped", allow: "203.0.113.0/24", addr: "::ffff:203.0.113.7", want: true},
+		{name: "ipv6", allow: "2001:db8::/32", addr: "2001:db8::1", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRules(tt.allow, tt.deny)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := r.Permits(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	l, err := Parse([]string{"10.1.2.3/8", "::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l[0].String() != "10.0.0.0/8" || l[1].String() != "::1/128" {
+		t.Errorf("unexpected list %v", l)
+	}
+
+	if _, err := Parse([]string{"office"}); err == nil {
+		t.Error("expected invalid CIDR to be rejected")
+	}
+}
@@ -0,0 +1,188 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Accepted signature algorithms
+const (
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+var (
+	ErrMalformed      = errors.New("malformed token")
+	ErrUnsupportedAlg = errors.New("unsupported signature algorithm")
+	ErrUnknownKey     = errors.New("unknown signing key")
+	ErrSignature      = errors.New("invalid signature")
+	ErrExpired        = errors.New("token expired")
+	ErrNotYetValid    = errors.New("token not yet valid")
+	ErrIssuer         = errors.New("unexpected issuer")
+	ErrAudience       = errors.New("unexpected audience")
+)
+
+// Claims are the verified claims of a token
+type Claims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+
+	all map[string]any
+}
+
+// Strings returns claim name as a list, splitting a string claim on spaces like the OAuth scope claim
+func (c *Claims) Strings(name string) []string {
+	switch v := c.all[name].(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		var res []string
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	default:
+		return nil
+	}
+}
+
+// audience is the aud claim, a single string or a list of strings
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+
+	*a = list
+	return nil
+}
+
+// Verifier verifies RS256 and ES256 signed tokens of an issuer meant for an audience
+type Verifier struct {
+	issuer    string
+	audience  string
+	clockSkew time.Duration
+	keys      atomic.Pointer[KeySet]
+}
+
+func NewVerifier(keys *KeySet, issuer, audience string, clockSkew time.Duration) *Verifier {
+	v := &Verifier{issuer: issuer, audience: audience, clockSkew: clockSkew}
+	v.keys.Store(keys)
+	return v
+}
+
+// SetKeys replaces the verification keys, e.g. after the identity provider rotated them
+func (v *Verifier) SetKeys(keys *KeySet) {
+	v.keys.Store(keys)
+}
+
+// Verify checks the signature, issuer, audience and validity period of token and returns its claims.
+// Tokens must expire.
+func (v *Verifier) Verify(token string, now time.Time) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrMalformed
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	key, ok := v.keys.Load().key(header.Kid)
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if header.Alg != RS256 {
+			return nil, ErrUnsupportedAlg
+		}
+		if rsa.VerifyPKCS1v15(k, crypto.SHA256, hash[:], signature) != nil {
+			return nil, ErrSignature
+		}
+	case *ecdsa.PublicKey:
+		if header.Alg != ES256 {
+			return nil, ErrUnsupportedAlg
+		}
+		if len(signature) != 64 {
+			return nil, ErrSignature
+		}
+		r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(k, hash[:], r, s) {
+			return nil, ErrSignature
+		}
+	default:
+		return nil, ErrUnsupportedAlg
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrMalformed
+	}
+	if err := decodeSegment(parts[1], &claims.all); err != nil {
+		return nil, ErrMalformed
+	}
+
+	if claims.ExpiresAt == nil || !now.Before(unixTime(*claims.ExpiresAt).Add(v.clockSkew)) {
+		return nil, ErrExpired
+	}
+	if claims.NotBefore != nil && now.Add(v.clockSkew).Before(unixTime(*claims.NotBefore)) {
+		return nil, ErrNotYetValid
+	}
+	if claims.Issuer != v.issuer {
+		return nil, ErrIssuer
+	}
+	if !slices.Contains(claims.Audience, v.audience) {
+		return nil, ErrAudience
+	}
+
+	return &claims, nil
+}
+
+func decodeSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+// unixTime converts a NumericDate, fractions of a second are dropped
+func unixTime(seconds float64) time.Time {
+	return time.Unix(int64(seconds), 0)
+}
@@ -0,0 +1,193 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// testKeys generates an RSA and an EC key and their JWKS
+func testKeys(t *testing.T) (*rsa.PrivateKey, *ecdsa.PrivateKey, []byte) {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks, err := json.Marshal(map[string]any{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+		{"kty": "oct", "kid": "hmac-1", "k": "c2VjcmV0"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rsaKey, ecKey, jwks
+}
+
+// signToken signs claims with key, an *rsa.PrivateKey or an *ecdsa.PrivateKey
+func signToken(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := b64(header) + "." + b64(payload)
+	hash := sha256.Sum256([]byte(signingInput))
+
+	var sig []byte
+	var err error
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, hash[:])
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, hash[:])
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signingInput + "." + b64(sig)
+}
+
+func TestVerify(t *testing.T) {
+	rsaKey, ecKey, jwks := testKeys(t)
+	keys, err := ParseKeySet(jwks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	v := NewVerifier(keys, "https://idp.example.com", "go-template", time.Minute)
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{
+			"iss":   "https://idp.example.com",
+			"aud":   "go-template",
+			"sub":   "billing-service",
+			"exp":   now.Add(time.Hour).Unix(),
+			"scope": "orders:read products:read",
+		}
+		for k, val := range overrides {
+			if val == nil {
+				delete(c, k)
+				continue
+			}
+			c[k] = val
+		}
+		return c
+	}
+
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{name: "rs256", token: signToken(t, RS256, "rsa-1", rsaKey, claims(nil))},
+		{name: "es256", token: signToken(t, ES256, "ec-1", ecKey, claims(nil))},
+		{name: "audience list", token: signToken(t, RS256, "rsa-1", rsaKey, claims(map[string]any{"aud": []string{"other", "go-template"}}))},
+		{name: "expired within skew", token: signToken(t, RS256, "rsa-1", rsaKey, claims(map[string]any{"exp": now.Add(-30 * time.Second).Unix()}))},
+		{name: "expired", token: signToken(t, RS256, "rsa-1", rsaKey, claims(map[string]any{"exp": now.Add(-2 * time.Minute).Unix()})), err: ErrExpired},
+		{name: "no expiry", token: signToken(t, RS256, "rsa-1", rsaKey, claims(map[string]any{"exp": nil})), err: ErrExpired},
+		{name: "not yet valid", token: signToken(t, RS256, "rsa-1", rsaKey, claims(map[string]any{"nbf": now.Add(time.Hour).Unix()})), err: ErrNotYetValid},
+		{name: "other issuer", token: signToken(t, RS256, "rsa-1", rsaKey, claims(map[string]any{"iss": "https://evil.example.com"})), err: ErrIssuer},
+		{name: "other audience", token: signToken(t, RS256, "rsa-1", rsaKey, claims(map[string]any{"aud": "other"})), err: ErrAudience},
+		{name: "unknown kid", token: signToken(t, RS256, "rsa-2", rsaKey, claims(nil)), err: ErrUnknownKey},
+		{name: "wrong key", token: signToken(t, RS256, "rsa-1", otherKey, claims(nil)), err: ErrSignature},
+		{name: "alg of other key type", token: signToken(t, ES256, "rsa-1", ecKey, claims(nil)), err: ErrUnsupportedAlg},
+		{name: "malformed", token: "not.a-token", err: ErrMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := v.Verify(tt.token, now)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if tt.err == nil && c.Subject != "billing-service" {
+				t.Errorf("expected subject billing-service, got %s", c.Subject)
+			}
+		})
+	}
+
+	t.Run("tampered payload", func(t *testing.T) {
+		token := signToken(t, RS256, "rsa-1", rsaKey, claims(nil))
+		forged, _ := json.Marshal(claims(map[string]any{"scope": "admin"}))
+		parts := strings.Split(token, ".")
+		if _, err := v.Verify(parts[0]+"."+b64(forged)+"."+parts[2], now); !errors.Is(err, ErrSignature) {
+			t.Errorf("expected signature error, got %v", err)
+		}
+	})
+
+	t.Run("none algorithm", func(t *testing.T) {
+		header, _ := json.Marshal(map[string]string{"alg": "none", "kid": "rsa-1"})
+		payload, _ := json.Marshal(claims(nil))
+		if _, err := v.Verify(b64(header)+"."+b64(payload)+".", now); err == nil {
+			t.Error("expected unsigned token to be rejected")
+		}
+	})
+}
+
+func TestClaimsStrings(t *testing.T) {
+	c := Claims{all: map[string]any{"scope": "a b", "scp": []any{"c", "d", 1}, "n": 1.0}}
+	if got := c.Strings("scope"); len(got) != 2 || got[1] != "b" {
+		t.Errorf("unexpected scope %v", got)
+	}
+	if got := c.Strings("scp"); len(got) != 2 || got[0] != "c" {
+		t.Errorf("unexpected scp %v", got)
+	}
+	if got := c.Strings("n"); got != nil {
+		t.Errorf("unexpected n %v", got)
+	}
+}
+
+func TestLoadKeySet(t *testing.T) {
+	_, _, jwks := testKeys(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwks, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(jwks)
+	}))
+	defer srv.Close()
+
+	for _, source := range []string{path, srv.URL} {
+		keys, err := LoadKeySet(context.Background(), source)
+		if err != nil {
+			t.Fatalf("loading %s: %v", source, err)
+		}
+		if len(keys.keys) != 2 {
+			t.Errorf("expected the rsa and ec keys from %s, got %d keys", source, len(keys.keys))
+		}
+	}
+
+	if _, err := ParseKeySet([]byte(`{"keys":[{"kty":"oct","k":"c2VjcmV0"}]}`)); err == nil {
+		t.Error("expected jwks without signature keys to be rejected")
+	}
+}
@@ -2,6 +2,7 @@ package log
 
 import (
 	"context"
+	"io"
 	"os"
 	"strings"
 	"time"
@@ -15,8 +16,13 @@ import (
 // Logger instance
 var Logger zerolog.Logger
 
-// Init initializes global logger
+// Init initializes global logger. Registered secrets are masked in all output, see RegisterSecret.
 func Init(serviceName string, cfg config.LoggingConfig) {
+	Logger = newLogger(os.Stderr, serviceName, cfg)
+	log.Logger = Logger
+}
+
+func newLogger(w io.Writer, serviceName string, cfg config.LoggingConfig) zerolog.Logger {
 	zerolog.TimeFieldFormat = time.RFC3339
 
 	levelStr := strings.ToLower(cfg.Level)
@@ -25,23 +31,21 @@ func Init(serviceName string, cfg config.LoggingConfig) {
 		level = zerolog.InfoLevel
 	}
 
-	var output zerolog.Logger
-	if cfg.JsonFormat {
-		output = zerolog.New(os.Stderr)
-	} else {
-		output = zerolog.New(zerolog.ConsoleWriter{
-			Out:        os.Stderr,
+	// secrets are masked in the JSON events, before the console writer formats them
+	if !cfg.JsonFormat {
+		w = zerolog.ConsoleWriter{
+			Out:        w,
 			TimeFormat: time.RFC3339,
-		})
+		}
 	}
 
-	Logger = output.Level(level).
+	output := zerolog.New(redactWriter{w: w})
+
+	return output.Level(level).
 		With().
 		Timestamp().
 		Str("service", serviceName).
 		Logger()
-
-	log.Logger = Logger
 }
 
 // WithCtx returns a logger with traceHook attached
@@ -2,12 +2,25 @@ package meta
 
 import "fmt"
 
+// Build information, set at build time with
+// -ldflags "-X github.com/malakagl/go-template/pkg/meta.Version=v1.2.3 -X ...meta.Commit=<sha> -X ...meta.BuildDate=<date>"
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
 type Meta struct {
 	BuildDate string
 	Version   string
 	Commit    string
 }
 
+// Get returns the build information of the running binary
+func Get() Meta {
+	return Meta{BuildDate: BuildDate, Version: Version, Commit: Commit}
+}
+
 func (m *Meta) String() string {
 	return fmt.Sprintf("BuildDate: %s, Version: %s, Commit: %s", m.BuildDate, m.Version, m.Commit)
 }
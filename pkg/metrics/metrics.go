@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"sync"
+
+	"github.com/malakagl/go-template/pkg/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds the metrics served by Handler, along with the Go runtime and process metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, chi route pattern and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of HTTP requests by method, chi route pattern and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	RateLimitRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rate_limit_rejections_total",
+		Help: "Requests rejected by the rate limit or quotas, by rate limit tier.",
+	}, []string{"tier"})
+
+	AuthFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_failures_total",
+		Help: "Requests rejected by authentication or authorization, by reason.",
+	}, []string{"reason"})
+
+	CouponValidationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "coupon_validation_duration_seconds",
+		Help:    "Duration of coupon code validations by backend and result.",
+		Buckets: []float64{.0005, .001, .005, .01, .05, .1, .5, 1, 5, 10},
+	}, []string{"backend", "result"})
+)
+
+var caches = &cacheCollector{stats: make(map[string]func() cache.Stats)}
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		RateLimitRejections,
+		AuthFailures,
+		CouponValidationDuration,
+		caches,
+	)
+}
+
+// Handler serves the metrics of Registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// RegisterCache exposes the hits, misses, evictions and size of the cache called name,
+// a cache registered again under the same name replaces the previous one
+func RegisterCache(name string, c interface{ Stats() cache.Stats }) {
+	caches.mu.Lock()
+	defer caches.mu.Unlock()
+	caches.stats[name] = c.Stats
+}
+
+// RegisterDB exposes the connection pool stats of db, see sql.DBStats
+func RegisterDB(name string, db *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+var (
+	cacheHits      = prometheus.NewDesc("cache_hits_total", "Cache lookups that found the key.", []string{"cache"}, nil)
+	cacheMisses    = prometheus.NewDesc("cache_misses_total", "Cache lookups that did not find the key.", []string{"cache"}, nil)
+	cacheEvictions = prometheus.NewDesc("cache_evictions_total", "Items dropped by the cache, by reason.", []string{"cache", "reason"}, nil)
+	cacheSize      = prometheus.NewDesc("cache_size", "Items in the cache.", []string{"cache"}, nil)
+)
+
+// cacheCollector reads the stats of the registered caches when metrics are scraped
+type cacheCollector struct {
+	mu    sync.Mutex
+	stats map[string]func() cache.Stats
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheHits
+	ch <- cacheMisses
+	ch <- cacheEvictions
+	ch <- cacheSize
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, stats := range c.stats {
+		s := stats()
+		ch <- prometheus.MustNewConstMetric(cacheHits, prometheus.CounterValue, float64(s.Hits), name)
+		ch <- prometheus.MustNewConstMetric(cacheMisses, prometheus.CounterValue, float64(s.Misses), name)
+		ch <- prometheus.MustNewConstMetric(cacheEvictions, prometheus.CounterValue, float64(s.Evictions), name, "capacity")
+		ch <- prometheus.MustNewConstMetric(cacheEvictions, prometheus.CounterValue, float64(s.Expired), name, "expired")
+		ch <- prometheus.MustNewConstMetric(cacheSize, prometheus.GaugeValue, float64(s.Size), name)
+	}
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/malakagl/go-template/pkg/cache"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRegisterCache(t *testing.T) {
+	c := cache.NewLRUCache[bool](1, time.Hour)
+	RegisterCache("test", c)
+	defer delete(caches.stats, "test")
+
+	c.Put("a", true)
+	c.Get("a")
+	c.Get("b")
+	c.Put("b", true) // evicts a
+
+	expected := `
+# HELP cache_evictions_total Items dropped by the cache, by reason.
+# TYPE cache_evictions_total counter
+cache_evictions_total{cache="test",reason="capacity"} 1
+cache_evictions_total{cache="test",reason="expired"} 0
+# HELP cache_hits_total Cache lookups that found the key.
+# TYPE cache_hits_total counter
+cache_hits_total{cache="test"} 1
+# HELP cache_misses_total Cache lookups that did not find the key.
+# TYPE cache_misses_total counter
+cache_misses_total{cache="test"} 1
+# HELP cache_size Items in the cache.
+# TYPE cache_size gauge
+cache_size{cache="test"} 1
+`
+	if err := testutil.CollectAndCompare(caches, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
@@ -3,12 +3,36 @@ package db
 import "time"
 
 type APIKey struct {
-	ID        uint      `gorm:"primaryKey"`
-	ClientID  string    `gorm:"not null"`
-	APIKey    string    `gorm:"not null"` // store bcrypt hash of the secret
-	CreatedAt time.Time `gorm:"autoCreateTime"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+	ID                uint       `gorm:"primaryKey"`
+	ClientID          string     `gorm:"not null;uniqueIndex"`
+	APIKey            string     `gorm:"not null"` // store bcrypt hash of the secret
+	ExpiresAt         *time.Time // nil when the key does not expire
+	RevokedAt         *time.Time
+	PreviousAPIKey    *string    // bcrypt hash of the secret replaced by the last rotation
+	PreviousExpiresAt *time.Time // end of the grace period of PreviousAPIKey
+	Scopes            string     `gorm:"not null;default:''"` // space separated scopes and roles
+	SigningSecret     *string    // sealed request signing secret, nil when the key cannot sign requests
+	RateLimitTier     *string    // rate limit tier of the config, nil for the default limits
+	RateLimit         *int       // requests per second, overrides the tier
+	RateBurst         *int       // overrides the tier
+	DailyQuota        *int64     // requests per UTC day, 0 is unlimited, overrides the tier
+	MonthlyQuota      *int64     // requests per UTC month, 0 is unlimited, overrides the tier
+	IPAllowlist       string     `gorm:"not null;default:''"` // space separated CIDRs the key can be used from, empty for any
+	IPDenylist        string     `gorm:"not null;default:''"` // space separated CIDRs the key cannot be used from
+	CreatedAt         time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt         time.Time  `gorm:"autoUpdateTime"`
 
 	// Associations
-	Endpoints []Endpoint `gorm:"many2many:api_key_endpoints;"`
+	Endpoints []Endpoint       `gorm:"many2many:api_key_endpoints;"` // grants regardless of is_active, see Grants
+	Grants    []APIKeyEndpoint `gorm:"foreignKey:APIKeyID"`
+}
+
+// Active reports whether the key is neither revoked nor expired at t.
+func (k *APIKey) Active(t time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || t.Before(*k.ExpiresAt))
+}
+
+// InGracePeriod reports whether the secret replaced by the last rotation is still accepted at t.
+func (k *APIKey) InGracePeriod(t time.Time) bool {
+	return k.PreviousAPIKey != nil && k.PreviousExpiresAt != nil && t.Before(*k.PreviousExpiresAt)
 }
@@ -5,7 +5,7 @@ import "time"
 type APIKeyEndpoint struct {
 	APIKeyID   uint      `gorm:"primaryKey"`
 	EndpointID uint      `gorm:"primaryKey"`
-	IsActive   bool      `gorm:"default:true"`
+	IsActive   bool      `gorm:"not null;default:true"`
 	CreatedAt  time.Time `gorm:"autoCreateTime"`
 	UpdatedAt  time.Time `gorm:"autoUpdateTime"`
 
@@ -0,0 +1,48 @@
+package db
+
+import "time"
+
+// AuditOutcome is how an audited action ended
+type AuditOutcome string
+
+const (
+	AuditSuccess AuditOutcome = "success"
+	AuditFailure AuditOutcome = "failure"
+	AuditDenied  AuditOutcome = "denied" // rejected by authentication, ip rules or authorization
+)
+
+// Audited actions
+const (
+	AuditAPIKeyCreate             = "apikey.create"
+	AuditAPIKeyRevoke             = "apikey.revoke"
+	AuditAPIKeyRotate             = "apikey.rotate"
+	AuditAPIKeySetExpiry          = "apikey.set_expiry"
+	AuditAPIKeyAddEndpoints       = "apikey.add_endpoints"
+	AuditAPIKeyRemoveEndpoint     = "apikey.remove_endpoint"
+	AuditAPIKeySetEndpointActive  = "apikey.set_endpoint_active"
+	AuditAPIKeySetScopes          = "apikey.set_scopes"
+	AuditAPIKeyIssueSigningSecret = "apikey.issue_signing_secret"
+	AuditAPIKeySetIPRules         = "apikey.set_ip_rules"
+	AuditAPIKeySetRateLimits      = "apikey.set_rate_limits"
+	AuditOrderCreate              = "order.create"
+	AuditOrderUpdateStatus        = "order.update_status"
+	AuditProductCreate            = "product.create"
+	AuditProductUpdate            = "product.update"
+	AuditProductDelete            = "product.delete"
+	AuditProductRestore           = "product.restore"
+	AuditAuthRepeatedFailures     = "auth.repeated_failures"
+	AuditAuthForbidden            = "auth.forbidden"
+)
+
+// AuditEvent records who did what to which target, the table is append-only
+type AuditEvent struct {
+	ID         uint64       `gorm:"primaryKey;autoIncrement"`
+	OccurredAt time.Time    `gorm:"not null;index"`
+	Actor      string       `gorm:"not null;default:''"` // client ID of the key, or the client ID claimed by failed requests
+	Action     string       `gorm:"not null"`
+	Target     string       `gorm:"not null;default:''"` // e.g. the client ID of a managed key or an order ID
+	Outcome    AuditOutcome `gorm:"not null"`
+	Detail     string       `gorm:"not null;default:''"`
+	IP         string       `gorm:"column:ip;not null;default:''"`
+	TraceID    string       `gorm:"not null;default:''"`
+}
@@ -3,11 +3,13 @@ package db
 import "time"
 
 type Endpoint struct {
-	ID           uint      `gorm:"primaryKey"`
-	HTTPMethod   string    `gorm:"not null"`
-	HTTPEndpoint string    `gorm:"not null"`
-	CreatedAt    time.Time `gorm:"autoCreateTime"`
-	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
+	ID           uint       `gorm:"primaryKey"`
+	HTTPMethod   string     `gorm:"not null"`
+	HTTPEndpoint string     `gorm:"not null"`            // chi route pattern
+	Scope        string     `gorm:"not null;default:''"` // scope bound to the route, "" when it has none
+	RetiredAt    *time.Time // set once the route is no longer registered
+	CreatedAt    time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt    time.Time  `gorm:"autoUpdateTime"`
 
 	// Associations
 	APIKeys []APIKey `gorm:"many2many:api_key_endpoints;"`
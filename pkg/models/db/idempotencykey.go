@@ -0,0 +1,15 @@
+package db
+
+import "time"
+
+// IdempotencyKey stores the outcome of a request sent with an Idempotency-Key header, scoped by API key client ID.
+type IdempotencyKey struct {
+	ClientID    string    `gorm:"primaryKey"`
+	Key         string    `gorm:"primaryKey;size:255"`
+	RequestHash string    `gorm:"not null"`
+	StatusCode  int       `gorm:"not null"` // 0 while the first request is still being processed
+	ContentType string    `gorm:"not null"`
+	Response    []byte    `gorm:"type:bytea"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	ExpiresAt   time.Time `gorm:"not null;index"`
+}
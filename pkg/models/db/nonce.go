@@ -0,0 +1,10 @@
+package db
+
+import "time"
+
+// UsedNonce is the nonce of a signed request of a client, kept until no request carrying it passes the clock skew check
+type UsedNonce struct {
+	ClientID  string    `gorm:"primaryKey"`
+	Nonce     string    `gorm:"primaryKey;size:128"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
@@ -4,19 +4,31 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/malakagl/go-template/pkg/money"
 )
 
 type Order struct {
-	ID        uuid.UUID       `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	Total     float64         `gorm:"not null"`
-	Discounts float64         `gorm:"not null"`
-	Products  []*OrderProduct `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE"`
-	CreatedAt time.Time       `gorm:"autoCreateTime"`
+	ID            uuid.UUID               `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	Status        OrderStatus             `gorm:"not null;default:pending"`
+	Subtotal      money.Money             `gorm:"embedded;embeddedPrefix:subtotal_"`
+	Total         money.Money             `gorm:"embedded;embeddedPrefix:total_"`
+	Discounts     money.Money             `gorm:"embedded;embeddedPrefix:discounts_"`      // line discounts + order discount
+	OrderDiscount money.Money             `gorm:"embedded;embeddedPrefix:order_discount_"` // discount not attributed to a single line
+	CouponCode    *string                 `gorm:"size:10"`
+	PromotionID   *uint                   `gorm:"index"`
+	Promotion     *Promotion              `gorm:"foreignKey:PromotionID"`
+	Products      []*OrderProduct         `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE"`
+	Transitions   []OrderStatusTransition `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE"`
+	CreatedAt     time.Time               `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time               `gorm:"autoUpdateTime"`
 }
 
 type OrderProduct struct {
-	ID        int       `gorm:"primaryKey;autoIncrement"`
-	OrderID   uuid.UUID `gorm:"type:uuid;not null;index"`
-	ProductID string    `gorm:"not null" json:"productId" validate:"required"`
-	Quantity  int       `gorm:"not null" json:"quantity" validate:"required,min=1"`
+	ID        int         `gorm:"primaryKey;autoIncrement"`
+	OrderID   uuid.UUID   `gorm:"type:uuid;not null;index"`
+	ProductID uint        `gorm:"not null" json:"productId" validate:"required"`
+	Product   *Product    `gorm:"foreignKey:ProductID"`
+	Quantity  int         `gorm:"not null" json:"quantity" validate:"required,min=1"`
+	UnitPrice money.Money `gorm:"embedded;embeddedPrefix:unit_price_" json:"unitPrice"`
+	Discount  money.Money `gorm:"embedded;embeddedPrefix:discount_" json:"discount"`
 }
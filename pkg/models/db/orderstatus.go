@@ -0,0 +1,47 @@
+package db
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OrderStatus string
+
+const (
+	OrderStatusPending   OrderStatus = "pending"
+	OrderStatusPaid      OrderStatus = "paid"
+	OrderStatusFulfilled OrderStatus = "fulfilled"
+	OrderStatusCancelled OrderStatus = "cancelled"
+	OrderStatusRefunded  OrderStatus = "refunded"
+)
+
+// orderTransitions lists the statuses an order may move to from each status.
+// cancelled and refunded are final.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPending:   {OrderStatusPaid, OrderStatusCancelled},
+	OrderStatusPaid:      {OrderStatusFulfilled, OrderStatusCancelled, OrderStatusRefunded},
+	OrderStatusFulfilled: {OrderStatusRefunded},
+}
+
+// CanTransitionTo reports whether an order in status s may move to status to.
+func (s OrderStatus) CanTransitionTo(to OrderStatus) bool {
+	for _, allowed := range orderTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// OrderStatusTransition records who moved an order between statuses, when and why.
+type OrderStatusTransition struct {
+	ID         uint        `gorm:"primaryKey;autoIncrement"`
+	OrderID    uuid.UUID   `gorm:"type:uuid;not null;index"`
+	FromStatus OrderStatus `gorm:"not null"`
+	ToStatus   OrderStatus `gorm:"not null"`
+	Actor      string      `gorm:"not null"`
+	Reason     string      `gorm:"not null"`
+	CreatedAt  time.Time   `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,29 @@
+package db
+
+import "testing"
+
+func TestOrderStatus_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from     OrderStatus
+		to       OrderStatus
+		expected bool
+	}{
+		{from: OrderStatusPending, to: OrderStatusPaid, expected: true},
+		{from: OrderStatusPending, to: OrderStatusCancelled, expected: true},
+		{from: OrderStatusPending, to: OrderStatusFulfilled, expected: false},
+		{from: OrderStatusPending, to: OrderStatusRefunded, expected: false},
+		{from: OrderStatusPaid, to: OrderStatusFulfilled, expected: true},
+		{from: OrderStatusPaid, to: OrderStatusCancelled, expected: true},
+		{from: OrderStatusPaid, to: OrderStatusRefunded, expected: true},
+		{from: OrderStatusFulfilled, to: OrderStatusRefunded, expected: true},
+		{from: OrderStatusFulfilled, to: OrderStatusCancelled, expected: false},
+		{from: OrderStatusCancelled, to: OrderStatusPaid, expected: false},
+		{from: OrderStatusRefunded, to: OrderStatusPending, expected: false},
+		{from: OrderStatusPaid, to: OrderStatusPaid, expected: false},
+	}
+	for _, tt := range tests {
+		if got := tt.from.CanTransitionTo(tt.to); got != tt.expected {
+			t.Errorf("%s -> %s: expected %v, got %v", tt.from, tt.to, tt.expected, got)
+		}
+	}
+}
@@ -1,15 +1,23 @@
 package db
 
-import "time"
+import (
+	"time"
+
+	"github.com/malakagl/go-template/pkg/money"
+	"gorm.io/gorm"
+)
 
 // Product represents the products table
 type Product struct {
-	ID        uint         `gorm:"primaryKey;autoIncrement"` // use uint for auto-increment
-	Name      string       `gorm:"size:255;not null"`
-	Price     float64      `gorm:"not null"`
-	Category  string       `gorm:"size:255;not null"`
-	Image     ProductImage `gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE"` // one-to-one relation
-	CreatedAt time.Time    `gorm:"autoCreateTime"`
+	ID        uint           `gorm:"primaryKey;autoIncrement"` // use uint for auto-increment
+	Name      string         `gorm:"size:255;not null"`
+	Price     money.Money    `gorm:"embedded;embeddedPrefix:price_"`
+	Category  string         `gorm:"size:255;not null"`
+	Stock     *int           // units available, nil when stock is not tracked
+	Image     ProductImage   `gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE"` // one-to-one relation
+	CreatedAt time.Time      `gorm:"autoCreateTime"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime"`
+	DeletedAt gorm.DeletedAt `gorm:"index"` // soft delete, hidden from queries unless Unscoped
 }
 
 // ProductImage represents the product_images table
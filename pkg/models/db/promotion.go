@@ -0,0 +1,21 @@
+package db
+
+import (
+	"time"
+
+	"github.com/malakagl/go-template/pkg/money"
+)
+
+// Promotion is the discount granted by a coupon code.
+// Category, when set, restricts the promotion to products of that category.
+type Promotion struct {
+	ID          uint        `gorm:"primaryKey;autoIncrement"`
+	Code        string      `gorm:"size:10;not null;uniqueIndex"`
+	Type        string      `gorm:"not null"`                            // percentage, fixed_amount, buy_x_get_y, cheapest_item_free
+	Value       float64     `gorm:"not null"`                            // percent off for percentage
+	AmountOff   money.Money `gorm:"embedded;embeddedPrefix:amount_off_"` // amount off for fixed_amount
+	Category    string      `gorm:"size:255;not null"`
+	BuyQuantity int         `gorm:"not null"` // X of buy_x_get_y, minimum items for cheapest_item_free
+	GetQuantity int         `gorm:"not null"` // Y of buy_x_get_y
+	CreatedAt   time.Time   `gorm:"autoCreateTime"`
+}
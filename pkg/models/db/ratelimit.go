@@ -0,0 +1,16 @@
+package db
+
+import "time"
+
+// RateLimit is the token bucket and quota counters of a client, shared by all instances
+type RateLimit struct {
+	ClientID    string    `gorm:"primaryKey"` // api key client ID, or ip: and the client IP
+	Tokens      float64   `gorm:"not null"`
+	RefilledAt  time.Time `gorm:"not null"`
+	DayStart    time.Time `gorm:"not null"`
+	DailyUsed   int64     `gorm:"not null"`
+	MonthStart  time.Time `gorm:"not null"`
+	MonthlyUsed int64     `gorm:"not null"`
+	Quotas      bool      `gorm:"not null"`
+	LastSeen    time.Time `gorm:"not null;index"`
+}
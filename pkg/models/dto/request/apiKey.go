@@ -1,5 +1,46 @@
 package request
 
+import "time"
+
 type ApiKeyRequest struct {
 	EndPoints []string `json:"endPoints"`
+	Scopes    []string `json:"scopes"` // scopes and roles, see pkg/scopes
+}
+
+// RotateAPIKeyRequest is the optional body of POST /admin/apikeys/{clientID}/rotate
+type RotateAPIKeyRequest struct {
+	// GracePeriodSeconds is how long the replaced secret keeps working, defaults to an hour
+	GracePeriodSeconds *int `json:"gracePeriodSeconds" validate:"omitempty,min=0,max=2592000"`
+}
+
+// APIKeyExpiryRequest is the body of PUT /admin/apikeys/{clientID}/expiry, a null expiresAt removes the expiry
+type APIKeyExpiryRequest struct {
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// APIKeyEndpointsRequest is the body of POST /admin/apikeys/{clientID}/endpoints
+type APIKeyEndpointsRequest struct {
+	EndPoints []string `json:"endPoints" validate:"required,min=1,dive,number"`
+}
+
+// APIKeyScopesRequest is the body of PUT /admin/apikeys/{clientID}/scopes, it replaces the scopes and roles of the key
+type APIKeyScopesRequest struct {
+	Scopes []string `json:"scopes" validate:"required"`
+}
+
+// APIKeyRateLimitsRequest is the body of PUT /admin/apikeys/{clientID}/rate-limits, it replaces the rate limit tier
+// and overrides of the key. A null tier gives the default limits, a null override keeps the value of the tier.
+type APIKeyRateLimitsRequest struct {
+	Tier              *string `json:"tier"`
+	RequestsPerSecond *int    `json:"requestsPerSecond" validate:"omitempty,min=1"`
+	Burst             *int    `json:"burst" validate:"omitempty,min=1"`
+	DailyQuota        *int64  `json:"dailyQuota" validate:"omitempty,min=0"`   // 0 is unlimited
+	MonthlyQuota      *int64  `json:"monthlyQuota" validate:"omitempty,min=0"` // 0 is unlimited
+}
+
+// APIKeyIPRulesRequest is the body of PUT /admin/apikeys/{clientID}/ip-rules, it replaces the ip rules of the key.
+// An empty allow list permits any IP that is not denied.
+type APIKeyIPRulesRequest struct {
+	Allow []string `json:"allow" validate:"dive,cidr|ip"`
+	Deny  []string `json:"deny" validate:"dive,cidr|ip"`
 }
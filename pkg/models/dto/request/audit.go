@@ -0,0 +1,13 @@
+package request
+
+import "time"
+
+// AuditFilter holds the query parameters of GET /admin/audit
+type AuditFilter struct {
+	From   *time.Time `validate:"omitempty"`
+	To     *time.Time `validate:"omitempty"`
+	Actor  string     `validate:"omitempty,max=255"`
+	Action string     `validate:"omitempty,max=100"`
+	Limit  int        `validate:"min=1,max=100"`
+	Offset int        `validate:"min=0"`
+}
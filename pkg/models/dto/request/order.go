@@ -1,5 +1,7 @@
 package request
 
+import "time"
+
 type OrderRequest struct {
 	CouponCode string `json:"couponCode,omitempty"`
 	Items      []Item `json:"items" validate:"required,dive,required"`
@@ -9,3 +11,20 @@ type Item struct {
 	ProductID string `json:"productId" validate:"required"`
 	Quantity  int    `json:"quantity" validate:"required,min=1"`
 }
+
+// OrderFilter holds the query parameters of GET /orders
+type OrderFilter struct {
+	From      *time.Time `validate:"omitempty"`
+	To        *time.Time `validate:"omitempty"`
+	MinTotal  *int64     `validate:"omitempty,min=0"` // minor units
+	MaxTotal  *int64     `validate:"omitempty,min=0"`
+	ProductID string     `validate:"omitempty,number"`
+	Status    string     `validate:"omitempty,oneof=pending paid fulfilled cancelled refunded"`
+	Limit     int        `validate:"min=1,max=100"`
+	Offset    int        `validate:"min=0"`
+}
+
+// OrderStatusRequest is the optional body of the order status endpoints
+type OrderStatusRequest struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
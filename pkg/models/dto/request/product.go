@@ -0,0 +1,34 @@
+package request
+
+type ProductRequest struct {
+	Name     string               `json:"name" validate:"required,max=255"`
+	Price    ProductPrice         `json:"price" validate:"required"`
+	Category string               `json:"category" validate:"required,max=255"`
+	Stock    *int                 `json:"stock" validate:"omitempty,min=0"`
+	Image    *ProductImageRequest `json:"image" validate:"omitempty"`
+}
+
+// ProductPrice is the price of a product in the minor unit of its currency, see money.Money.
+// Unlike money.Money it has to be positive, products are not given away.
+type ProductPrice struct {
+	Amount   int64  `json:"amount" validate:"gt=0"`
+	Currency string `json:"currency" validate:"required,iso4217"`
+}
+
+type ProductImageRequest struct {
+	Thumbnail string `json:"thumbnail" validate:"required,max=255"`
+	Mobile    string `json:"mobile" validate:"required,max=255"`
+	Tablet    string `json:"tablet" validate:"required,max=255"`
+	Desktop   string `json:"desktop" validate:"required,max=255"`
+}
+
+// ProductFilter holds the query parameters of GET /products
+type ProductFilter struct {
+	Category string `validate:"omitempty,max=255"`
+	MinPrice *int64 `validate:"omitempty,min=0"` // minor units
+	MaxPrice *int64 `validate:"omitempty,min=0"`
+	Search   string `validate:"omitempty,max=255"`
+	Sort     string `validate:"omitempty,oneof=id -id name -name price -price created_at -created_at"`
+	Limit    int    `validate:"min=1,max=100"`
+	Cursor   string `validate:"omitempty,base64rawurl"`
+}
@@ -1,5 +1,65 @@
 package response
 
+import "time"
+
 type APIKeyResponse struct {
-	ApiKey string `json:"apiKey"`
+	ApiKey     string     `json:"apiKey"`
+	GraceUntil *time.Time `json:"graceUntil,omitempty"` // the replaced secret works until then
+}
+
+// SigningSecretResponse holds a new request signing secret, it is only returned once
+type SigningSecretResponse struct {
+	KeyID         string `json:"keyId"`
+	SigningSecret string `json:"signingSecret"`
+}
+
+// API key statuses
+const (
+	APIKeyActive  = "active"
+	APIKeyExpired = "expired"
+	APIKeyRevoked = "revoked"
+)
+
+// APIKey describes a key without its secret
+type APIKey struct {
+	ClientID   string          `json:"clientId"`
+	Status     string          `json:"status"`
+	ExpiresAt  *time.Time      `json:"expiresAt,omitempty"`
+	RevokedAt  *time.Time      `json:"revokedAt,omitempty"`
+	GraceUntil *time.Time      `json:"graceUntil,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	Scopes     []string        `json:"scopes"`
+	Signing    bool            `json:"signing"` // whether the key has a request signing secret
+	Tier       string          `json:"rateLimitTier,omitempty"`
+	IPAllow    []string        `json:"ipAllowlist"`
+	IPDeny     []string        `json:"ipDenylist"`
+	Endpoints  []EndpointGrant `json:"endpoints"`
+}
+
+// EndpointGrant is an endpoint granted to an API key, inactive grants do not authorize requests
+type EndpointGrant struct {
+	Endpoint
+	Active bool `json:"active"`
+}
+
+type APIKeysResponse struct {
+	APIKeys []APIKey `json:"apiKeys"`
+}
+
+// APIKeyUsage is the use of the rate limit and quotas of a key, counted by the instance answering the request
+type APIKeyUsage struct {
+	ClientID          string     `json:"clientId"`
+	Tier              string     `json:"tier,omitempty"`
+	RequestsPerSecond int        `json:"requestsPerSecond"`
+	Burst             int        `json:"burst"`
+	Remaining         int        `json:"remaining"` // requests left in the current burst
+	Daily             QuotaUsage `json:"daily"`
+	Monthly           QuotaUsage `json:"monthly"`
+}
+
+// QuotaUsage is the use of a daily or monthly quota, a limit of 0 is unlimited
+type QuotaUsage struct {
+	Limit    int64     `json:"limit"`
+	Used     int64     `json:"used"`
+	ResetsAt time.Time `json:"resetsAt"`
 }
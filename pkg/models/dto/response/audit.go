@@ -0,0 +1,22 @@
+package response
+
+import "time"
+
+type AuditEvent struct {
+	ID         uint64    `json:"id"`
+	OccurredAt time.Time `json:"occurredAt"`
+	Actor      string    `json:"actor"`
+	Action     string    `json:"action"`
+	Target     string    `json:"target,omitempty"`
+	Outcome    string    `json:"outcome"`
+	Detail     string    `json:"detail,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	TraceID    string    `json:"traceId,omitempty"`
+}
+
+type AuditEventsResponse struct {
+	Events []AuditEvent `json:"events"`
+	Total  int64        `json:"total"`
+	Limit  int          `json:"limit"`
+	Offset int          `json:"offset"`
+}
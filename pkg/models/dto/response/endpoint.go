@@ -4,6 +4,7 @@ type Endpoint struct {
 	ID           string `json:"id"`
 	HttpMethod   string `gorm:"size:10;not null"`
 	HttpEndpoint string `gorm:"not null"`
+	Scope        string `json:"scope,omitempty"`
 }
 
 type Endpoints []Endpoint
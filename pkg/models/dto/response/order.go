@@ -1,14 +1,53 @@
 package response
 
+import (
+	"time"
+
+	"github.com/malakagl/go-template/pkg/money"
+)
+
 type OrderResponse struct {
-	ID        string    `json:"id"`
-	Total     float64   `json:"total"`
-	Discounts float64   `json:"discounts,omitempty"`
-	Items     []Item    `json:"items"`
-	Products  []Product `json:"products"`
+	ID            string             `json:"id"`
+	Status        string             `json:"status"`
+	CreatedAt     time.Time          `json:"createdAt"`
+	Subtotal      money.Money        `json:"subtotal"`
+	Total         money.Money        `json:"total"`
+	Discounts     money.Money        `json:"discounts"`
+	OrderDiscount money.Money        `json:"orderDiscount"`
+	Promotion     *Promotion         `json:"promotion,omitempty"`
+	Items         []Item             `json:"items"`
+	Products      []Product          `json:"products"`
+	Transitions   []StatusTransition `json:"transitions,omitempty"`
+}
+
+type StatusTransition struct {
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to"`
+	Actor     string    `json:"actor,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
 }
 
 type Item struct {
-	ProductID string `json:"productId"`
-	Quantity  int    `json:"quantity"`
+	ProductID string      `json:"productId"`
+	Quantity  int         `json:"quantity"`
+	UnitPrice money.Money `json:"unitPrice"`
+	Discount  money.Money `json:"discount"`
+}
+
+type Promotion struct {
+	Code        string       `json:"code"`
+	Type        string       `json:"type"`
+	Value       float64      `json:"value,omitempty"`
+	AmountOff   *money.Money `json:"amountOff,omitempty"`
+	Category    string       `json:"category,omitempty"`
+	BuyQuantity int          `json:"buyQuantity,omitempty"`
+	GetQuantity int          `json:"getQuantity,omitempty"`
+}
+
+type OrdersResponse struct {
+	Orders []OrderResponse `json:"orders"`
+	Total  int64           `json:"total"`
+	Limit  int             `json:"limit"`
+	Offset int             `json:"offset"`
 }
@@ -1,10 +1,13 @@
 package response
 
+import "github.com/malakagl/go-template/pkg/money"
+
 type Product struct {
 	ID       string       `json:"id"`
 	Name     string       `gorm:"size:255;not null"`
-	Price    float64      `gorm:"not null"`
+	Price    money.Money  `json:"price"`
 	Category string       `gorm:"size:255;not null"`
+	Stock    *int         `json:"stock,omitempty"`
 	Image    ProductImage `gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE"` // one-to-one relation
 }
 
@@ -19,6 +22,8 @@ type Products []Product
 
 type ProductsResponse struct {
 	Products
+	// NextCursor fetches the next page, it is empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 type ProductResponse Product
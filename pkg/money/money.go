@@ -0,0 +1,92 @@
+package money
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/malakagl/go-template/pkg/errors"
+)
+
+// Money is an amount in the minor unit of an ISO 4217 currency, e.g. cents for USD.
+type Money struct {
+	Amount   int64  `json:"amount" gorm:"not null" validate:"min=0"`
+	Currency string `json:"currency" gorm:"size:3;not null" validate:"required,iso4217"`
+}
+
+// minorUnits lists currencies whose minor unit is not a hundredth.
+var minorUnits = map[string]int{
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+}
+
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+// Zero returns no money in currency.
+func Zero(currency string) Money {
+	return New(0, currency)
+}
+
+// Add returns m + o, or ErrCurrencyMismatch when they are in different currencies.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", errors.ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}, nil
+}
+
+// Sub returns m - o, or ErrCurrencyMismatch when they are in different currencies.
+func (m Money) Sub(o Money) (Money, error) {
+	return m.Add(Money{Amount: -o.Amount, Currency: o.Currency})
+}
+
+// Mul returns m times n.
+func (m Money) Mul(n int) Money {
+	return Money{Amount: m.Amount * int64(n), Currency: m.Currency}
+}
+
+// Percent returns pct percent of m, rounded half away from zero to the minor unit.
+func (m Money) Percent(pct float64) Money {
+	return Money{Amount: int64(math.Round(float64(m.Amount) * pct / 100)), Currency: m.Currency}
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// String formats m in major units, e.g. "12.50 USD".
+func (m Money) String() string {
+	exp, ok := minorUnits[m.Currency]
+	if !ok {
+		exp = 2
+	}
+
+	sign, amount := "", m.Amount
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+
+	if exp == 0 {
+		return fmt.Sprintf("%s%d %s", sign, amount, m.Currency)
+	}
+
+	unit := int64(math.Pow10(exp))
+	return fmt.Sprintf("%s%d.%0*d %s", sign, amount/unit, exp, amount%unit, m.Currency)
+}
+
+// Sum adds up amounts of the same currency. The sum of no amounts is zero in currency.
+func Sum(currency string, amounts ...Money) (Money, error) {
+	total := Zero(currency)
+	for _, a := range amounts {
+		var err error
+		if total, err = total.Add(a); err != nil {
+			return Money{}, err
+		}
+	}
+
+	return total, nil
+}
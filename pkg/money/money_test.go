@@ -0,0 +1,54 @@
+package money
+
+import (
+	"testing"
+
+	"github.com/malakagl/go-template/pkg/errors"
+)
+
+func TestMoney(t *testing.T) {
+	a := New(1050, "usd")
+	if a.Currency != "USD" {
+		t.Errorf("expected currency to be normalised, got %s", a.Currency)
+	}
+
+	sum, err := a.Add(New(250, "USD"))
+	if err != nil || sum != New(1300, "USD") {
+		t.Errorf("expected 13.00 USD, got %v, %v", sum, err)
+	}
+
+	if _, err := a.Add(New(250, "EUR")); !errors.Is(err, errors.ErrCurrencyMismatch) {
+		t.Errorf("expected currency mismatch, got %v", err)
+	}
+
+	if diff, _ := a.Sub(New(1100, "USD")); diff.Amount != -50 {
+		t.Errorf("expected -50, got %d", diff.Amount)
+	}
+
+	if got := a.Mul(3); got.Amount != 3150 {
+		t.Errorf("expected 3150, got %d", got.Amount)
+	}
+
+	if got := New(999, "USD").Percent(12.5); got.Amount != 125 {
+		t.Errorf("expected 12.5%% of 999 to round to 125, got %d", got.Amount)
+	}
+
+	if _, err := Sum("USD", New(1, "USD"), New(1, "EUR")); err == nil {
+		t.Error("expected sum of mixed currencies to fail")
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := map[string]Money{
+		"10.50 USD": New(1050, "USD"),
+		"-0.05 EUR": New(-5, "EUR"),
+		"1500 JPY":  New(1500, "JPY"),
+		"1.250 KWD": New(1250, "KWD"),
+		"0.00 USD":  Zero("USD"),
+	}
+	for want, m := range tests {
+		if got := m.String(); got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package otel
+
+import (
+	"context"
+	"net"
+
+	"github.com/malakagl/go-template/pkg/health"
+)
+
+// ExporterReachable reports whether the exporter accepts connections at endpoint, a host:port
+func ExporterReachable(endpoint string) health.Check {
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", endpoint)
+		if err != nil {
+			return err
+		}
+
+		return conn.Close()
+	}
+}
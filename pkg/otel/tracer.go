@@ -2,15 +2,25 @@ package otel
 
 import (
 	"context"
-	"log"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
 
+	"github.com/malakagl/go-template/internal/config"
 	"github.com/malakagl/go-template/pkg/constants"
+	"github.com/malakagl/go-template/pkg/log"
+	"github.com/malakagl/go-template/pkg/meta"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/credentials"
 )
 
 var service string
@@ -19,41 +29,167 @@ func SetServiceName(s string) {
 	service = s
 }
 
-func InitTracer(serviceName, otlpEndpoint string) (*sdktrace.TracerProvider, error) {
-	ctx := context.Background()
-	SetServiceName(serviceName)
-	// Create OTLP HTTP exporter
-	exp, err := otlptracehttp.New(ctx,
-		otlptracehttp.WithEndpoint(otlpEndpoint),
-		otlptracehttp.WithInsecure(),
-	)
+// InitTracer sets up the global TracerProvider from cfg, the build information m is added to the spans.
+// The provider must be shut down to flush the spans it has not exported yet.
+func InitTracer(ctx context.Context, cfg config.TelemetryConfig, m meta.Meta) (*sdktrace.TracerProvider, error) {
+	SetServiceName(cfg.ServiceName)
+	exp, err := newExporter(ctx, cfg)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", cfg.Exporter, err)
 	}
 
-	// Create resource with service name
-	res, err := resource.New(ctx,
-		resource.WithAttributes(
-			attribute.String("service.name", serviceName),
-		),
-	)
+	res, err := newResource(ctx, cfg, m)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create TracerProvider
+	ratio := 1.0
+	if cfg.SampleRatio != nil {
+		ratio = *cfg.SampleRatio
+	}
+
+	// Create TracerProvider, traces continued from a caller keep its sampling decision
 	tp := sdktrace.NewTracerProvider(
 		sdktrace.WithBatcher(exp),
 		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
 	)
 
-	// Set global TracerProvider
+	// Set global TracerProvider, and the propagator continuing the traces and baggage of callers
 	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
 
-	log.Printf("Tracer initialized for service: %s -> sending traces to OTLP endpoint %s", serviceName, otlpEndpoint)
+	log.Info().Msgf("Tracer initialized for service: %s -> exporting %.2f of traces with %s to %s",
+		cfg.ServiceName, ratio, cfg.Exporter, destination(cfg))
 	return tp, nil
 }
 
+// Endpoint is the host:port of the OTLP collector
+func Endpoint(cfg config.TelemetryConfig) string {
+	return fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+}
+
+func destination(cfg config.TelemetryConfig) string {
+	switch cfg.Exporter {
+	case "stdout":
+		return "stdout"
+	case "file":
+		return cfg.FilePath
+	default:
+		return Endpoint(cfg)
+	}
+}
+
+func newExporter(ctx context.Context, cfg config.TelemetryConfig) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case "stdout":
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case "file":
+		f, err := os.OpenFile(cfg.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+
+		return fileExporter{SpanExporter: exp, f: f}, nil
+	case "otlp-grpc":
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(Endpoint(cfg)), otlptracegrpc.WithHeaders(cfg.Headers)}
+		if cfg.TLS.Enabled {
+			tlsCfg, err := tlsConfig(cfg.TLS)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(tlsCfg)))
+		} else {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+
+		return otlptracegrpc.New(ctx, opts...)
+	default:
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(Endpoint(cfg)), otlptracehttp.WithHeaders(cfg.Headers)}
+		if cfg.TLS.Enabled {
+			tlsCfg, err := tlsConfig(cfg.TLS)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, otlptracehttp.WithTLSClientConfig(tlsCfg))
+		} else {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+
+		return otlptracehttp.New(ctx, opts...)
+	}
+}
+
+// fileExporter closes the file the spans are written to on shutdown
+type fileExporter struct {
+	sdktrace.SpanExporter
+	f *os.File
+}
+
+func (e fileExporter) Shutdown(ctx context.Context) error {
+	err := e.SpanExporter.Shutdown(ctx)
+	if errClose := e.f.Close(); err == nil {
+		err = errClose
+	}
+
+	return err
+}
+
+func tlsConfig(cfg config.TelemetryTLSConfig) (*tls.Config, error) {
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+
+		tlsCfg.RootCAs = x509.NewCertPool()
+		if !tlsCfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", cfg.CAFile)
+		}
+	}
+
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsCfg, nil
+}
+
+// newResource describes the service deployment, the configured attributes can not replace the service ones
+func newResource(ctx context.Context, cfg config.TelemetryConfig, m meta.Meta) (*resource.Resource, error) {
+	attrs := make([]attribute.KeyValue, 0, len(cfg.ResourceAttributes)+5)
+	for k, v := range cfg.ResourceAttributes {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+
+	attrs = append(attrs,
+		attribute.String("service.name", cfg.ServiceName),
+		attribute.String("service.version", m.Version),
+		attribute.String("service.commit", m.Commit),
+		attribute.String("service.build_date", m.BuildDate),
+	)
+	if cfg.Environment != "" {
+		attrs = append(attrs, attribute.String("deployment.environment", cfg.Environment))
+	}
+
+	return resource.New(ctx,
+		resource.WithHost(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(attrs...),
+	)
+}
+
 func Tracer(ctx context.Context, s string) (context.Context, trace.Span) {
 	sCtx, span := otel.Tracer(service).Start(ctx, s)
 	parentSpan := trace.SpanFromContext(ctx)
@@ -0,0 +1,125 @@
+package otel
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/malakagl/go-template/internal/config"
+	"github.com/malakagl/go-template/pkg/meta"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+func TestInitTracerFileExporter(t *testing.T) {
+	never, always := 0.0, 1.0
+	tests := []struct {
+		name    string
+		ratio   *float64
+		sampled bool
+	}{
+		{name: "sampled", ratio: &always, sampled: true},
+		{name: "not sampled", ratio: &never, sampled: false},
+		{name: "default ratio", sampled: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "spans.json")
+			cfg := config.TelemetryConfig{
+				ServiceName:        "orders",
+				Environment:        "test",
+				Exporter:           "file",
+				FilePath:           path,
+				SampleRatio:        tt.ratio,
+				ResourceAttributes: map[string]string{"team": "checkout", "service.name": "ignored"},
+			}
+			tp, err := InitTracer(t.Context(), cfg, meta.Meta{Version: "v1.2.3", Commit: "abc123", BuildDate: "2025-01-01"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, span := Tracer(t.Context(), "place-order")
+			span.End()
+			// spans are batched, shutting down flushes them to the file
+			if err = tp.Shutdown(t.Context()); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := string(data)
+			if !tt.sampled {
+				if out != "" {
+					t.Errorf("expected no spans, got %s", out)
+				}
+				return
+			}
+
+			for _, want := range []string{`"Name":"place-order"`, `"Value":"orders"`, `"Value":"v1.2.3"`, `"Value":"abc123"`, `"Value":"test"`, `"Value":"checkout"`} {
+				if !strings.Contains(out, want) {
+					t.Errorf("expected exported span to contain %s, got %s", want, out)
+				}
+			}
+			if strings.Contains(out, "ignored") {
+				t.Errorf("expected configured attributes not to replace the service name, got %s", out)
+			}
+		})
+	}
+}
+
+func TestInitTracerContinuesCallerSampling(t *testing.T) {
+	never, always := 0.0, 1.0
+	tests := []struct {
+		name        string
+		ratio       *float64
+		traceparent string
+		sampled     bool
+	}{
+		{name: "caller not sampled", ratio: &always, traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", sampled: false},
+		{name: "caller sampled", ratio: &never, traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sampled: true},
+		{name: "no caller", ratio: &always, sampled: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.TelemetryConfig{ServiceName: "orders", Exporter: "file", FilePath: filepath.Join(t.TempDir(), "spans.json"), SampleRatio: tt.ratio}
+			tp, err := InitTracer(t.Context(), cfg, meta.Get())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = tp.Shutdown(t.Context()) }()
+
+			header := http.Header{}
+			if tt.traceparent != "" {
+				header.Set("traceparent", tt.traceparent)
+			}
+			ctx := otel.GetTextMapPropagator().Extract(t.Context(), propagation.HeaderCarrier(header))
+			_, span := Tracer(ctx, "place-order")
+			defer span.End()
+
+			sc := span.SpanContext()
+			if sc.IsSampled() != tt.sampled {
+				t.Errorf("expected sampled %t, got %t", tt.sampled, sc.IsSampled())
+			}
+			if tt.traceparent != "" && sc.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Errorf("expected the trace of the caller to be continued, got %s", sc.TraceID())
+			}
+		})
+	}
+}
+
+func TestInitTracerTLS(t *testing.T) {
+	cfg := config.TelemetryConfig{
+		ServiceName: "orders",
+		Exporter:    "otlp-grpc",
+		Host:        "localhost",
+		Port:        4317,
+		TLS:         config.TelemetryTLSConfig{Enabled: true, CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+	}
+	if _, err := InitTracer(t.Context(), cfg, meta.Get()); err == nil {
+		t.Error("expected a missing CA file to fail")
+	}
+}
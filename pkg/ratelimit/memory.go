@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryLimiter keeps the state of clients in memory. Each instance enforces the limits on its own,
+// and quotas start over on restart.
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	clients map[string]*State
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{clients: make(map[string]*State)}
+}
+
+func (l *MemoryLimiter) Allow(_ context.Context, id string, limits Limits, now time.Time) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s, ok := l.clients[id]
+	if !ok {
+		state := NewState(limits, now)
+		s = &state
+		l.clients[id] = s
+	}
+
+	return s.Allow(limits, now), nil
+}
+
+func (l *MemoryLimiter) Usage(_ context.Context, id string, limits Limits, now time.Time) (Usage, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if s, ok := l.clients[id]; ok {
+		return s.Usage(limits, now), nil
+	}
+
+	return NewState(limits, now).Usage(limits, now), nil
+}
+
+func (l *MemoryLimiter) RemoveIdle(_ context.Context, idle time.Duration, now time.Time) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var n int64
+	for id, s := range l.clients {
+		if s.Idle(idle, now) {
+			delete(l.clients, id)
+			n++
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,201 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/malakagl/go-template/pkg/models/db"
+)
+
+// Limiter backends
+const (
+	BackendMemory   = "memory"
+	BackendDatabase = "database"
+)
+
+// Limits are the request rate and quotas of a client, a quota of 0 is unlimited
+type Limits struct {
+	Tier         string // empty for the default limits
+	Rate         int    // requests per second
+	Burst        int
+	DailyQuota   int64
+	MonthlyQuota int64
+}
+
+// Policy resolves the limits of a key from the overrides stored with it, then its tier, then the defaults.
+// Keys of a tier that is no longer configured fall back to the defaults.
+type Policy struct {
+	Default Limits
+	Tiers   map[string]Limits
+}
+
+func (p Policy) HasTier(name string) bool {
+	_, ok := p.Tiers[name]
+	return ok
+}
+
+func (p Policy) For(key *db.APIKey) Limits {
+	l := p.Default
+	if key.RateLimitTier != nil {
+		if tier, ok := p.Tiers[*key.RateLimitTier]; ok {
+			l = tier
+			l.Tier = *key.RateLimitTier
+		}
+	}
+
+	if key.RateLimit != nil {
+		l.Rate = *key.RateLimit
+	}
+	if key.RateBurst != nil {
+		l.Burst = *key.RateBurst
+	}
+	if key.DailyQuota != nil {
+		l.DailyQuota = *key.DailyQuota
+	}
+	if key.MonthlyQuota != nil {
+		l.MonthlyQuota = *key.MonthlyQuota
+	}
+
+	return l
+}
+
+// Result is the outcome of a request against the limits of its client
+type Result struct {
+	Allowed    bool
+	Limit      int           // requests allowed in a burst
+	Remaining  int           // requests left in the current burst
+	Reset      time.Duration // until the burst is fully available again
+	RetryAfter time.Duration // until the next request is allowed, set when the request is not allowed
+}
+
+// Quota is the use of a daily or monthly quota, a Limit of 0 is unlimited
+type Quota struct {
+	Limit    int64
+	Used     int64
+	ResetsAt time.Time
+}
+
+// Usage is the current state of the limits of a client
+type Usage struct {
+	Limits
+	Remaining int
+	Daily     Quota
+	Monthly   Quota
+}
+
+// Limiter counts the requests of clients against their limits. MemoryLimiter keeps the state per instance,
+// repositories.RateLimitRepo shares it between all instances.
+type Limiter interface {
+	// Allow counts a request of client id against limits
+	Allow(ctx context.Context, id string, limits Limits, now time.Time) (Result, error)
+	// Usage reports the state of the limits of client id without counting a request
+	Usage(ctx context.Context, id string, limits Limits, now time.Time) (Usage, error)
+	// RemoveIdle drops the state of clients idle for longer than idle and returns how many were removed
+	RemoveIdle(ctx context.Context, idle time.Duration, now time.Time) (int64, error)
+}
+
+// State is the token bucket and the quota counters of a client
+type State struct {
+	Tokens      float64
+	RefilledAt  time.Time
+	DayStart    time.Time // UTC day DailyUsed counts requests of
+	DailyUsed   int64
+	MonthStart  time.Time // UTC month MonthlyUsed counts requests of
+	MonthlyUsed int64
+	Quotas      bool // whether the counters have to be kept while the client is idle
+	LastSeen    time.Time
+}
+
+// NewState is the state of a client that has not made requests yet
+func NewState(limits Limits, now time.Time) State {
+	day, month := periods(now)
+	return State{
+		Tokens:     float64(limits.Burst),
+		RefilledAt: now,
+		DayStart:   day,
+		MonthStart: month,
+		LastSeen:   now,
+	}
+}
+
+// Allow counts a request against limits, rejected requests are not counted
+func (s *State) Allow(limits Limits, now time.Time) Result {
+	s.advance(limits, now)
+	s.Quotas = limits.DailyQuota > 0 || limits.MonthlyQuota > 0
+	s.LastSeen = now
+
+	res := Result{Limit: limits.Burst}
+	if limits.DailyQuota > 0 && s.DailyUsed >= limits.DailyQuota {
+		res.RetryAfter = s.DayStart.AddDate(0, 0, 1).Sub(now)
+	}
+	if limits.MonthlyQuota > 0 && s.MonthlyUsed >= limits.MonthlyQuota {
+		res.RetryAfter = max(res.RetryAfter, s.MonthStart.AddDate(0, 1, 0).Sub(now))
+	}
+
+	if res.RetryAfter == 0 {
+		if s.Tokens >= 1 {
+			res.Allowed = true
+			s.Tokens--
+			s.DailyUsed++
+			s.MonthlyUsed++
+		} else {
+			res.RetryAfter = tokenWait(1-s.Tokens, limits.Rate)
+		}
+	}
+
+	res.Remaining = max(int(s.Tokens), 0)
+	res.Reset = tokenWait(float64(limits.Burst)-s.Tokens, limits.Rate)
+	return res
+}
+
+// Usage reports the state against limits at now
+func (s State) Usage(limits Limits, now time.Time) Usage {
+	s.advance(limits, now)
+	return Usage{
+		Limits:    limits,
+		Remaining: max(int(s.Tokens), 0),
+		Daily:     Quota{Limit: limits.DailyQuota, Used: s.DailyUsed, ResetsAt: s.DayStart.AddDate(0, 0, 1)},
+		Monthly:   Quota{Limit: limits.MonthlyQuota, Used: s.MonthlyUsed, ResetsAt: s.MonthStart.AddDate(0, 1, 0)},
+	}
+}
+
+// Idle reports whether the state can be dropped, counters of quotas are kept until their month is over
+func (s State) Idle(idle time.Duration, now time.Time) bool {
+	_, month := periods(now)
+	return now.Sub(s.LastSeen) > idle && (!s.Quotas || s.MonthStart.Before(month))
+}
+
+// advance refills the bucket and starts new quota periods. Instances sharing the state may disagree
+// slightly on the time, so neither the refill time nor the periods move back.
+func (s *State) advance(limits Limits, now time.Time) {
+	if elapsed := now.Sub(s.RefilledAt); elapsed > 0 {
+		s.Tokens += elapsed.Seconds() * float64(limits.Rate)
+		s.RefilledAt = now
+	}
+	s.Tokens = min(s.Tokens, float64(limits.Burst))
+
+	day, month := periods(now)
+	if s.DayStart.Before(day) {
+		s.DayStart, s.DailyUsed = day, 0
+	}
+	if s.MonthStart.Before(month) {
+		s.MonthStart, s.MonthlyUsed = month, 0
+	}
+}
+
+// periods returns the start of the UTC day and month of t
+func periods(t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC),
+		time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// tokenWait is how long a bucket refilling at r tokens per second takes to gain tokens
+func tokenWait(tokens float64, r int) time.Duration {
+	if tokens <= 0 || r <= 0 {
+		return 0
+	}
+
+	return time.Duration(math.Ceil(tokens / float64(r) * float64(time.Second)))
+}
@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/malakagl/go-template/pkg/models/db"
+)
+
+func TestPolicyFor(t *testing.T) {
+	p := Policy{
+		Default: Limits{Rate: 5, Burst: 10},
+		Tiers:   map[string]Limits{"free": {Rate: 1, Burst: 2, DailyQuota: 100, MonthlyQuota: 1000}},
+	}
+	free, gone, burst, daily := "free", "gold", 4, int64(0)
+
+	tests := []struct {
+		name string
+		key  db.APIKey
+		want Limits
+	}{
+		{name: "default", key: db.APIKey{}, want: Limits{Rate: 5, Burst: 10}},
+		{name: "tier", key: db.APIKey{RateLimitTier: &free}, want: Limits{Tier: "free", Rate: 1, Burst: 2, DailyQuota: 100, MonthlyQuota: 1000}},
+		{name: "overrides", key: db.APIKey{RateLimitTier: &free, RateBurst: &burst, DailyQuota: &daily}, want: Limits{Tier: "free", Rate: 1, Burst: 4, MonthlyQuota: 1000}},
+		{name: "unconfigured tier", key: db.APIKey{RateLimitTier: &gone}, want: Limits{Rate: 5, Burst: 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.For(&tt.key); got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func allow(t *testing.T, l Limiter, id string, limits Limits, now time.Time) Result {
+	t.Helper()
+	res, err := l.Allow(context.Background(), id, limits, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func TestLimiterAllow(t *testing.T) {
+	l := NewMemoryLimiter()
+	now := time.Date(2026, 3, 31, 23, 59, 58, 0, time.UTC)
+	limits := Limits{Rate: 1, Burst: 2}
+
+	for i, want := range []int{1, 0} {
+		res := allow(t, l, "a", limits, now)
+		if !res.Allowed || res.Remaining != want || res.Limit != 2 {
+			t.Fatalf("request %d: unexpected result %+v", i, res)
+		}
+	}
+
+	res := allow(t, l, "a", limits, now)
+	if res.Allowed || res.RetryAfter != time.Second || res.Reset != 2*time.Second {
+		t.Fatalf("expected burst to be exhausted, got %+v", res)
+	}
+
+	if res := allow(t, l, "b", limits, now); !res.Allowed {
+		t.Errorf("expected clients to be limited apart, got %+v", res)
+	}
+
+	if res := allow(t, l, "a", limits, now.Add(time.Second)); !res.Allowed {
+		t.Errorf("expected a token after a second, got %+v", res)
+	}
+}
+
+func TestLimiterQuotas(t *testing.T) {
+	l := NewMemoryLimiter()
+	now := time.Date(2026, 3, 31, 23, 59, 0, 0, time.UTC)
+	limits := Limits{Rate: 100, Burst: 100, DailyQuota: 2, MonthlyQuota: 3}
+
+	allow(t, l, "a", limits, now)
+	allow(t, l, "a", limits, now)
+	res := allow(t, l, "a", limits, now)
+	if res.Allowed || res.RetryAfter != time.Minute {
+		t.Fatalf("expected daily quota to be exhausted until midnight, got %+v", res)
+	}
+
+	u, _ := l.Usage(context.Background(), "a", limits, now)
+	if u.Daily.Used != 2 || u.Monthly.Used != 2 || u.Remaining != 98 {
+		t.Errorf("unexpected usage %+v", u)
+	}
+
+	nextDay := now.Add(2 * time.Minute)
+	if res := allow(t, l, "a", limits, nextDay); !res.Allowed {
+		t.Fatalf("expected daily quota to reset, got %+v", res)
+	}
+
+	allow(t, l, "a", limits, nextDay)
+	allow(t, l, "a", limits, nextDay.Add(24*time.Hour))
+	res = allow(t, l, "a", limits, nextDay.Add(24*time.Hour+time.Minute))
+	if res.Allowed || res.RetryAfter != 29*24*time.Hour-2*time.Minute {
+		t.Fatalf("expected monthly quota to be exhausted until May, got %+v", res)
+	}
+}
+
+func TestLimiterRemoveIdle(t *testing.T) {
+	l := NewMemoryLimiter()
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	allow(t, l, "ip:10.0.0.1", Limits{Rate: 1, Burst: 1}, now)
+	allow(t, l, "a", Limits{Rate: 1, Burst: 1, DailyQuota: 10}, now)
+
+	l.RemoveIdle(context.Background(), time.Minute, now.Add(time.Hour))
+	if _, ok := l.clients["ip:10.0.0.1"]; ok {
+		t.Error("expected idle client without quotas to be removed")
+	}
+	if _, ok := l.clients["a"]; !ok {
+		t.Error("expected client with quotas to be kept for the month")
+	}
+
+	l.RemoveIdle(context.Background(), time.Minute, now.AddDate(0, 1, 0))
+	if len(l.clients) != 0 {
+		t.Errorf("expected all clients to be removed, got %d", len(l.clients))
+	}
+}
@@ -2,10 +2,18 @@ package repositories
 
 import (
 	"context"
+	"slices"
+	"time"
 
+	"github.com/malakagl/go-template/pkg/errors"
+	"github.com/malakagl/go-template/pkg/iprules"
+	"github.com/malakagl/go-template/pkg/log"
 	"github.com/malakagl/go-template/pkg/models/db"
+	"github.com/malakagl/go-template/pkg/otel"
+	"github.com/malakagl/go-template/pkg/scopes"
 	"github.com/malakagl/go-template/pkg/util"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ApiKeyRepository struct {
@@ -16,14 +24,19 @@ func NewApiKeyRepository(db *gorm.DB) *ApiKeyRepository {
 	return &ApiKeyRepository{db: db}
 }
 
+// GetEndPoints returns the key of clientID with the endpoints of its active grants
 func (a *ApiKeyRepository) GetEndPoints(clientID string) (*db.APIKey, error) {
 	var key db.APIKey
-	err := a.db.Preload("Endpoints").Where("client_id = ?", clientID).First(&key).Error
+	err := a.db.Preload("Endpoints", func(tx *gorm.DB) *gorm.DB {
+		// the many2many preload cannot filter on the join table, so join it again
+		return tx.Select("endpoints.*").Joins("JOIN api_key_endpoints ake ON ake.endpoint_id = endpoints.id").
+			Where("ake.is_active AND ake.api_key_id = (SELECT id FROM api_keys WHERE client_id = ?)", clientID)
+	}).Where("client_id = ?", clientID).First(&key).Error
 	return &key, err
 }
 
-// CreateAPIKeyWithEndpoints generates a key and associates it with endpoints
-func (r *ApiKeyRepository) CreateAPIKeyWithEndpoints(ctx context.Context, endpointIDs []uint) (string, error) {
+// CreateAPIKeyWithEndpoints generates a key holding scopes and associates it with endpoints
+func (r *ApiKeyRepository) CreateAPIKeyWithEndpoints(ctx context.Context, endpointIDs []uint, scopeNames []string) (string, error) {
 	clientID, secretHash, fullKey, err := util.GenerateAPIKey()
 	if err != nil {
 		return "", err
@@ -32,6 +45,7 @@ func (r *ApiKeyRepository) CreateAPIKeyWithEndpoints(ctx context.Context, endpoi
 	apiKey := db.APIKey{
 		ClientID: clientID,
 		APIKey:   secretHash,
+		Scopes:   scopes.Format(scopeNames),
 	}
 
 	err = r.db.Transaction(func(tx *gorm.DB) error {
@@ -57,3 +71,305 @@ func (r *ApiKeyRepository) CreateAPIKeyWithEndpoints(ctx context.Context, endpoi
 
 	return fullKey, nil
 }
+
+// FindAll returns all keys with their grants, oldest first
+func (a *ApiKeyRepository) FindAll(ctx context.Context) ([]db.APIKey, error) {
+	spanCtx, span := otel.Tracer(ctx, "apikeyRepo.findAll")
+	defer span.End()
+
+	var keys []db.APIKey
+	if err := a.db.WithContext(spanCtx).Preload("Grants.Endpoint").Order("id").Find(&keys).Error; err != nil {
+		log.WithCtx(spanCtx).Error().Msgf("Error fetching api keys: %v", err)
+		span.RecordError(err)
+		return nil, errors.ErrDatabaseError
+	}
+
+	return keys, nil
+}
+
+// Revoke revokes the key of clientID. Revoking a revoked key keeps the original revocation time.
+func (a *ApiKeyRepository) Revoke(ctx context.Context, clientID string) error {
+	spanCtx, span := otel.Tracer(ctx, "apikeyRepo.revoke")
+	defer span.End()
+
+	err := a.db.WithContext(spanCtx).Model(&db.APIKey{}).
+		Where("client_id = ? AND revoked_at IS NULL", clientID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		log.WithCtx(spanCtx).Error().Msgf("Error revoking api key %s: %v", clientID, err)
+		span.RecordError(err)
+		return errors.ErrDatabaseError
+	}
+
+	_, err = a.FindByClientID(spanCtx, clientID)
+	return err
+}
+
+// Rotate replaces the secret of clientID and returns the new full key.
+// The replaced secret keeps working until gracePeriod has passed.
+func (a *ApiKeyRepository) Rotate(ctx context.Context, clientID string, gracePeriod time.Duration) (string, error) {
+	spanCtx, span := otel.Tracer(ctx, "apikeyRepo.rotate")
+	defer span.End()
+
+	secretHash, fullKey, err := util.GenerateAPISecret(clientID)
+	if err != nil {
+		span.RecordError(err)
+		return "", err
+	}
+
+	// the right hand sides see the row before the update, so the current hash moves to previous_api_key
+	res := a.db.WithContext(spanCtx).Model(&db.APIKey{}).
+		Where("client_id = ? AND revoked_at IS NULL", clientID).
+		Updates(map[string]any{
+			"previous_api_key":    gorm.Expr("api_key"),
+			"previous_expires_at": time.Now().Add(gracePeriod),
+			"api_key":             secretHash,
+		})
+	if res.Error != nil {
+		log.WithCtx(spanCtx).Error().Msgf("Error rotating api key %s: %v", clientID, res.Error)
+		span.RecordError(res.Error)
+		return "", errors.ErrDatabaseError
+	}
+
+	if res.RowsAffected == 0 {
+		key, err := a.FindByClientID(spanCtx, clientID)
+		if err != nil {
+			return "", err
+		}
+
+		if key.RevokedAt != nil {
+			return "", errors.ErrAPIKeyRevoked
+		}
+	}
+
+	return fullKey, nil
+}
+
+// SetExpiry sets when the key of clientID expires, nil removes the expiry.
+func (a *ApiKeyRepository) SetExpiry(ctx context.Context, clientID string, expiresAt *time.Time) error {
+	spanCtx, span := otel.Tracer(ctx, "apikeyRepo.setExpiry")
+	defer span.End()
+
+	res := a.db.WithContext(spanCtx).Model(&db.APIKey{}).
+		Where("client_id = ?", clientID).
+		Update("expires_at", expiresAt)
+	if res.Error != nil {
+		log.WithCtx(spanCtx).Error().Msgf("Error setting expiry of api key %s: %v", clientID, res.Error)
+		span.RecordError(res.Error)
+		return errors.ErrDatabaseError
+	}
+
+	if res.RowsAffected == 0 {
+		return errors.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// SetScopes replaces the scopes and roles held by the key of clientID
+func (a *ApiKeyRepository) SetScopes(ctx context.Context, clientID string, scopeNames []string) error {
+	spanCtx, span := otel.Tracer(ctx, "apikeyRepo.setScopes")
+	defer span.End()
+
+	res := a.db.WithContext(spanCtx).Model(&db.APIKey{}).
+		Where("client_id = ?", clientID).
+		Update("scopes", scopes.Format(scopeNames))
+	if res.Error != nil {
+		log.WithCtx(spanCtx).Error().Msgf("Error setting scopes of api key %s: %v", clientID, res.Error)
+		span.RecordError(res.Error)
+		return errors.ErrDatabaseError
+	}
+
+	if res.RowsAffected == 0 {
+		return errors.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// SetIPRules replaces the ip allow and deny lists of the key of clientID
+func (a *ApiKeyRepository) SetIPRules(ctx context.Context, clientID string, allow, deny []string) error {
+	spanCtx, span := otel.Tracer(ctx, "apikeyRepo.setIPRules")
+	defer span.End()
+
+	res := a.db.WithContext(spanCtx).Model(&db.APIKey{}).
+		Where("client_id = ?", clientID).
+		Updates(map[string]any{"ip_allowlist": iprules.Format(allow), "ip_denylist": iprules.Format(deny)})
+	if res.Error != nil {
+		log.WithCtx(spanCtx).Error().Msgf("Error setting ip rules of api key %s: %v", clientID, res.Error)
+		span.RecordError(res.Error)
+		return errors.ErrDatabaseError
+	}
+
+	if res.RowsAffected == 0 {
+		return errors.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// SetRateLimits replaces the rate limit tier and overrides of the key of clientID with those of limits
+func (a *ApiKeyRepository) SetRateLimits(ctx context.Context, clientID string, limits *db.APIKey) error {
+	spanCtx, span := otel.Tracer(ctx, "apikeyRepo.setRateLimits")
+	defer span.End()
+
+	res := a.db.WithContext(spanCtx).Model(&db.APIKey{}).
+		Where("client_id = ?", clientID).
+		Updates(map[string]any{
+			"rate_limit_tier": limits.RateLimitTier,
+			"rate_limit":      limits.RateLimit,
+			"rate_burst":      limits.RateBurst,
+			"daily_quota":     limits.DailyQuota,
+			"monthly_quota":   limits.MonthlyQuota,
+		})
+	if res.Error != nil {
+		log.WithCtx(spanCtx).Error().Msgf("Error setting rate limits of api key %s: %v", clientID, res.Error)
+		span.RecordError(res.Error)
+		return errors.ErrDatabaseError
+	}
+
+	if res.RowsAffected == 0 {
+		return errors.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// SetSigningSecret stores the sealed request signing secret of the key of clientID
+func (a *ApiKeyRepository) SetSigningSecret(ctx context.Context, clientID, sealed string) error {
+	spanCtx, span := otel.Tracer(ctx, "apikeyRepo.setSigningSecret")
+	defer span.End()
+
+	res := a.db.WithContext(spanCtx).Model(&db.APIKey{}).
+		Where("client_id = ? AND revoked_at IS NULL", clientID).
+		Update("signing_secret", sealed)
+	if res.Error != nil {
+		log.WithCtx(spanCtx).Error().Msgf("Error setting signing secret of api key %s: %v", clientID, res.Error)
+		span.RecordError(res.Error)
+		return errors.ErrDatabaseError
+	}
+
+	if res.RowsAffected == 0 {
+		key, err := a.FindByClientID(spanCtx, clientID)
+		if err != nil {
+			return err
+		}
+
+		if key.RevokedAt != nil {
+			return errors.ErrAPIKeyRevoked
+		}
+	}
+
+	return nil
+}
+
+// FindByClientID returns the key of clientID with its grants
+func (a *ApiKeyRepository) FindByClientID(ctx context.Context, clientID string) (*db.APIKey, error) {
+	var key db.APIKey
+	if err := a.db.WithContext(ctx).Preload("Grants", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("endpoint_id")
+	}).Preload("Grants.Endpoint").First(&key, "client_id = ?", clientID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.ErrAPIKeyNotFound
+		}
+
+		log.WithCtx(ctx).Error().Msgf("Error fetching api key %s: %v", clientID, err)
+		return nil, errors.ErrDatabaseError
+	}
+
+	return &key, nil
+}
+
+// AddGrants grants endpointIDs to the key of clientID. Existing grants are reactivated.
+// Endpoints of retired routes cannot be granted, repeated IDs are granted once.
+func (a *ApiKeyRepository) AddGrants(ctx context.Context, clientID string, endpointIDs []uint) error {
+	spanCtx, span := otel.Tracer(ctx, "apikeyRepo.addGrants")
+	defer span.End()
+
+	endpointIDs = slices.Clone(endpointIDs)
+	slices.Sort(endpointIDs)
+	endpointIDs = slices.Compact(endpointIDs)
+
+	key, err := a.FindByClientID(spanCtx, clientID)
+	if err != nil {
+		return err
+	}
+
+	var found int64
+	if err := a.db.WithContext(spanCtx).Model(&db.Endpoint{}).Where("id IN ? AND retired_at IS NULL", endpointIDs).Count(&found).Error; err != nil {
+		log.WithCtx(spanCtx).Error().Msgf("Error fetching endpoints %v: %v", endpointIDs, err)
+		span.RecordError(err)
+		return errors.ErrDatabaseError
+	}
+
+	if int(found) != len(endpointIDs) {
+		return errors.ErrEndpointsNotFound
+	}
+
+	grants := make([]db.APIKeyEndpoint, len(endpointIDs))
+	for i, eid := range endpointIDs {
+		grants[i] = db.APIKeyEndpoint{APIKeyID: key.ID, EndpointID: eid, IsActive: true}
+	}
+
+	err = a.db.WithContext(spanCtx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "api_key_id"}, {Name: "endpoint_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"is_active", "updated_at"}),
+	}).Create(&grants).Error
+	if err != nil {
+		log.WithCtx(spanCtx).Error().Msgf("Error granting endpoints %v to api key %s: %v", endpointIDs, clientID, err)
+		span.RecordError(err)
+		return errors.ErrDatabaseError
+	}
+
+	return nil
+}
+
+// RemoveGrant revokes endpointID from the key of clientID
+func (a *ApiKeyRepository) RemoveGrant(ctx context.Context, clientID string, endpointID uint) error {
+	spanCtx, span := otel.Tracer(ctx, "apikeyRepo.removeGrant")
+	defer span.End()
+
+	key, err := a.FindByClientID(spanCtx, clientID)
+	if err != nil {
+		return err
+	}
+
+	res := a.db.WithContext(spanCtx).Delete(&db.APIKeyEndpoint{}, "api_key_id = ? AND endpoint_id = ?", key.ID, endpointID)
+	if res.Error != nil {
+		log.WithCtx(spanCtx).Error().Msgf("Error removing endpoint %d from api key %s: %v", endpointID, clientID, res.Error)
+		span.RecordError(res.Error)
+		return errors.ErrDatabaseError
+	}
+
+	if res.RowsAffected == 0 {
+		return errors.ErrGrantNotFound
+	}
+
+	return nil
+}
+
+// SetGrantActive activates or deactivates the grant of endpointID to the key of clientID
+func (a *ApiKeyRepository) SetGrantActive(ctx context.Context, clientID string, endpointID uint, active bool) error {
+	spanCtx, span := otel.Tracer(ctx, "apikeyRepo.setGrantActive")
+	defer span.End()
+
+	key, err := a.FindByClientID(spanCtx, clientID)
+	if err != nil {
+		return err
+	}
+
+	res := a.db.WithContext(spanCtx).Model(&db.APIKeyEndpoint{}).
+		Where("api_key_id = ? AND endpoint_id = ?", key.ID, endpointID).
+		Update("is_active", active)
+	if res.Error != nil {
+		log.WithCtx(spanCtx).Error().Msgf("Error updating endpoint %d of api key %s: %v", endpointID, clientID, res.Error)
+		span.RecordError(res.Error)
+		return errors.ErrDatabaseError
+	}
+
+	if res.RowsAffected == 0 {
+		return errors.ErrGrantNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/malakagl/go-template/pkg/errors"
+	"github.com/malakagl/go-template/pkg/models/db"
+)
+
+func TestApiKeyRepositoryAddGrants(t *testing.T) {
+	database := testDB(t, &db.APIKey{}, &db.Endpoint{}, &db.APIKeyEndpoint{})
+	repo := NewApiKeyRepository(database)
+	retired := time.Now()
+	endpoints := []db.Endpoint{
+		{ID: 1, HTTPMethod: "GET", HTTPEndpoint: "/products"},
+		{ID: 2, HTTPMethod: "POST", HTTPEndpoint: "/orders"},
+		{ID: 3, HTTPMethod: "GET", HTTPEndpoint: "/retired", RetiredAt: &retired},
+	}
+	if err := database.Create(&endpoints).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Create(&db.APIKey{ClientID: "client", APIKey: "hash"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		endpointIDs []uint
+		err         error
+		grants      int64
+	}{
+		{name: "grant", endpointIDs: []uint{1}, grants: 1},
+		{name: "duplicate id", endpointIDs: []uint{2, 1, 2}, grants: 2},
+		{name: "missing endpoint", endpointIDs: []uint{1, 4}, err: errors.ErrEndpointsNotFound, grants: 2},
+		{name: "retired endpoint", endpointIDs: []uint{3}, err: errors.ErrEndpointsNotFound, grants: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := repo.AddGrants(t.Context(), "client", tt.endpointIDs); !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			var grants int64
+			if err := database.Model(&db.APIKeyEndpoint{}).Count(&grants).Error; err != nil {
+				t.Fatal(err)
+			}
+			if grants != tt.grants {
+				t.Errorf("expected %d grants, got %d", tt.grants, grants)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/malakagl/go-template/pkg/errors"
+	"github.com/malakagl/go-template/pkg/log"
+	"github.com/malakagl/go-template/pkg/models/db"
+	"github.com/malakagl/go-template/pkg/otel"
+	"gorm.io/gorm"
+)
+
+type AuditRepo struct {
+	db *gorm.DB
+}
+
+func NewAuditRepo(db *gorm.DB) AuditRepo {
+	return AuditRepo{db: db}
+}
+
+// AuditFilter narrows down audit events, zero values match everything
+type AuditFilter struct {
+	From   *time.Time
+	To     *time.Time
+	Actor  string
+	Action string
+	Limit  int
+	Offset int
+}
+
+// Create appends e to the audit log
+func (r *AuditRepo) Create(ctx context.Context, e *db.AuditEvent) error {
+	spanCtx, span := otel.Tracer(ctx, "auditRepo.create")
+	defer span.End()
+
+	if err := r.db.WithContext(spanCtx).Create(e).Error; err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	return nil
+}
+
+// FindAll returns a page of events matching f, newest first, and the number of matching events
+func (r *AuditRepo) FindAll(ctx context.Context, f AuditFilter) ([]db.AuditEvent, int64, error) {
+	spanCtx, span := otel.Tracer(ctx, "auditRepo.findAll")
+	defer span.End()
+
+	q := r.db.WithContext(spanCtx).Model(&db.AuditEvent{})
+	if f.From != nil {
+		q = q.Where("occurred_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		q = q.Where("occurred_at < ?", *f.To)
+	}
+	if f.Actor != "" {
+		q = q.Where("actor = ?", f.Actor)
+	}
+	if f.Action != "" {
+		q = q.Where("action = ?", f.Action)
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		log.WithCtx(spanCtx).Error().Msgf("Error counting audit events: %v", err)
+		span.RecordError(err)
+		return nil, 0, errors.ErrDatabaseError
+	}
+
+	var events []db.AuditEvent
+	if err := q.Order("occurred_at DESC, id DESC").Limit(f.Limit).Offset(f.Offset).Find(&events).Error; err != nil {
+		log.WithCtx(spanCtx).Error().Msgf("Error fetching audit events: %v", err)
+		span.RecordError(err)
+		return nil, 0, errors.ErrDatabaseError
+	}
+
+	return events, total, nil
+}
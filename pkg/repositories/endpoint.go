@@ -2,20 +2,68 @@ package repositories
 
 import (
 	"context"
+	"time"
 
+	"github.com/malakagl/go-template/pkg/log"
 	"github.com/malakagl/go-template/pkg/models/db"
 	"github.com/malakagl/go-template/pkg/otel"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// FindAllEndpoints returns the endpoints of the registered routes, retired ones are left out
 func (a *ApiKeyRepository) FindAllEndpoints(ctx context.Context) ([]db.Endpoint, error) {
 	spanCtx, span := otel.Tracer(ctx, "apikeyRepo.findAllEndpoints")
 	defer span.End()
 
 	var endpoints []db.Endpoint
-	if err := a.db.WithContext(spanCtx).Find(&endpoints).Error; err != nil {
+	if err := a.db.WithContext(spanCtx).Where("retired_at IS NULL").Order("id").Find(&endpoints).Error; err != nil {
 		span.RecordError(err)
 		return nil, err
 	}
 
 	return endpoints, nil
 }
+
+// SyncEndpoints upserts endpoints, the registered routes, and retires the endpoints of all other routes.
+// Endpoints of routes registered again are un-retired and keep their id, and so their grants.
+// It returns the number of endpoints retired by this sync.
+func (a *ApiKeyRepository) SyncEndpoints(ctx context.Context, endpoints []db.Endpoint) (int64, error) {
+	spanCtx, span := otel.Tracer(ctx, "apikeyRepo.syncEndpoints")
+	defer span.End()
+
+	routes := make([][]any, len(endpoints))
+	for i, e := range endpoints {
+		routes[i] = []any{e.HTTPMethod, e.HTTPEndpoint}
+	}
+
+	var retired int64
+	err := a.db.WithContext(spanCtx).Transaction(func(tx *gorm.DB) error {
+		if len(endpoints) != 0 {
+			// retired_at of the inserted rows is NULL, so the update un-retires
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "http_method"}, {Name: "http_endpoint"}},
+				DoUpdates: clause.AssignmentColumns([]string{"scope", "retired_at", "updated_at"}),
+			}).Create(&endpoints).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		q := tx.Model(&db.Endpoint{}).Where("retired_at IS NULL")
+		if len(routes) != 0 {
+			q = q.Where("(http_method, http_endpoint) NOT IN ?", routes)
+		}
+
+		res := q.Updates(map[string]any{"retired_at": time.Now(), "updated_at": time.Now()})
+		retired = res.RowsAffected
+		return res.Error
+	})
+	if err != nil {
+		log.WithCtx(spanCtx).Error().Msgf("Error syncing endpoints: %v", err)
+		span.RecordError(err)
+		return 0, err
+	}
+
+	return retired, nil
+}
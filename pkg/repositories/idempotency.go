@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/malakagl/go-template/pkg/models/db"
+	"github.com/malakagl/go-template/pkg/otel"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepo struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepo(db *gorm.DB) *IdempotencyRepo {
+	return &IdempotencyRepo{db: db}
+}
+
+// Reserve claims rec.Key for rec.ClientID. Expired keys and keys whose request did not complete within lease
+// are taken over. When the key is held by another record, that record is returned with reserved set to false.
+func (r *IdempotencyRepo) Reserve(ctx context.Context, rec *db.IdempotencyKey, lease time.Duration) (*db.IdempotencyKey, bool, error) {
+	spanCtx, span := otel.Tracer(ctx, "idempotencyRepo.reserve")
+	defer span.End()
+
+	now := time.Now()
+	// created_at identifies the reservation in Complete and Release, postgres keeps microseconds
+	rec.CreatedAt = now.Truncate(time.Microsecond)
+	var existing db.IdempotencyKey
+	reserved := false
+	err := r.db.WithContext(spanCtx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("client_id = ? AND key = ? AND (expires_at < ? OR (status_code = 0 AND created_at < ?))",
+			rec.ClientID, rec.Key, now, now.Add(-lease)).
+			Delete(&db.IdempotencyKey{}).Error; err != nil {
+			return err
+		}
+
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(rec)
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected == 1 {
+			reserved = true
+			return nil
+		}
+
+		return tx.First(&existing, "client_id = ? AND key = ?", rec.ClientID, rec.Key).Error
+	})
+	if err != nil {
+		span.RecordError(err)
+		return nil, false, err
+	}
+
+	if reserved {
+		return rec, true, nil
+	}
+
+	return &existing, false, nil
+}
+
+// Complete stores the response of the request holding the reservation rec so it can be replayed.
+// Nothing is stored when the reservation was taken over after its lease ran out.
+func (r *IdempotencyRepo) Complete(ctx context.Context, rec *db.IdempotencyKey, statusCode int, contentType string, body []byte) error {
+	spanCtx, span := otel.Tracer(ctx, "idempotencyRepo.complete")
+	defer span.End()
+
+	err := r.db.WithContext(spanCtx).Model(&db.IdempotencyKey{}).
+		Where("client_id = ? AND key = ? AND created_at = ? AND status_code = 0", rec.ClientID, rec.Key, rec.CreatedAt).
+		Updates(map[string]any{"status_code": statusCode, "content_type": contentType, "response": body}).Error
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return err
+}
+
+// Release drops the reservation rec so the request can be retried, unless it was taken over already.
+func (r *IdempotencyRepo) Release(ctx context.Context, rec *db.IdempotencyKey) error {
+	spanCtx, span := otel.Tracer(ctx, "idempotencyRepo.release")
+	defer span.End()
+
+	err := r.db.WithContext(spanCtx).
+		Where("client_id = ? AND key = ? AND created_at = ? AND status_code = 0", rec.ClientID, rec.Key, rec.CreatedAt).
+		Delete(&db.IdempotencyKey{}).Error
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return err
+}
+
+// DeleteExpired removes keys past their expiry and returns how many were removed.
+func (r *IdempotencyRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	spanCtx, span := otel.Tracer(ctx, "idempotencyRepo.deleteExpired")
+	defer span.End()
+
+	res := r.db.WithContext(spanCtx).Where("expires_at < ?", time.Now()).Delete(&db.IdempotencyKey{})
+	if res.Error != nil {
+		span.RecordError(res.Error)
+	}
+
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/malakagl/go-template/pkg/models/db"
+	"github.com/malakagl/go-template/pkg/otel"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NonceRepo remembers the nonces of signed requests, so each nonce is accepted once across all instances
+type NonceRepo struct {
+	db *gorm.DB
+}
+
+func NewNonceRepo(db *gorm.DB) *NonceRepo {
+	return &NonceRepo{db: db}
+}
+
+// Use records nonce of clientID until expires, it reports false when the nonce was already used
+func (r *NonceRepo) Use(ctx context.Context, clientID, nonce string, expires time.Time) (bool, error) {
+	spanCtx, span := otel.Tracer(ctx, "nonceRepo.use")
+	defer span.End()
+
+	res := r.db.WithContext(spanCtx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&db.UsedNonce{ClientID: clientID, Nonce: nonce, ExpiresAt: expires})
+	if res.Error != nil {
+		span.RecordError(res.Error)
+		return false, res.Error
+	}
+
+	return res.RowsAffected == 1, nil
+}
+
+// DeleteExpired removes nonces past their expiry and returns how many were removed.
+func (r *NonceRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	spanCtx, span := otel.Tracer(ctx, "nonceRepo.deleteExpired")
+	defer span.End()
+
+	res := r.db.WithContext(spanCtx).Where("expires_at < ?", time.Now()).Delete(&db.UsedNonce{})
+	if res.Error != nil {
+		span.RecordError(res.Error)
+	}
+
+	return res.RowsAffected, res.Error
+}
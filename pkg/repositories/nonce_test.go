@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/malakagl/go-template/pkg/models/db"
+)
+
+func TestNonceRepo(t *testing.T) {
+	repo := NewNonceRepo(testDB(t, &db.UsedNonce{}))
+	ctx := t.Context()
+	now := time.Now()
+
+	uses := []struct {
+		clientID string
+		nonce    string
+		expires  time.Time
+		fresh    bool
+	}{
+		{clientID: "client-a", nonce: "n1", expires: now.Add(-time.Minute), fresh: true},
+		{clientID: "client-a", nonce: "n1", expires: now.Add(10 * time.Minute), fresh: false},
+		{clientID: "client-b", nonce: "n1", expires: now.Add(10 * time.Minute), fresh: true},
+		{clientID: "client-a", nonce: "n2", expires: now.Add(10 * time.Minute), fresh: true},
+	}
+	for _, u := range uses {
+		fresh, err := repo.Use(ctx, u.clientID, u.nonce, u.expires)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fresh != u.fresh {
+			t.Errorf("expected nonce %s of %s to be fresh %v, got %v", u.nonce, u.clientID, u.fresh, fresh)
+		}
+	}
+
+	n, err := repo.DeleteExpired(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 expired nonce to be deleted, got %d", n)
+	}
+	if fresh, _ := repo.Use(ctx, "client-a", "n2", now.Add(10*time.Minute)); fresh {
+		t.Error("expected unexpired nonces to be kept")
+	}
+}
@@ -2,7 +2,13 @@ package repositories
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/malakagl/go-template/pkg/errors"
+	"github.com/malakagl/go-template/pkg/log"
 	"github.com/malakagl/go-template/pkg/models/db"
 	"github.com/malakagl/go-template/pkg/otel"
 	"gorm.io/gorm"
@@ -13,19 +19,212 @@ type OrderRepo struct {
 	db *gorm.DB
 }
 
+// OrderFilter narrows down FindAll. Zero values are ignored.
+type OrderFilter struct {
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	MinTotal    *int64 // minor units
+	MaxTotal    *int64
+	ProductID   uint
+	Status      db.OrderStatus
+	Limit       int
+	Offset      int
+}
+
 func NewOrderRepo(db *gorm.DB) OrderRepo {
 	return OrderRepo{db: db}
 }
 
-// Create inserts a new order with products
+// Create reserves stock for the order products and inserts the order with its products in one transaction.
+// It returns ErrInsufficientStock, and inserts nothing, when a product does not have enough stock.
 func (r *OrderRepo) Create(ctx context.Context, order *db.Order) error {
 	spanCtx, span := otel.Tracer(ctx, "orderRepo.create")
 	defer span.End()
 
-	if err := r.db.WithContext(spanCtx).Clauses(clause.Returning{}).Create(&order).Error; err != nil {
+	err := r.db.WithContext(spanCtx).Transaction(func(tx *gorm.DB) error {
+		if err := adjustStock(tx, order.Products, -1); err != nil {
+			return err
+		}
+
+		return tx.Clauses(clause.Returning{}).Create(&order).Error
+	})
+	if err != nil {
+		if errors.Is(err, errors.ErrInsufficientStock) {
+			log.WithCtx(spanCtx).Warn().Msgf("Insufficient stock to create order: %v", err)
+			return err
+		}
+
 		span.RecordError(err)
 		return err
 	}
 
 	return nil
 }
+
+// FindByID returns an order with its products and promotion
+func (r *OrderRepo) FindByID(ctx context.Context, id uuid.UUID) (*db.Order, error) {
+	spanCtx, span := otel.Tracer(ctx, "orderRepo.findByID")
+	defer span.End()
+
+	var order db.Order
+	if err := r.withDetails(spanCtx).First(&order, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.ErrOrderNotFound
+		}
+
+		log.WithCtx(spanCtx).Error().Msgf("Error fetching order with ID %s: %v", id, err)
+		span.RecordError(err)
+		return nil, errors.ErrDatabaseError
+	}
+
+	return &order, nil
+}
+
+// FindAll returns a page of orders matching f, newest first, and the number of matching orders
+func (r *OrderRepo) FindAll(ctx context.Context, f OrderFilter) ([]db.Order, int64, error) {
+	spanCtx, span := otel.Tracer(ctx, "orderRepo.findAll")
+	defer span.End()
+
+	q := r.db.WithContext(spanCtx).Model(&db.Order{})
+	if f.CreatedFrom != nil {
+		q = q.Where("created_at >= ?", *f.CreatedFrom)
+	}
+	if f.CreatedTo != nil {
+		q = q.Where("created_at < ?", *f.CreatedTo)
+	}
+	if f.MinTotal != nil {
+		q = q.Where("total_amount >= ?", *f.MinTotal)
+	}
+	if f.MaxTotal != nil {
+		q = q.Where("total_amount <= ?", *f.MaxTotal)
+	}
+	if f.Status != "" {
+		q = q.Where("status = ?", f.Status)
+	}
+	if f.ProductID != 0 {
+		q = q.Where("EXISTS (SELECT 1 FROM order_products op WHERE op.order_id = orders.id AND op.product_id = ?)", f.ProductID)
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		log.WithCtx(spanCtx).Error().Msgf("Error counting orders: %v", err)
+		span.RecordError(err)
+		return nil, 0, errors.ErrDatabaseError
+	}
+
+	var orders []db.Order
+	err := r.preloadDetails(q).Order("created_at DESC, id").Limit(f.Limit).Offset(f.Offset).Find(&orders).Error
+	if err != nil {
+		log.WithCtx(spanCtx).Error().Msgf("Error fetching orders: %v", err)
+		span.RecordError(err)
+		return nil, 0, errors.ErrDatabaseError
+	}
+
+	return orders, total, nil
+}
+
+// UpdateStatus moves an order to status to and records the transition.
+// The order row is locked so concurrent transitions of the same order are serialised.
+func (r *OrderRepo) UpdateStatus(ctx context.Context, id uuid.UUID, to db.OrderStatus, actor, reason string) (*db.Order, error) {
+	spanCtx, span := otel.Tracer(ctx, "orderRepo.updateStatus")
+	defer span.End()
+
+	err := r.db.WithContext(spanCtx).Transaction(func(tx *gorm.DB) error {
+		var order db.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.ErrOrderNotFound
+			}
+			return err
+		}
+
+		if !order.Status.CanTransitionTo(to) {
+			log.WithCtx(spanCtx).Warn().Msgf("order %s cannot move from %s to %s", id, order.Status, to)
+			return errors.ErrInvalidTransition
+		}
+
+		if err := tx.Model(&order).Update("status", to).Error; err != nil {
+			return err
+		}
+
+		if to == db.OrderStatusCancelled {
+			var products []*db.OrderProduct
+			if err := tx.Find(&products, "order_id = ?", id).Error; err != nil {
+				return err
+			}
+
+			if err := adjustStock(tx, products, 1); err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&db.OrderStatusTransition{
+			OrderID:    id,
+			FromStatus: order.Status,
+			ToStatus:   to,
+			Actor:      actor,
+			Reason:     reason,
+		}).Error
+	})
+	if err != nil {
+		if errors.Is(err, errors.ErrOrderNotFound) || errors.Is(err, errors.ErrInvalidTransition) {
+			return nil, err
+		}
+
+		log.WithCtx(spanCtx).Error().Msgf("Error updating status of order %s: %v", id, err)
+		span.RecordError(err)
+		return nil, errors.ErrDatabaseError
+	}
+
+	return r.FindByID(spanCtx, id)
+}
+
+// adjustStock adds sign times the ordered quantities to the stock of stock tracked products.
+// Decrements are conditional updates, the row lock they take makes concurrent orders for the last units
+// wait for each other and re-check the remaining stock. Products are updated in id order to avoid deadlocks.
+func adjustStock(tx *gorm.DB, products []*db.OrderProduct, sign int) error {
+	quantities := make(map[uint]int)
+	for _, p := range products {
+		quantities[p.ProductID] += p.Quantity
+	}
+
+	ids := make([]uint, 0, len(quantities))
+	for id := range quantities {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	for _, id := range ids {
+		q := tx.Model(&db.Product{}).Where("id = ?", id)
+		if sign < 0 {
+			q = q.Where("stock IS NULL OR stock >= ?", quantities[id])
+		} else {
+			// restocking must also reach products deleted since the order
+			q = q.Unscoped()
+		}
+
+		res := q.Update("stock", gorm.Expr("stock + ?", sign*quantities[id]))
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if sign < 0 && res.RowsAffected == 0 {
+			return fmt.Errorf("%w: product %d", errors.ErrInsufficientStock, id)
+		}
+	}
+
+	return nil
+}
+
+func (r *OrderRepo) withDetails(ctx context.Context) *gorm.DB {
+	return r.preloadDetails(r.db.WithContext(ctx))
+}
+
+func (r *OrderRepo) preloadDetails(q *gorm.DB) *gorm.DB {
+	return q.Preload("Promotion").
+		Preload("Transitions", func(tx *gorm.DB) *gorm.DB { return tx.Order("id") }).
+		Preload("Products", func(tx *gorm.DB) *gorm.DB { return tx.Order("id") }).
+		// deleted products are still part of the order history
+		Preload("Products.Product", func(tx *gorm.DB) *gorm.DB { return tx.Unscoped() }).
+		Preload("Products.Product.Image")
+}
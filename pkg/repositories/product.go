@@ -2,35 +2,130 @@ package repositories
 
 import (
 	"context"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/malakagl/go-template/pkg/errors"
 	"github.com/malakagl/go-template/pkg/log"
 	"github.com/malakagl/go-template/pkg/models/db"
 	"github.com/malakagl/go-template/pkg/otel"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProductRepo struct {
 	db *gorm.DB
 }
 
+// Product sort keys, ties are broken by id
+const (
+	ProductSortID        = "id"
+	ProductSortName      = "name"
+	ProductSortPrice     = "price"
+	ProductSortCreatedAt = "created_at"
+)
+
+var (
+	productSortColumns = map[string]string{
+		ProductSortID:        "id",
+		ProductSortName:      "name",
+		ProductSortPrice:     "price_amount",
+		ProductSortCreatedAt: "created_at",
+	}
+
+	likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+)
+
+// ProductFilter narrows down and orders FindAll. Zero values are ignored.
+type ProductFilter struct {
+	Category string
+	MinPrice *int64 // minor units
+	MaxPrice *int64
+	Search   string
+	SortBy   string
+	Desc     bool
+	Limit    int
+	After    *ProductCursor
+}
+
+// ProductCursor is the position of the last product of a page. Value is the sort key of that product.
+type ProductCursor struct {
+	Value string
+	ID    uint
+}
+
 func NewProductRepo(db *gorm.DB) ProductRepo {
 	return ProductRepo{db: db}
 }
 
-func (r *ProductRepo) FindAll(ctx context.Context) ([]db.Product, error) {
+// FindAll returns a page of products matching f in keyset order, at most f.Limit products
+func (r *ProductRepo) FindAll(ctx context.Context, f ProductFilter) ([]db.Product, error) {
 	spanCtx, span := otel.Tracer(ctx, "productRepo.findAll")
 	defer span.End()
 
+	q := r.db.WithContext(spanCtx).Preload("Image")
+	if f.Category != "" {
+		q = q.Where("LOWER(category) = LOWER(?)", f.Category)
+	}
+	if f.MinPrice != nil {
+		q = q.Where("price_amount >= ?", *f.MinPrice)
+	}
+	if f.MaxPrice != nil {
+		q = q.Where("price_amount <= ?", *f.MaxPrice)
+	}
+	if f.Search != "" {
+		q = q.Where("name ILIKE ?", "%"+likeEscaper.Replace(f.Search)+"%")
+	}
+
+	sortBy := f.SortBy
+	column, ok := productSortColumns[sortBy]
+	if !ok {
+		sortBy, column = ProductSortID, "id"
+	}
+
+	op, dir := ">", "ASC"
+	if f.Desc {
+		op, dir = "<", "DESC"
+	}
+
+	if f.After != nil {
+		if sortBy == ProductSortID {
+			q = q.Where("id "+op+" ?", f.After.ID)
+		} else {
+			v, err := cursorValue(sortBy, f.After.Value)
+			if err != nil {
+				return nil, errors.ErrInvalidCursor
+			}
+			q = q.Where("("+column+", id) "+op+" (?, ?)", v, f.After.ID)
+		}
+	}
+	if sortBy != ProductSortID {
+		q = q.Order(column + " " + dir)
+	}
+
 	var products []db.Product
-	if err := r.db.WithContext(spanCtx).Preload("Image").Find(&products).Error; err != nil {
+	if err := q.Order("id " + dir).Limit(f.Limit).Find(&products).Error; err != nil {
+		log.WithCtx(spanCtx).Error().Msgf("Error fetching products: %v", err)
 		span.RecordError(err)
-		return nil, errors.ErrProductNotFound
+		return nil, errors.ErrDatabaseError
 	}
 
 	return products, nil
 }
 
+// cursorValue converts the string form of a sort key back to the column type.
+func cursorValue(sortBy, v string) (any, error) {
+	switch sortBy {
+	case ProductSortPrice:
+		return strconv.ParseInt(v, 10, 64)
+	case ProductSortCreatedAt:
+		return time.Parse(time.RFC3339Nano, v)
+	default:
+		return v, nil
+	}
+}
+
 func (r *ProductRepo) FindByID(ctx context.Context, id uint) (*db.Product, error) {
 	spanCtx, span := otel.Tracer(ctx, "productRepo.findByID")
 	defer span.End()
@@ -70,3 +165,99 @@ func (r *ProductRepo) FindByIDs(ctx context.Context, ids []uint) ([]db.Product,
 
 	return products, nil
 }
+
+// Create inserts the product together with its image, when one is set.
+func (r *ProductRepo) Create(ctx context.Context, product *db.Product) error {
+	spanCtx, span := otel.Tracer(ctx, "productRepo.create")
+	defer span.End()
+
+	if err := r.db.WithContext(spanCtx).Create(product).Error; err != nil {
+		log.WithCtx(spanCtx).Error().Msgf("Error creating product: %v", err)
+		span.RecordError(err)
+		return errors.ErrDatabaseError
+	}
+
+	return nil
+}
+
+// Update saves the product fields of an existing, not deleted, product. The image is created or replaced too
+// when replaceImage is set, in the same transaction.
+func (r *ProductRepo) Update(ctx context.Context, product *db.Product, replaceImage bool) error {
+	spanCtx, span := otel.Tracer(ctx, "productRepo.update")
+	defer span.End()
+
+	err := r.db.WithContext(spanCtx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&db.Product{ID: product.ID}).
+			Select("Name", "price_amount", "price_currency", "Category", "Stock", "UpdatedAt").
+			Updates(product)
+		if res.Error != nil {
+			log.WithCtx(spanCtx).Error().Msgf("Error updating product %d: %v", product.ID, res.Error)
+			return errors.ErrDatabaseError
+		}
+
+		if res.RowsAffected == 0 {
+			return errors.ErrProductNotFound
+		}
+
+		if !replaceImage {
+			return nil
+		}
+
+		product.Image.ProductID = product.ID
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "product_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"thumbnail", "mobile", "tablet", "desktop"}),
+		}).Create(&product.Image).Error
+		if err != nil {
+			log.WithCtx(spanCtx).Error().Msgf("Error saving image of product %d: %v", product.ID, err)
+			return errors.ErrDatabaseError
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, errors.ErrProductNotFound) {
+		span.RecordError(err)
+	}
+
+	return err
+}
+
+// Delete soft deletes a product, it is hidden from reads until restored.
+func (r *ProductRepo) Delete(ctx context.Context, id uint) error {
+	spanCtx, span := otel.Tracer(ctx, "productRepo.delete")
+	defer span.End()
+
+	res := r.db.WithContext(spanCtx).Delete(&db.Product{}, "id = ?", id)
+	if res.Error != nil {
+		log.WithCtx(spanCtx).Error().Msgf("Error deleting product %d: %v", id, res.Error)
+		span.RecordError(res.Error)
+		return errors.ErrDatabaseError
+	}
+
+	if res.RowsAffected == 0 {
+		return errors.ErrProductNotFound
+	}
+
+	return nil
+}
+
+// Restore undoes a soft delete. Restoring a product that is not deleted is a no-op.
+func (r *ProductRepo) Restore(ctx context.Context, id uint) error {
+	spanCtx, span := otel.Tracer(ctx, "productRepo.restore")
+	defer span.End()
+
+	res := r.db.WithContext(spanCtx).Unscoped().Model(&db.Product{}).
+		Where("id = ?", id).
+		Update("deleted_at", nil)
+	if res.Error != nil {
+		log.WithCtx(spanCtx).Error().Msgf("Error restoring product %d: %v", id, res.Error)
+		span.RecordError(res.Error)
+		return errors.ErrDatabaseError
+	}
+
+	if res.RowsAffected == 0 {
+		return errors.ErrProductNotFound
+	}
+
+	return nil
+}
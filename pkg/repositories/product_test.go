@@ -0,0 +1,30 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/malakagl/go-template/pkg/models/db"
+	"github.com/malakagl/go-template/pkg/money"
+)
+
+func TestProductRepoUpdate(t *testing.T) {
+	repo := NewProductRepo(testDB(t, &db.Product{}, &db.ProductImage{}))
+	product := &db.Product{Name: "Waffle", Price: money.New(650, "USD"), Category: "Waffle"}
+	if err := repo.Create(t.Context(), product); err != nil {
+		t.Fatal(err)
+	}
+
+	update := &db.Product{ID: product.ID, Name: "Waffle with Berries", Price: money.New(900, "EUR"), Category: "Waffle",
+		Image: db.ProductImage{Thumbnail: "t.jpg", Mobile: "m.jpg", Tablet: "t.jpg", Desktop: "d.jpg"}}
+	if err := repo.Update(t.Context(), update, true); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repo.FindByID(t.Context(), product.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Waffle with Berries" || got.Price != money.New(900, "EUR") || got.Image.Desktop != "d.jpg" {
+		t.Errorf("expected the update to be saved, got %+v", got)
+	}
+}
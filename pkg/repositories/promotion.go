@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/malakagl/go-template/pkg/errors"
+	"github.com/malakagl/go-template/pkg/log"
+	"github.com/malakagl/go-template/pkg/models/db"
+	"github.com/malakagl/go-template/pkg/otel"
+	"gorm.io/gorm"
+)
+
+type PromotionRepo struct {
+	db *gorm.DB
+}
+
+func NewPromotionRepo(db *gorm.DB) PromotionRepo {
+	return PromotionRepo{db: db}
+}
+
+func (r *PromotionRepo) FindByCode(ctx context.Context, code string) (*db.Promotion, error) {
+	spanCtx, span := otel.Tracer(ctx, "promotionRepo.findByCode")
+	defer span.End()
+
+	var promotion db.Promotion
+	if err := r.db.WithContext(spanCtx).First(&promotion, "code = ?", code).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.ErrPromotionNotFound
+		}
+
+		log.WithCtx(spanCtx).Error().Msgf("Error fetching promotion for code %s: %v", code, err)
+		span.RecordError(err)
+		return nil, errors.ErrDatabaseError
+	}
+
+	return &promotion, nil
+}
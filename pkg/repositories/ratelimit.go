@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/malakagl/go-template/pkg/errors"
+	"github.com/malakagl/go-template/pkg/models/db"
+	"github.com/malakagl/go-template/pkg/otel"
+	"github.com/malakagl/go-template/pkg/ratelimit"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RateLimitRepo is a ratelimit.Limiter keeping the state of clients in the database, so the limits hold
+// across all instances. Each request of a client locks its row for the duration of a short transaction.
+type RateLimitRepo struct {
+	db *gorm.DB
+}
+
+func NewRateLimitRepo(db *gorm.DB) *RateLimitRepo {
+	return &RateLimitRepo{db: db}
+}
+
+func (r *RateLimitRepo) Allow(ctx context.Context, id string, limits ratelimit.Limits, now time.Time) (ratelimit.Result, error) {
+	spanCtx, span := otel.Tracer(ctx, "rateLimitRepo.allow")
+	defer span.End()
+
+	var res ratelimit.Result
+	err := r.db.WithContext(spanCtx).Transaction(func(tx *gorm.DB) error {
+		row := toRateLimit(id, ratelimit.NewState(limits, now))
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&row, "client_id = ?", id).Error; err != nil {
+			return err
+		}
+
+		state := toState(&row)
+		res = state.Allow(limits, now)
+		row = toRateLimit(id, state)
+		return tx.Save(&row).Error
+	})
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return res, err
+}
+
+func (r *RateLimitRepo) Usage(ctx context.Context, id string, limits ratelimit.Limits, now time.Time) (ratelimit.Usage, error) {
+	spanCtx, span := otel.Tracer(ctx, "rateLimitRepo.usage")
+	defer span.End()
+
+	var row db.RateLimit
+	err := r.db.WithContext(spanCtx).First(&row, "client_id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ratelimit.NewState(limits, now).Usage(limits, now), nil
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		return ratelimit.Usage{}, err
+	}
+
+	return toState(&row).Usage(limits, now), nil
+}
+
+func (r *RateLimitRepo) RemoveIdle(ctx context.Context, idle time.Duration, now time.Time) (int64, error) {
+	spanCtx, span := otel.Tracer(ctx, "rateLimitRepo.removeIdle")
+	defer span.End()
+
+	// the same condition as ratelimit.State.Idle
+	now = now.UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	res := r.db.WithContext(spanCtx).
+		Where("last_seen < ? AND (NOT quotas OR month_start < ?)", now.Add(-idle), month).
+		Delete(&db.RateLimit{})
+	if res.Error != nil {
+		span.RecordError(res.Error)
+	}
+
+	return res.RowsAffected, res.Error
+}
+
+func toState(row *db.RateLimit) ratelimit.State {
+	return ratelimit.State{
+		Tokens:      row.Tokens,
+		RefilledAt:  row.RefilledAt,
+		DayStart:    row.DayStart,
+		DailyUsed:   row.DailyUsed,
+		MonthStart:  row.MonthStart,
+		MonthlyUsed: row.MonthlyUsed,
+		Quotas:      row.Quotas,
+		LastSeen:    row.LastSeen,
+	}
+}
+
+// toRateLimit stores times in UTC, the timestamp columns have no time zone
+func toRateLimit(id string, s ratelimit.State) db.RateLimit {
+	return db.RateLimit{
+		ClientID:    id,
+		Tokens:      s.Tokens,
+		RefilledAt:  s.RefilledAt.UTC(),
+		DayStart:    s.DayStart,
+		DailyUsed:   s.DailyUsed,
+		MonthStart:  s.MonthStart,
+		MonthlyUsed: s.MonthlyUsed,
+		Quotas:      s.Quotas,
+		LastSeen:    s.LastSeen.UTC(),
+	}
+}
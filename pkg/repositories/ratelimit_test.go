@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/malakagl/go-template/pkg/models/db"
+	"github.com/malakagl/go-template/pkg/ratelimit"
+)
+
+// the cases of the ratelimit.MemoryLimiter tests, times are not in UTC to check they are stored in UTC
+
+var nonUTC = time.FixedZone("UTC+2", 2*60*60)
+
+func allow(t *testing.T, r *RateLimitRepo, id string, limits ratelimit.Limits, now time.Time) ratelimit.Result {
+	t.Helper()
+	res, err := r.Allow(t.Context(), id, limits, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func TestRateLimitRepoAllow(t *testing.T) {
+	r := NewRateLimitRepo(testDB(t, &db.RateLimit{}))
+	now := time.Date(2026, 4, 1, 1, 59, 58, 0, nonUTC)
+	limits := ratelimit.Limits{Rate: 1, Burst: 2}
+
+	for i, want := range []int{1, 0} {
+		res := allow(t, r, "a", limits, now)
+		if !res.Allowed || res.Remaining != want || res.Limit != 2 {
+			t.Fatalf("request %d: unexpected result %+v", i, res)
+		}
+	}
+
+	res := allow(t, r, "a", limits, now)
+	if res.Allowed || res.RetryAfter != time.Second || res.Reset != 2*time.Second {
+		t.Fatalf("expected burst to be exhausted, got %+v", res)
+	}
+
+	if res := allow(t, r, "b", limits, now); !res.Allowed {
+		t.Errorf("expected clients to be limited apart, got %+v", res)
+	}
+
+	if res := allow(t, r, "a", limits, now.Add(time.Second)); !res.Allowed {
+		t.Errorf("expected a token after a second, got %+v", res)
+	}
+}
+
+func TestRateLimitRepoQuotas(t *testing.T) {
+	r := NewRateLimitRepo(testDB(t, &db.RateLimit{}))
+	now := time.Date(2026, 4, 1, 1, 59, 0, 0, nonUTC) // 23:59 UTC on March 31st
+	limits := ratelimit.Limits{Rate: 100, Burst: 100, DailyQuota: 2, MonthlyQuota: 3}
+
+	allow(t, r, "a", limits, now)
+	allow(t, r, "a", limits, now)
+	res := allow(t, r, "a", limits, now)
+	if res.Allowed || res.RetryAfter != time.Minute {
+		t.Fatalf("expected daily quota to be exhausted until midnight UTC, got %+v", res)
+	}
+
+	u, err := r.Usage(t.Context(), "a", limits, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Daily.Used != 2 || u.Monthly.Used != 2 || u.Remaining != 98 {
+		t.Errorf("unexpected usage %+v", u)
+	}
+
+	nextDay := now.Add(2 * time.Minute)
+	if res := allow(t, r, "a", limits, nextDay); !res.Allowed {
+		t.Fatalf("expected daily quota to reset, got %+v", res)
+	}
+
+	allow(t, r, "a", limits, nextDay)
+	allow(t, r, "a", limits, nextDay.Add(24*time.Hour))
+	res = allow(t, r, "a", limits, nextDay.Add(24*time.Hour+time.Minute))
+	if res.Allowed || res.RetryAfter != 29*24*time.Hour-2*time.Minute {
+		t.Fatalf("expected monthly quota to be exhausted until May, got %+v", res)
+	}
+
+	if u, _ := r.Usage(t.Context(), "unknown", limits, now); u.Remaining != 100 || u.Daily.Used != 0 {
+		t.Errorf("expected full limits for clients without requests, got %+v", u)
+	}
+}
+
+func TestRateLimitRepoRemoveIdle(t *testing.T) {
+	database := testDB(t, &db.RateLimit{})
+	r := NewRateLimitRepo(database)
+	now := time.Date(2026, 3, 15, 14, 0, 0, 0, nonUTC)
+	allow(t, r, "ip:10.0.0.1", ratelimit.Limits{Rate: 1, Burst: 1}, now)
+	allow(t, r, "a", ratelimit.Limits{Rate: 1, Burst: 1, DailyQuota: 10}, now)
+
+	clients := func() []string {
+		var ids []string
+		if err := database.Model(&db.RateLimit{}).Order("client_id").Pluck("client_id", &ids).Error; err != nil {
+			t.Fatal(err)
+		}
+		return ids
+	}
+
+	if n, err := r.RemoveIdle(t.Context(), time.Minute, now.Add(time.Hour)); err != nil || n != 1 {
+		t.Fatalf("expected 1 idle client to be removed, got %d: %v", n, err)
+	}
+	if ids := clients(); len(ids) != 1 || ids[0] != "a" {
+		t.Errorf("expected client with quotas to be kept for the month, got %v", ids)
+	}
+
+	if _, err := r.RemoveIdle(t.Context(), time.Minute, now.AddDate(0, 1, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if ids := clients(); len(ids) != 0 {
+		t.Errorf("expected all clients to be removed, got %v", ids)
+	}
+}
+
+func TestRateLimitRepoConcurrentAllow(t *testing.T) {
+	r := NewRateLimitRepo(testDB(t, &db.RateLimit{}))
+	now := time.Now()
+	limits := ratelimit.Limits{Rate: 1, Burst: 5}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	allowed := 0
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := r.Allow(t.Context(), "a", limits, now)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if res.Allowed {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed != limits.Burst {
+		t.Errorf("expected %d of the concurrent requests to be allowed, got %d", limits.Burst, allowed)
+	}
+}
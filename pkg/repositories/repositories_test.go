@@ -0,0 +1,26 @@
+package repositories
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB opens a new sqlite database with the tables of models, it stands in for postgres.
+// Transactions take the write lock when they begin, like the row locks of postgres they serialize writers.
+func testDB(t *testing.T, models ...any) *gorm.DB {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_busy_timeout=5000&_txlock=immediate"
+	database, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = database.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+
+	return database
+}
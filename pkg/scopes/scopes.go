@@ -0,0 +1,72 @@
+package scopes
+
+import (
+	"slices"
+	"strings"
+)
+
+// Scopes bound to routes
+const (
+	ProductsRead  = "products:read"
+	ProductsWrite = "products:write"
+	OrdersRead    = "orders:read"
+	OrdersWrite   = "orders:write"
+	Admin         = "admin" // api key and endpoint management
+)
+
+// Roles are named bundles of scopes a key can hold instead of listing them
+const (
+	RoleOperator = "operator"
+	RoleCustomer = "customer"
+	RoleViewer   = "viewer"
+)
+
+var all = []string{ProductsRead, ProductsWrite, OrdersRead, OrdersWrite, Admin}
+
+var roles = map[string][]string{
+	RoleOperator: all,
+	RoleCustomer: {ProductsRead, OrdersRead, OrdersWrite},
+	RoleViewer:   {ProductsRead, OrdersRead},
+}
+
+// Set is a set of scopes
+type Set map[string]struct{}
+
+// Has reports whether scope is in the set
+func (s Set) Has(scope string) bool {
+	_, ok := s[scope]
+	return ok
+}
+
+// Expand returns the scopes held through names, a mix of scopes and roles. Unknown names are ignored.
+func Expand(names ...string) Set {
+	set := make(Set)
+	for _, name := range names {
+		if slices.Contains(all, name) {
+			set[name] = struct{}{}
+			continue
+		}
+
+		for _, scope := range roles[name] {
+			set[scope] = struct{}{}
+		}
+	}
+
+	return set
+}
+
+// Valid reports whether name is a known scope or role
+func Valid(name string) bool {
+	_, isRole := roles[name]
+	return isRole || slices.Contains(all, name)
+}
+
+// Parse splits a space separated list of scopes and roles, as stored on api keys
+func Parse(s string) []string {
+	return strings.Fields(s)
+}
+
+// Format joins names into the space separated form read by Parse
+func Format(names []string) string {
+	return strings.Join(names, " ")
+}
@@ -0,0 +1,50 @@
+package scopes
+
+import "testing"
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   []string
+		has     []string
+		hasNot  []string
+		wantLen int
+	}{
+		{name: "scopes", names: []string{ProductsRead, Admin}, has: []string{ProductsRead, Admin}, hasNot: []string{OrdersRead}, wantLen: 2},
+		{name: "role", names: []string{RoleViewer}, has: []string{ProductsRead, OrdersRead}, hasNot: []string{OrdersWrite}, wantLen: 2},
+		{name: "role and scope", names: []string{RoleCustomer, OrdersRead, Admin}, has: []string{OrdersWrite, Admin}, wantLen: 4},
+		{name: "operator", names: []string{RoleOperator}, has: []string{ProductsWrite, Admin}, wantLen: 5},
+		{name: "unknown", names: []string{"orders:delete", ""}, hasNot: []string{"orders:delete"}, wantLen: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := Expand(tt.names...)
+			if len(set) != tt.wantLen {
+				t.Errorf("expected %d scopes, got %v", tt.wantLen, set)
+			}
+			for _, s := range tt.has {
+				if !set.Has(s) {
+					t.Errorf("expected %s in %v", s, set)
+				}
+			}
+			for _, s := range tt.hasNot {
+				if set.Has(s) {
+					t.Errorf("unexpected %s in %v", s, set)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	names := Parse("  products:read   customer ")
+	if len(names) != 2 || names[0] != ProductsRead || names[1] != RoleCustomer {
+		t.Fatalf("unexpected names %q", names)
+	}
+	if got := Format(names); got != "products:read customer" {
+		t.Errorf("unexpected format %q", got)
+	}
+	if !Valid(RoleViewer) || !Valid(Admin) || Valid("root") {
+		t.Error("unexpected validity")
+	}
+}
@@ -2,24 +2,60 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/malakagl/go-template/pkg/errors"
+	"github.com/malakagl/go-template/pkg/iprules"
+	"github.com/malakagl/go-template/pkg/log"
+	"github.com/malakagl/go-template/pkg/models/db"
 	"github.com/malakagl/go-template/pkg/models/dto/request"
 	"github.com/malakagl/go-template/pkg/models/dto/response"
+	"github.com/malakagl/go-template/pkg/ratelimit"
 	"github.com/malakagl/go-template/pkg/repositories"
+	"github.com/malakagl/go-template/pkg/scopes"
 	"github.com/malakagl/go-template/pkg/util"
 )
 
+const defaultRotationGracePeriod = time.Hour
+
 type IAPIKeyService interface {
 	Create(ctx context.Context, req *request.ApiKeyRequest) (*response.APIKeyResponse, error)
+	FindAll(ctx context.Context) (*response.APIKeysResponse, error)
+	Revoke(ctx context.Context, clientID string) (*response.APIKey, error)
+	Rotate(ctx context.Context, clientID string, req *request.RotateAPIKeyRequest) (*response.APIKeyResponse, error)
+	SetExpiry(ctx context.Context, clientID string, req *request.APIKeyExpiryRequest) (*response.APIKey, error)
+	AddEndpoints(ctx context.Context, clientID string, req *request.APIKeyEndpointsRequest) (*response.APIKey, error)
+	RemoveEndpoint(ctx context.Context, clientID, endpointID string) (*response.APIKey, error)
+	SetEndpointActive(ctx context.Context, clientID, endpointID string, active bool) (*response.APIKey, error)
+	SetScopes(ctx context.Context, clientID string, req *request.APIKeyScopesRequest) (*response.APIKey, error)
+	IssueSigningSecret(ctx context.Context, clientID string) (*response.SigningSecretResponse, error)
+	SetIPRules(ctx context.Context, clientID string, req *request.APIKeyIPRulesRequest) (*response.APIKey, error)
+	SetRateLimits(ctx context.Context, clientID string, req *request.APIKeyRateLimitsRequest) (*response.APIKeyUsage, error)
+	Usage(ctx context.Context, clientID string) (*response.APIKeyUsage, error)
 }
 
 type APIKeyService struct {
 	apiKeyRepo *repositories.ApiKeyRepository
+	invalidate func(clientID string)                                              // drops cached credentials of a changed key
+	seal       func(secret string) (string, error)                                // seals signing secrets for storage
+	usage      func(ctx context.Context, key *db.APIKey) (ratelimit.Usage, error) // rate limit and quota use of a key
+	tierExists func(name string) bool                                             // whether a rate limit tier is configured
+	audit      IAuditLogger
 }
 
-func NewAPIKeyService(a *repositories.ApiKeyRepository) APIKeyService {
-	return APIKeyService{apiKeyRepo: a}
+func NewAPIKeyService(
+	a *repositories.ApiKeyRepository,
+	invalidate func(clientID string),
+	seal func(secret string) (string, error),
+	usage func(ctx context.Context, key *db.APIKey) (ratelimit.Usage, error),
+	tierExists func(name string) bool,
+	audit IAuditLogger,
+) APIKeyService {
+	return APIKeyService{apiKeyRepo: a, invalidate: invalidate, seal: seal, usage: usage, tierExists: tierExists, audit: audit}
 }
 
 func (a *APIKeyService) Create(ctx context.Context, req *request.ApiKeyRequest) (*response.APIKeyResponse, error) {
@@ -31,10 +67,311 @@ func (a *APIKeyService) Create(ctx context.Context, req *request.ApiKeyRequest)
 		}
 	}
 
-	apiKey, err := a.apiKeyRepo.CreateAPIKeyWithEndpoints(ctx, eps)
+	if err := validateScopes(req.Scopes); err != nil {
+		return nil, err
+	}
+
+	apiKey, err := a.apiKeyRepo.CreateAPIKeyWithEndpoints(ctx, eps, req.Scopes)
+	clientID, _, _ := strings.Cut(apiKey, ".") // never the secret
+	a.audit.Record(ctx, db.AuditAPIKeyCreate, clientID, "", err)
 	if err != nil {
 		return nil, errors.ErrInternalServerError
 	}
 
 	return &response.APIKeyResponse{ApiKey: apiKey}, nil
 }
+
+func (a *APIKeyService) FindAll(ctx context.Context) (*response.APIKeysResponse, error) {
+	keys, err := a.apiKeyRepo.FindAll(ctx)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("findAll failed with error: %v", err)
+		return nil, err
+	}
+
+	now := time.Now()
+	res := &response.APIKeysResponse{APIKeys: make([]response.APIKey, len(keys))}
+	for i := range keys {
+		res.APIKeys[i] = toAPIKeyResponse(&keys[i], now)
+	}
+
+	return res, nil
+}
+
+func (a *APIKeyService) Revoke(ctx context.Context, clientID string) (*response.APIKey, error) {
+	err := a.apiKeyRepo.Revoke(ctx, clientID)
+	a.audit.Record(ctx, db.AuditAPIKeyRevoke, clientID, "", err)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("revoke of api key %s failed with error: %v", clientID, err)
+		return nil, err
+	}
+
+	a.invalidate(clientID)
+	log.WithCtx(ctx).Info().Msgf("api key %s revoked by %s", clientID, util.ClientIDFromContext(ctx))
+	return a.find(ctx, clientID)
+}
+
+// Rotate issues a new secret for the key. The previous secret keeps working during the grace period.
+func (a *APIKeyService) Rotate(ctx context.Context, clientID string, req *request.RotateAPIKeyRequest) (*response.APIKeyResponse, error) {
+	gracePeriod := defaultRotationGracePeriod
+	if req.GracePeriodSeconds != nil {
+		gracePeriod = time.Duration(*req.GracePeriodSeconds) * time.Second
+	}
+
+	apiKey, err := a.apiKeyRepo.Rotate(ctx, clientID, gracePeriod)
+	a.audit.Record(ctx, db.AuditAPIKeyRotate, clientID, "", err)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("rotation of api key %s failed with error: %v", clientID, err)
+		return nil, err
+	}
+
+	a.invalidate(clientID)
+	log.WithCtx(ctx).Info().Msgf("api key %s rotated by %s", clientID, util.ClientIDFromContext(ctx))
+	graceUntil := time.Now().Add(gracePeriod)
+	return &response.APIKeyResponse{ApiKey: apiKey, GraceUntil: &graceUntil}, nil
+}
+
+func (a *APIKeyService) SetExpiry(ctx context.Context, clientID string, req *request.APIKeyExpiryRequest) (*response.APIKey, error) {
+	err := a.apiKeyRepo.SetExpiry(ctx, clientID, req.ExpiresAt)
+	detail := "no expiry"
+	if req.ExpiresAt != nil {
+		detail = "expires at " + req.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	a.audit.Record(ctx, db.AuditAPIKeySetExpiry, clientID, detail, err)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("setting expiry of api key %s failed with error: %v", clientID, err)
+		return nil, err
+	}
+
+	a.invalidate(clientID)
+	return a.find(ctx, clientID)
+}
+
+func (a *APIKeyService) AddEndpoints(ctx context.Context, clientID string, req *request.APIKeyEndpointsRequest) (*response.APIKey, error) {
+	eps := make([]uint, len(req.EndPoints))
+	for i := range req.EndPoints {
+		var err error
+		if eps[i], err = util.StringToUint(req.EndPoints[i]); err != nil {
+			return nil, errors.ErrBadRequest
+		}
+	}
+
+	err := a.apiKeyRepo.AddGrants(ctx, clientID, eps)
+	a.audit.Record(ctx, db.AuditAPIKeyAddEndpoints, clientID, fmt.Sprintf("endpoints %v", eps), err)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("granting endpoints %v to api key %s failed with error: %v", eps, clientID, err)
+		return nil, err
+	}
+
+	a.invalidate(clientID)
+	return a.find(ctx, clientID)
+}
+
+func (a *APIKeyService) RemoveEndpoint(ctx context.Context, clientID, endpointID string) (*response.APIKey, error) {
+	eid, err := util.StringToUint(endpointID)
+	if err != nil {
+		return nil, errors.ErrBadRequest
+	}
+
+	err = a.apiKeyRepo.RemoveGrant(ctx, clientID, eid)
+	a.audit.Record(ctx, db.AuditAPIKeyRemoveEndpoint, clientID+"/"+endpointID, "", err)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("removing endpoint %d from api key %s failed with error: %v", eid, clientID, err)
+		return nil, err
+	}
+
+	a.invalidate(clientID)
+	return a.find(ctx, clientID)
+}
+
+func (a *APIKeyService) SetEndpointActive(ctx context.Context, clientID, endpointID string, active bool) (*response.APIKey, error) {
+	eid, err := util.StringToUint(endpointID)
+	if err != nil {
+		return nil, errors.ErrBadRequest
+	}
+
+	err = a.apiKeyRepo.SetGrantActive(ctx, clientID, eid, active)
+	a.audit.Record(ctx, db.AuditAPIKeySetEndpointActive, clientID+"/"+endpointID, fmt.Sprintf("active=%v", active), err)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("setting endpoint %d of api key %s active=%v failed with error: %v", eid, clientID, active, err)
+		return nil, err
+	}
+
+	a.invalidate(clientID)
+	return a.find(ctx, clientID)
+}
+
+func (a *APIKeyService) SetScopes(ctx context.Context, clientID string, req *request.APIKeyScopesRequest) (*response.APIKey, error) {
+	if err := validateScopes(req.Scopes); err != nil {
+		return nil, err
+	}
+
+	err := a.apiKeyRepo.SetScopes(ctx, clientID, req.Scopes)
+	a.audit.Record(ctx, db.AuditAPIKeySetScopes, clientID, fmt.Sprintf("scopes %v", req.Scopes), err)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("setting scopes %v of api key %s failed with error: %v", req.Scopes, clientID, err)
+		return nil, err
+	}
+
+	a.invalidate(clientID)
+	log.WithCtx(ctx).Info().Msgf("scopes of api key %s set to %v by %s", clientID, req.Scopes, util.ClientIDFromContext(ctx))
+	return a.find(ctx, clientID)
+}
+
+// IssueSigningSecret issues a new request signing secret for the key, replacing the previous one at once
+func (a *APIKeyService) IssueSigningSecret(ctx context.Context, clientID string) (*response.SigningSecretResponse, error) {
+	secret, err := util.GenerateSigningSecret()
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("generating signing secret failed with error: %v", err)
+		return nil, errors.ErrInternalServerError
+	}
+
+	sealed, err := a.seal(secret)
+	if errors.Is(err, errors.ErrSigningDisabled) {
+		return nil, err
+	}
+
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("sealing signing secret failed with error: %v", err)
+		return nil, errors.ErrInternalServerError
+	}
+
+	err = a.apiKeyRepo.SetSigningSecret(ctx, clientID, sealed)
+	a.audit.Record(ctx, db.AuditAPIKeyIssueSigningSecret, clientID, "", err)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("setting signing secret of api key %s failed with error: %v", clientID, err)
+		return nil, err
+	}
+
+	a.invalidate(clientID)
+	log.WithCtx(ctx).Info().Msgf("signing secret of api key %s issued by %s", clientID, util.ClientIDFromContext(ctx))
+	return &response.SigningSecretResponse{KeyID: clientID, SigningSecret: secret}, nil
+}
+
+// SetIPRules replaces the ip allow and deny lists of the key
+func (a *APIKeyService) SetIPRules(ctx context.Context, clientID string, req *request.APIKeyIPRulesRequest) (*response.APIKey, error) {
+	if _, err := iprules.Parse(append(slices.Clone(req.Allow), req.Deny...)); err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrBadRequest, err)
+	}
+
+	err := a.apiKeyRepo.SetIPRules(ctx, clientID, req.Allow, req.Deny)
+	a.audit.Record(ctx, db.AuditAPIKeySetIPRules, clientID, fmt.Sprintf("allow %v deny %v", req.Allow, req.Deny), err)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("setting ip rules of api key %s failed with error: %v", clientID, err)
+		return nil, err
+	}
+
+	a.invalidate(clientID)
+	log.WithCtx(ctx).Info().Msgf("ip rules of api key %s set to allow %v and deny %v by %s", clientID, req.Allow, req.Deny, util.ClientIDFromContext(ctx))
+	return a.find(ctx, clientID)
+}
+
+// SetRateLimits replaces the rate limit tier and overrides of the key and returns its usage of the new limits
+func (a *APIKeyService) SetRateLimits(ctx context.Context, clientID string, req *request.APIKeyRateLimitsRequest) (*response.APIKeyUsage, error) {
+	if req.Tier != nil && !a.tierExists(*req.Tier) {
+		return nil, fmt.Errorf("%w: %q", errors.ErrUnknownTier, *req.Tier)
+	}
+
+	limits := &db.APIKey{
+		RateLimitTier: req.Tier,
+		RateLimit:     req.RequestsPerSecond,
+		RateBurst:     req.Burst,
+		DailyQuota:    req.DailyQuota,
+		MonthlyQuota:  req.MonthlyQuota,
+	}
+	err := a.apiKeyRepo.SetRateLimits(ctx, clientID, limits)
+	a.audit.Record(ctx, db.AuditAPIKeySetRateLimits, clientID, "", err)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("setting rate limits of api key %s failed with error: %v", clientID, err)
+		return nil, err
+	}
+
+	a.invalidate(clientID)
+	log.WithCtx(ctx).Info().Msgf("rate limits of api key %s set by %s", clientID, util.ClientIDFromContext(ctx))
+	return a.Usage(ctx, clientID)
+}
+
+// Usage reports the use of the rate limit and quotas of the key
+func (a *APIKeyService) Usage(ctx context.Context, clientID string) (*response.APIKeyUsage, error) {
+	key, err := a.apiKeyRepo.FindByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := a.usage(ctx, key)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("fetching usage of api key %s failed with error: %v", clientID, err)
+		return nil, errors.ErrInternalServerError
+	}
+
+	return &response.APIKeyUsage{
+		ClientID:          clientID,
+		Tier:              u.Tier,
+		RequestsPerSecond: u.Rate,
+		Burst:             u.Burst,
+		Remaining:         u.Remaining,
+		Daily:             response.QuotaUsage{Limit: u.Daily.Limit, Used: u.Daily.Used, ResetsAt: u.Daily.ResetsAt},
+		Monthly:           response.QuotaUsage{Limit: u.Monthly.Limit, Used: u.Monthly.Used, ResetsAt: u.Monthly.ResetsAt},
+	}, nil
+}
+
+func validateScopes(names []string) error {
+	for _, name := range names {
+		if !scopes.Valid(name) {
+			return fmt.Errorf("%w: %q", errors.ErrUnknownScope, name)
+		}
+	}
+
+	return nil
+}
+
+func (a *APIKeyService) find(ctx context.Context, clientID string) (*response.APIKey, error) {
+	key, err := a.apiKeyRepo.FindByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := toAPIKeyResponse(key, time.Now())
+	return &res, nil
+}
+
+func toAPIKeyResponse(key *db.APIKey, now time.Time) response.APIKey {
+	res := response.APIKey{
+		ClientID:  key.ClientID,
+		Status:    response.APIKeyActive,
+		ExpiresAt: key.ExpiresAt,
+		RevokedAt: key.RevokedAt,
+		CreatedAt: key.CreatedAt,
+		Scopes:    scopes.Parse(key.Scopes),
+		Signing:   key.SigningSecret != nil,
+		IPAllow:   strings.Fields(key.IPAllowlist),
+		IPDeny:    strings.Fields(key.IPDenylist),
+		Endpoints: make([]response.EndpointGrant, len(key.Grants)),
+	}
+	switch {
+	case key.RevokedAt != nil:
+		res.Status = response.APIKeyRevoked
+	case !key.Active(now):
+		res.Status = response.APIKeyExpired
+	}
+
+	if key.RateLimitTier != nil {
+		res.Tier = *key.RateLimitTier
+	}
+
+	if key.InGracePeriod(now) {
+		res.GraceUntil = key.PreviousExpiresAt
+	}
+
+	for i, g := range key.Grants {
+		res.Endpoints[i] = response.EndpointGrant{
+			Endpoint: response.Endpoint{
+				ID:           strconv.Itoa(int(g.EndpointID)),
+				HttpMethod:   g.Endpoint.HTTPMethod,
+				HttpEndpoint: g.Endpoint.HTTPEndpoint,
+			},
+			Active: g.IsActive,
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/malakagl/go-template/pkg/log"
+	"github.com/malakagl/go-template/pkg/models/db"
+	"github.com/malakagl/go-template/pkg/models/dto/request"
+	"github.com/malakagl/go-template/pkg/models/dto/response"
+	"github.com/malakagl/go-template/pkg/repositories"
+	"github.com/malakagl/go-template/pkg/util"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type IAuditLogger interface {
+	Log(ctx context.Context, e *db.AuditEvent)
+	Record(ctx context.Context, action, target, detail string, err error)
+	FindAll(ctx context.Context, f *request.AuditFilter) (*response.AuditEventsResponse, error)
+}
+
+type AuditLogger struct {
+	auditRepo repositories.AuditRepo
+}
+
+func NewAuditLogger(r repositories.AuditRepo) AuditLogger {
+	return AuditLogger{auditRepo: r}
+}
+
+// Log appends e to the audit log. The actor, IP and trace ID are taken from ctx unless e sets them.
+// Failing to write the event is logged, it does not fail the audited action.
+func (a *AuditLogger) Log(ctx context.Context, e *db.AuditEvent) {
+	if e.OccurredAt.IsZero() {
+		e.OccurredAt = time.Now()
+	}
+	if e.Actor == "" {
+		e.Actor = util.ClientIDFromContext(ctx)
+	}
+	if e.IP == "" {
+		e.IP = util.ClientIPFromContext(ctx)
+	}
+	if e.TraceID == "" {
+		if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+			e.TraceID = sc.TraceID().String()
+		}
+	}
+
+	// the event is written even when the client went away
+	if err := a.auditRepo.Create(context.WithoutCancel(ctx), e); err != nil {
+		log.WithCtx(ctx).Error().Msgf("writing audit event %s %s by %s with outcome %s failed with error: %v",
+			e.Action, e.Target, e.Actor, e.Outcome, err)
+	}
+}
+
+// Record logs action on target by the authenticated client, err is the error the action failed with
+func (a *AuditLogger) Record(ctx context.Context, action, target, detail string, err error) {
+	e := &db.AuditEvent{Action: action, Target: target, Outcome: db.AuditSuccess, Detail: detail}
+	if err != nil {
+		e.Outcome, e.Detail = db.AuditFailure, strings.TrimPrefix(detail+": "+err.Error(), ": ")
+	}
+
+	a.Log(ctx, e)
+}
+
+func (a *AuditLogger) FindAll(ctx context.Context, f *request.AuditFilter) (*response.AuditEventsResponse, error) {
+	events, total, err := a.auditRepo.FindAll(ctx, repositories.AuditFilter{
+		From:   f.From,
+		To:     f.To,
+		Actor:  f.Actor,
+		Action: f.Action,
+		Limit:  f.Limit,
+		Offset: f.Offset,
+	})
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("findAll audit events failed with error: %v", err)
+		return nil, err
+	}
+
+	res := &response.AuditEventsResponse{Events: make([]response.AuditEvent, len(events)), Total: total, Limit: f.Limit, Offset: f.Offset}
+	for i, e := range events {
+		res.Events[i] = response.AuditEvent{
+			ID:         e.ID,
+			OccurredAt: e.OccurredAt,
+			Actor:      e.Actor,
+			Action:     e.Action,
+			Target:     e.Target,
+			Outcome:    string(e.Outcome),
+			Detail:     e.Detail,
+			IP:         e.IP,
+			TraceID:    e.TraceID,
+		}
+	}
+
+	return res, nil
+}
@@ -40,6 +40,7 @@ func (a *EndpointService) GetEndpoints(ctx context.Context) (*response.Endpoints
 			ID:           strconv.Itoa(int(e.ID)),
 			HttpMethod:   string(e.HTTPMethod),
 			HttpEndpoint: e.HTTPEndpoint,
+			Scope:        e.Scope,
 		}
 	}
 
@@ -2,49 +2,65 @@ package services
 
 import (
 	"context"
+	"strconv"
 
+	"github.com/google/uuid"
 	"github.com/malakagl/go-template/internal/couponcode"
+	"github.com/malakagl/go-template/pkg/discount"
 	"github.com/malakagl/go-template/pkg/errors"
 	"github.com/malakagl/go-template/pkg/log"
 	"github.com/malakagl/go-template/pkg/models/db"
 	"github.com/malakagl/go-template/pkg/models/dto/request"
 	"github.com/malakagl/go-template/pkg/models/dto/response"
+	"github.com/malakagl/go-template/pkg/money"
 	"github.com/malakagl/go-template/pkg/repositories"
 	"github.com/malakagl/go-template/pkg/util"
 )
 
 type IOrderService interface {
 	Create(ctx context.Context, req *request.OrderRequest) (*response.OrderResponse, error)
+	FindByID(ctx context.Context, id string) (*response.OrderResponse, error)
+	FindAll(ctx context.Context, filter *request.OrderFilter) (*response.OrdersResponse, error)
+	UpdateStatus(ctx context.Context, id string, status db.OrderStatus, req *request.OrderStatusRequest) (*response.OrderResponse, error)
 }
 
 type OrderService struct {
-	orderRepo      repositories.OrderRepo
-	couponCodeRepo repositories.CouponCodeRepo
-	productRepo    repositories.ProductRepo
+	orderRepo       repositories.OrderRepo
+	couponValidator couponcode.CouponValidator
+	productRepo     repositories.ProductRepo
+	promotionRepo   repositories.PromotionRepo
+	audit           IAuditLogger
 }
 
 func NewOrderService(
 	r repositories.OrderRepo,
-	c repositories.CouponCodeRepo,
+	c couponcode.CouponValidator,
 	p repositories.ProductRepo,
+	pr repositories.PromotionRepo,
+	a IAuditLogger,
 ) OrderService {
 	return OrderService{
-		orderRepo:      r,
-		couponCodeRepo: c,
-		productRepo:    p,
+		orderRepo:       r,
+		couponValidator: c,
+		productRepo:     p,
+		promotionRepo:   pr,
+		audit:           a,
 	}
 }
 
+// findPromotion returns the promotion attached to a valid coupon code, or nil when it grants no discount.
+func (o *OrderService) findPromotion(ctx context.Context, code string) (*db.Promotion, error) {
+	promotion, err := o.promotionRepo.FindByCode(ctx, code)
+	if errors.Is(err, errors.ErrPromotionNotFound) {
+		log.WithCtx(ctx).Debug().Msgf("No promotion attached to coupon code: %s", code)
+		return nil, nil
+	}
+
+	return promotion, err
+}
+
 func (o *OrderService) isCouponCodeValid(ctx context.Context, code string) (bool, error) {
-	return couponcode.ValidateCouponCode(ctx, code)
-	// use database
-	// count, errors := o.couponCodeRepo.CountFilesByCode(code)
-	// if count > 1 {
-	// 	log.WithCtx(ctx).Error().Msgf("Coupon code %s is valid: found in multiple files", code)
-	// 	return true, nil
-	// }
-	//
-	// return false, nil
+	return o.couponValidator.Validate(ctx, code)
 }
 
 func (o *OrderService) Create(ctx context.Context, req *request.OrderRequest) (*response.OrderResponse, error) {
@@ -53,15 +69,26 @@ func (o *OrderService) Create(ctx context.Context, req *request.OrderRequest) (*
 		return nil, errors.ErrInternalServerError
 	}
 
-	if !couponCodeIsValid { // !h.couponValidator.ValidateCouponCode(orderReq.CouponCode)
+	if !couponCodeIsValid {
 		log.WithCtx(ctx).Error().Msgf("Invalid coupon code: %s", req.CouponCode)
 		return nil, errors.ErrInvalidCouponCode
 	}
 
-	order := db.Order{}
-	orderProducts := make([]*db.OrderProduct, len(req.Items))
-	products := make([]response.Product, len(req.Items))
-	items := make([]response.Item, len(req.Items))
+	promotion, err := o.findPromotion(ctx, req.CouponCode)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("Error fetching promotion: %v", err)
+		return nil, errors.ErrInternalServerError
+	}
+
+	order := db.Order{
+		Status:     db.OrderStatusPending,
+		CouponCode: &req.CouponCode,
+		Transitions: []db.OrderStatusTransition{{
+			ToStatus: db.OrderStatusPending,
+			Actor:    util.ClientIDFromContext(ctx),
+			Reason:   "order created",
+		}},
+	}
 	productIds := make([]uint, len(req.Items))
 	for i, item := range req.Items {
 		productId, err := util.StringToUint(item.ProductID)
@@ -74,7 +101,7 @@ func (o *OrderService) Create(ctx context.Context, req *request.OrderRequest) (*
 	}
 
 	productsDB, err := o.productRepo.FindByIDs(ctx, productIds)
-	if err != nil && err.Error() == "not found" {
+	if err != nil && errors.Is(err, errors.ErrProductNotFound) {
 		log.WithCtx(ctx).Error().Msgf("Error fetching product: %v", err)
 		return nil, errors.ErrProductNotFound
 	}
@@ -84,48 +111,226 @@ func (o *OrderService) Create(ctx context.Context, req *request.OrderRequest) (*
 		return nil, errors.ErrInternalServerError
 	}
 
+	products := make([]*db.Product, len(req.Items))
+	lines := make([]discount.Line, len(req.Items))
 	for i, item := range req.Items {
-		var product db.Product
-		for _, productDB := range productsDB {
-			pId, _ := util.StringToUint(item.ProductID)
-			if pId == productDB.ID {
-				product = productDB
+		for j := range productsDB {
+			if productIds[i] == productsDB[j].ID {
+				products[i] = &productsDB[j]
 				break
 			}
 		}
 
-		orderProducts[i] = &db.OrderProduct{ProductID: item.ProductID, Quantity: item.Quantity}
-		order.Total += product.Price * float64(item.Quantity)
-		products[i] = response.Product{
-			ID:       item.ProductID,
-			Name:     product.Name,
-			Price:    product.Price,
-			Category: product.Category,
-			Image: response.ProductImage{
-				Thumbnail: product.Image.Thumbnail,
-				Mobile:    product.Image.Mobile,
-				Tablet:    product.Image.Tablet,
-				Desktop:   product.Image.Desktop,
-			},
+		if products[i] == nil {
+			log.WithCtx(ctx).Error().Msgf("Product %d not found or deleted", productIds[i])
+			return nil, errors.ErrProductNotFound
 		}
-		items[i] = response.Item{
+
+		lines[i] = discount.Line{
 			ProductID: item.ProductID,
+			Category:  products[i].Category,
+			UnitPrice: products[i].Price,
 			Quantity:  item.Quantity,
 		}
 	}
-	order.Products = orderProducts
+
+	// an order is charged in a single currency, the one of its first product
+	currency := products[0].Price.Currency
+	totals, err := discount.Apply(promotion, currency, lines)
+	if err != nil {
+		log.WithCtx(ctx).Warn().Msgf("Cannot price order in %s: %v", currency, err)
+		return nil, err
+	}
+
+	order.Products = make([]*db.OrderProduct, len(lines))
+	for i, line := range lines {
+		order.Products[i] = &db.OrderProduct{
+			ProductID: productIds[i],
+			Quantity:  line.Quantity,
+			UnitPrice: line.UnitPrice,
+			Discount:  totals.LineDiscounts[i],
+		}
+	}
+	order.Subtotal = totals.Subtotal
+	order.OrderDiscount = totals.OrderDiscount
+	order.Discounts = totals.Discounts
+	order.Total = totals.Total
+	if promotion != nil {
+		order.PromotionID = &promotion.ID
+	}
 
 	err = o.orderRepo.Create(ctx, &order)
+	o.audit.Record(ctx, db.AuditOrderCreate, orderTarget(&order, err), "", err)
+	if errors.Is(err, errors.ErrInsufficientStock) {
+		return nil, err
+	}
+
 	if err != nil {
 		log.WithCtx(ctx).Error().Msgf("Error creating order: %v", err)
 		return nil, errors.ErrInternalServerError
 	}
 
+	// attach details after saving so associations are not written back
+	order.Promotion = promotion
+	for i := range order.Products {
+		order.Products[i].Product = products[i]
+	}
+
+	return toOrderResponse(&order), nil
+}
+
+func (o *OrderService) FindByID(ctx context.Context, id string) (*response.OrderResponse, error) {
+	orderID, err := uuid.Parse(id)
+	if err != nil {
+		log.WithCtx(ctx).Warn().Msgf("Invalid order ID: %s", id)
+		return nil, errors.ErrInvalidOrderID
+	}
+
+	order, err := o.orderRepo.FindByID(ctx, orderID)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("findByID failed with error: %v", err)
+		return nil, err
+	}
+
+	return toOrderResponse(order), nil
+}
+
+func (o *OrderService) FindAll(ctx context.Context, f *request.OrderFilter) (*response.OrdersResponse, error) {
+	filter := repositories.OrderFilter{
+		CreatedFrom: f.From,
+		CreatedTo:   f.To,
+		MinTotal:    f.MinTotal,
+		MaxTotal:    f.MaxTotal,
+		Status:      db.OrderStatus(f.Status),
+		Limit:       f.Limit,
+		Offset:      f.Offset,
+	}
+	if f.ProductID != "" {
+		productID, err := util.StringToUint(f.ProductID)
+		if err != nil || productID == 0 {
+			return nil, errors.ErrInvalidProductID
+		}
+
+		filter.ProductID = productID
+	}
+
+	res, total, err := o.orderRepo.FindAll(ctx, filter)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("findAll failed with error: %v", err)
+		return nil, err
+	}
+
+	orders := make([]response.OrderResponse, len(res))
+	for i := range res {
+		orders[i] = *toOrderResponse(&res[i])
+	}
+
+	return &response.OrdersResponse{Orders: orders, Total: total, Limit: f.Limit, Offset: f.Offset}, nil
+}
+
+func (o *OrderService) UpdateStatus(
+	ctx context.Context,
+	id string,
+	status db.OrderStatus,
+	req *request.OrderStatusRequest,
+) (*response.OrderResponse, error) {
+	orderID, err := uuid.Parse(id)
+	if err != nil {
+		log.WithCtx(ctx).Warn().Msgf("Invalid order ID: %s", id)
+		return nil, errors.ErrInvalidOrderID
+	}
+
+	order, err := o.orderRepo.UpdateStatus(ctx, orderID, status, util.ClientIDFromContext(ctx), req.Reason)
+	o.audit.Record(ctx, db.AuditOrderUpdateStatus, id, "to "+string(status), err)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("updateStatus to %s failed with error: %v", status, err)
+		return nil, err
+	}
+
+	return toOrderResponse(order), nil
+}
+
+// orderTarget is the ID of a created order, orders that failed to be created have none
+func orderTarget(order *db.Order, err error) string {
+	if err != nil {
+		return ""
+	}
+
+	return order.ID.String()
+}
+
+func toOrderResponse(order *db.Order) *response.OrderResponse {
+	items := make([]response.Item, len(order.Products))
+	products := make([]response.Product, len(order.Products))
+	for i, op := range order.Products {
+		productID := strconv.FormatUint(uint64(op.ProductID), 10)
+		items[i] = response.Item{
+			ProductID: productID,
+			Quantity:  op.Quantity,
+			UnitPrice: op.UnitPrice,
+			Discount:  op.Discount,
+		}
+
+		products[i] = response.Product{ID: productID}
+		if p := op.Product; p != nil {
+			products[i] = response.Product{
+				ID:       productID,
+				Name:     p.Name,
+				Price:    p.Price,
+				Category: p.Category,
+				Image: response.ProductImage{
+					Thumbnail: p.Image.Thumbnail,
+					Mobile:    p.Image.Mobile,
+					Tablet:    p.Image.Tablet,
+					Desktop:   p.Image.Desktop,
+				},
+			}
+		}
+	}
+
+	var transitions []response.StatusTransition
+	for _, t := range order.Transitions {
+		transitions = append(transitions, response.StatusTransition{
+			From:      string(t.FromStatus),
+			To:        string(t.ToStatus),
+			Actor:     t.Actor,
+			Reason:    t.Reason,
+			CreatedAt: t.CreatedAt,
+		})
+	}
+
 	return &response.OrderResponse{
-		ID:        order.ID.String(),
-		Total:     order.Total,
-		Discounts: order.Discounts,
-		Items:     items,
-		Products:  products,
-	}, nil
+		ID:            order.ID.String(),
+		Status:        string(order.Status),
+		CreatedAt:     order.CreatedAt,
+		Subtotal:      order.Subtotal,
+		Total:         order.Total,
+		Discounts:     order.Discounts,
+		OrderDiscount: order.OrderDiscount,
+		Promotion:     toPromotionResponse(order.Promotion),
+		Items:         items,
+		Products:      products,
+		Transitions:   transitions,
+	}
+}
+
+func toPromotionResponse(p *db.Promotion) *response.Promotion {
+	if p == nil {
+		return nil
+	}
+
+	var amountOff *money.Money
+	if !p.AmountOff.IsZero() {
+		amountOff = &p.AmountOff
+	}
+
+	return &response.Promotion{
+		Code:        p.Code,
+		Type:        p.Type,
+		Value:       p.Value,
+		AmountOff:   amountOff,
+		Category:    p.Category,
+		BuyQuantity: p.BuyQuantity,
+		GetQuantity: p.GetQuantity,
+	}
 }
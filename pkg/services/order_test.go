@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/malakagl/go-template/pkg/errors"
+	"github.com/malakagl/go-template/pkg/models/dto/request"
+	"github.com/malakagl/go-template/pkg/repositories"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockCouponValidator implements couponcode.CouponValidator for testing
+type MockCouponValidator struct {
+	mock.Mock
+}
+
+func (m *MockCouponValidator) Validate(_ context.Context, code string) (bool, error) {
+	args := m.Called(code)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestOrderService_CreateRejectsCoupon(t *testing.T) {
+	tests := []struct {
+		name        string
+		valid       bool
+		validErr    error
+		expectedErr error
+	}{
+		{
+			name:        "invalid coupon code",
+			valid:       false,
+			expectedErr: errors.ErrInvalidCouponCode,
+		},
+		{
+			name:        "validator error",
+			validErr:    errors.New("index unavailable"),
+			expectedErr: errors.ErrInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := new(MockCouponValidator)
+			validator.On("Validate", "WRONGCODE").Return(tt.valid, tt.validErr)
+			s := NewOrderService(repositories.OrderRepo{}, validator, repositories.ProductRepo{}, repositories.PromotionRepo{}, nil)
+
+			res, err := s.Create(t.Context(), &request.OrderRequest{
+				CouponCode: "WRONGCODE",
+				Items:      []request.Item{{ProductID: "1", Quantity: 1}},
+			})
+			if !errors.Is(err, tt.expectedErr) || res != nil {
+				t.Errorf("expected %v, got %v, %v", tt.expectedErr, res, err)
+			}
+			validator.AssertExpectations(t)
+		})
+	}
+}
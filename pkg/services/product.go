@@ -2,37 +2,75 @@ package services
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/malakagl/go-template/pkg/errors"
 	"github.com/malakagl/go-template/pkg/log"
+	"github.com/malakagl/go-template/pkg/models/db"
+	"github.com/malakagl/go-template/pkg/models/dto/request"
 	"github.com/malakagl/go-template/pkg/models/dto/response"
+	"github.com/malakagl/go-template/pkg/money"
 	"github.com/malakagl/go-template/pkg/repositories"
 )
 
 type IProductService interface {
-	FindAll(ctx context.Context) (*response.ProductsResponse, error)
+	FindAll(ctx context.Context, filter *request.ProductFilter) (*response.ProductsResponse, error)
 	FindByID(ctx context.Context, id uint) (*response.ProductResponse, error)
+	Create(ctx context.Context, req *request.ProductRequest) (*response.ProductResponse, error)
+	Update(ctx context.Context, id uint, req *request.ProductRequest) (*response.ProductResponse, error)
+	Delete(ctx context.Context, id uint) error
+	Restore(ctx context.Context, id uint) (*response.ProductResponse, error)
 }
 
 type ProductService struct {
-	repo repositories.ProductRepo
+	repo  repositories.ProductRepo
+	audit IAuditLogger
 }
 
-func NewProductService(r repositories.ProductRepo) ProductService {
-	return ProductService{repo: r}
+func NewProductService(r repositories.ProductRepo, a IAuditLogger) ProductService {
+	return ProductService{repo: r, audit: a}
 }
 
-func (s *ProductService) FindAll(ctx context.Context) (*response.ProductsResponse, error) {
-	res, err := s.repo.FindAll(ctx)
+// FindAll returns a page of products. The next_cursor of the response continues the listing with the same sort.
+func (s *ProductService) FindAll(ctx context.Context, f *request.ProductFilter) (*response.ProductsResponse, error) {
+	filter := repositories.ProductFilter{
+		Category: f.Category,
+		MinPrice: f.MinPrice,
+		MaxPrice: f.MaxPrice,
+		Search:   f.Search,
+		SortBy:   strings.TrimPrefix(f.Sort, "-"),
+		Desc:     strings.HasPrefix(f.Sort, "-"),
+		// one extra product tells whether there is a next page
+		Limit: f.Limit + 1,
+	}
+	if filter.SortBy == "" {
+		filter.SortBy = repositories.ProductSortID
+	}
+	if f.Cursor != "" {
+		after, err := decodeProductCursor(f.Cursor, f.Sort)
+		if err != nil {
+			log.WithCtx(ctx).Warn().Msgf("Invalid product cursor %s: %v", f.Cursor, err)
+			return nil, errors.ErrInvalidCursor
+		}
+
+		filter.After = after
+	}
+
+	res, err := s.repo.FindAll(ctx, filter)
 	if err != nil {
 		log.WithCtx(ctx).Error().Msgf("findAll failed with error: %v", err)
-		return nil, errors.ErrProductNotFound
+		return nil, err
 	}
 
-	if res == nil {
-		log.WithCtx(ctx).Error().Msg("findAll returned 0 elements")
-		return nil, errors.ErrProductNotFound
+	var nextCursor string
+	if len(res) > f.Limit {
+		res = res[:f.Limit]
+		nextCursor = encodeProductCursor(&res[len(res)-1], filter.SortBy, f.Sort)
 	}
 
 	products := make([]response.Product, len(res))
@@ -42,6 +80,7 @@ func (s *ProductService) FindAll(ctx context.Context) (*response.ProductsRespons
 			Name:     p.Name,
 			Price:    p.Price,
 			Category: p.Category,
+			Stock:    p.Stock,
 			Image: response.ProductImage{
 				Thumbnail: p.Image.Thumbnail,
 				Mobile:    p.Image.Mobile,
@@ -51,7 +90,47 @@ func (s *ProductService) FindAll(ctx context.Context) (*response.ProductsRespons
 		}
 	}
 
-	return &response.ProductsResponse{Products: products}, nil
+	return &response.ProductsResponse{Products: products, NextCursor: nextCursor}, nil
+}
+
+// productCursor is the opaque next_cursor, it is bound to the sort it was issued for.
+type productCursor struct {
+	Sort  string `json:"s,omitempty"`
+	Value string `json:"v,omitempty"`
+	ID    uint   `json:"id"`
+}
+
+func encodeProductCursor(p *db.Product, sortBy, sort string) string {
+	c := productCursor{Sort: sort, ID: p.ID}
+	switch sortBy {
+	case repositories.ProductSortName:
+		c.Value = p.Name
+	case repositories.ProductSortPrice:
+		c.Value = strconv.FormatInt(p.Price.Amount, 10)
+	case repositories.ProductSortCreatedAt:
+		c.Value = p.CreatedAt.Format(time.RFC3339Nano)
+	}
+
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeProductCursor(cursor, sort string) (*repositories.ProductCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	var c productCursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+
+	if c.Sort != sort {
+		return nil, fmt.Errorf("cursor was issued for sort %q", c.Sort)
+	}
+
+	return &repositories.ProductCursor{Value: c.Value, ID: c.ID}, nil
 }
 
 func (s *ProductService) FindByID(ctx context.Context, id uint) (*response.ProductResponse, error) {
@@ -71,6 +150,7 @@ func (s *ProductService) FindByID(ctx context.Context, id uint) (*response.Produ
 		Name:     res.Name,
 		Price:    res.Price,
 		Category: res.Category,
+		Stock:    res.Stock,
 		Image: response.ProductImage{
 			Thumbnail: res.Image.Thumbnail,
 			Mobile:    res.Image.Mobile,
@@ -79,3 +159,79 @@ func (s *ProductService) FindByID(ctx context.Context, id uint) (*response.Produ
 		},
 	}, nil
 }
+
+func (s *ProductService) Create(ctx context.Context, req *request.ProductRequest) (*response.ProductResponse, error) {
+	product := toProduct(0, req)
+	err := s.repo.Create(ctx, product)
+	s.audit.Record(ctx, db.AuditProductCreate, productTarget(product, err), "", err)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("create failed with error: %v", err)
+		return nil, err
+	}
+
+	return s.FindByID(ctx, product.ID)
+}
+
+// Update replaces the product fields, a request without stock stops stock tracking.
+// The image is only replaced when the request carries one.
+func (s *ProductService) Update(ctx context.Context, id uint, req *request.ProductRequest) (*response.ProductResponse, error) {
+	product := toProduct(id, req)
+	err := s.repo.Update(ctx, product, req.Image != nil)
+	s.audit.Record(ctx, db.AuditProductUpdate, strconv.FormatUint(uint64(id), 10), "", err)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("update of product %d failed with error: %v", id, err)
+		return nil, err
+	}
+
+	return s.FindByID(ctx, id)
+}
+
+func (s *ProductService) Delete(ctx context.Context, id uint) error {
+	err := s.repo.Delete(ctx, id)
+	s.audit.Record(ctx, db.AuditProductDelete, strconv.FormatUint(uint64(id), 10), "", err)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("delete of product %d failed with error: %v", id, err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *ProductService) Restore(ctx context.Context, id uint) (*response.ProductResponse, error) {
+	err := s.repo.Restore(ctx, id)
+	s.audit.Record(ctx, db.AuditProductRestore, strconv.FormatUint(uint64(id), 10), "", err)
+	if err != nil {
+		log.WithCtx(ctx).Error().Msgf("restore of product %d failed with error: %v", id, err)
+		return nil, err
+	}
+
+	return s.FindByID(ctx, id)
+}
+
+func productTarget(product *db.Product, err error) string {
+	if err != nil {
+		return ""
+	}
+
+	return strconv.FormatUint(uint64(product.ID), 10)
+}
+
+func toProduct(id uint, req *request.ProductRequest) *db.Product {
+	product := &db.Product{
+		ID:       id,
+		Name:     req.Name,
+		Price:    money.New(req.Price.Amount, req.Price.Currency),
+		Category: req.Category,
+		Stock:    req.Stock,
+	}
+	if req.Image != nil {
+		product.Image = db.ProductImage{
+			Thumbnail: req.Image.Thumbnail,
+			Mobile:    req.Image.Mobile,
+			Tablet:    req.Image.Tablet,
+			Desktop:   req.Image.Desktop,
+		}
+	}
+
+	return product
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/malakagl/go-template/pkg/errors"
+	"github.com/malakagl/go-template/pkg/models/db"
+	"github.com/malakagl/go-template/pkg/models/dto/request"
+	"github.com/malakagl/go-template/pkg/models/dto/response"
+	"github.com/malakagl/go-template/pkg/money"
+	"github.com/malakagl/go-template/pkg/repositories"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeAuditLogger keeps the recorded events in memory
+type fakeAuditLogger struct {
+	events []*db.AuditEvent
+}
+
+func (f *fakeAuditLogger) Log(_ context.Context, e *db.AuditEvent) {
+	f.events = append(f.events, e)
+}
+
+func (f *fakeAuditLogger) Record(ctx context.Context, action, target, detail string, err error) {
+	e := &db.AuditEvent{Action: action, Target: target, Outcome: db.AuditSuccess, Detail: detail}
+	if err != nil {
+		e.Outcome = db.AuditFailure
+	}
+	f.Log(ctx, e)
+}
+
+func (f *fakeAuditLogger) FindAll(context.Context, *request.AuditFilter) (*response.AuditEventsResponse, error) {
+	return &response.AuditEventsResponse{}, nil
+}
+
+func TestProductServiceAudit(t *testing.T) {
+	database, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = database.AutoMigrate(&db.Product{}, &db.ProductImage{}); err != nil {
+		t.Fatal(err)
+	}
+
+	audit := &fakeAuditLogger{}
+	s := NewProductService(repositories.NewProductRepo(database), audit)
+	req := &request.ProductRequest{Name: "Waffle", Price: request.ProductPrice{Amount: 650, Currency: "USD"}, Category: "Waffle"}
+	p, err := s.Create(t.Context(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Update(t.Context(), 1, req); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Delete(t.Context(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Restore(t.Context(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Delete(t.Context(), 2); !errors.Is(err, errors.ErrProductNotFound) {
+		t.Fatalf("expected deleting a missing product to fail, got %v", err)
+	}
+
+	want := []db.AuditEvent{
+		{Action: db.AuditProductCreate, Target: p.ID, Outcome: db.AuditSuccess},
+		{Action: db.AuditProductUpdate, Target: "1", Outcome: db.AuditSuccess},
+		{Action: db.AuditProductDelete, Target: "1", Outcome: db.AuditSuccess},
+		{Action: db.AuditProductRestore, Target: "1", Outcome: db.AuditSuccess},
+		{Action: db.AuditProductDelete, Target: "2", Outcome: db.AuditFailure},
+	}
+	if len(audit.events) != len(want) {
+		t.Fatalf("expected %d audit events, got %d", len(want), len(audit.events))
+	}
+	for i, w := range want {
+		if e := audit.events[i]; e.Action != w.Action || e.Target != w.Target || e.Outcome != w.Outcome {
+			t.Errorf("event %d: expected %s %s %s, got %s %s %s", i, w.Action, w.Target, w.Outcome, e.Action, e.Target, e.Outcome)
+		}
+	}
+}
+
+func TestProductCursor(t *testing.T) {
+	p := &db.Product{ID: 7, Name: "Waffle", Price: money.New(650, "USD"), CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC)}
+	tests := []struct {
+		sort  string
+		value string
+	}{
+		{sort: "", value: ""},
+		{sort: "-name", value: "Waffle"},
+		{sort: "price", value: "650"},
+		{sort: "-created_at", value: "2024-01-02T03:04:05.000006Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			sortBy := strings.TrimPrefix(tt.sort, "-")
+			if sortBy == "" {
+				sortBy = repositories.ProductSortID
+			}
+
+			c, err := decodeProductCursor(encodeProductCursor(p, sortBy, tt.sort), tt.sort)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if c.ID != p.ID || c.Value != tt.value {
+				t.Errorf("expected cursor {%s %d}, got {%s %d}", tt.value, p.ID, c.Value, c.ID)
+			}
+		})
+	}
+
+	if _, err := decodeProductCursor(encodeProductCursor(p, "price", "price"), "-price"); err == nil {
+		t.Error("expected a cursor issued for another sort to be rejected")
+	}
+	if _, err := decodeProductCursor("not a cursor", ""); err == nil {
+		t.Error("expected a malformed cursor to be rejected")
+	}
+}
@@ -1,6 +1,9 @@
 package util
 
 import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
@@ -9,6 +12,7 @@ import (
 	"runtime"
 	"strconv"
 
+	"github.com/malakagl/go-template/pkg/constants"
 	"github.com/malakagl/go-template/pkg/errors"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -30,15 +34,49 @@ func StringToUint(s string) (uint, error) {
 	return uint(t), nil
 }
 
+// MapErrorToHTTP returns the status and message of the response for err, unknown errors are internal server errors
 func MapErrorToHTTP(err error) (int, string) {
 	switch {
 	case errors.Is(err, errors.ErrInvalidCouponCode):
 		return http.StatusUnprocessableEntity, "Invalid coupon code"
+	case errors.Is(err, errors.ErrCurrencyMismatch):
+		return http.StatusUnprocessableEntity, "Mixed currencies"
+	case errors.Is(err, errors.ErrInvalidOrderID), errors.Is(err, errors.ErrInvalidProductID), errors.Is(err, errors.ErrBadRequest),
+		errors.Is(err, errors.ErrInvalidCursor), errors.Is(err, errors.ErrUnknownScope), errors.Is(err, errors.ErrUnknownTier):
+		return http.StatusBadRequest, "Invalid request data"
+	case errors.Is(err, errors.ErrInvalidTransition):
+		return http.StatusConflict, "Invalid order status transition"
+	case errors.Is(err, errors.ErrInsufficientStock):
+		return http.StatusConflict, "Insufficient stock"
+	case errors.Is(err, errors.ErrAPIKeyRevoked):
+		return http.StatusConflict, "API key is revoked"
+	case errors.Is(err, errors.ErrSigningDisabled):
+		return http.StatusConflict, "Request signing is disabled"
+	case errors.Is(err, errors.ErrAPIKeyNotFound):
+		return http.StatusNotFound, "API key not found"
+	case errors.Is(err, errors.ErrEndpointsNotFound), errors.Is(err, errors.ErrGrantNotFound):
+		return http.StatusNotFound, "Endpoint not found"
+	case errors.Is(err, errors.ErrOrderNotFound):
+		return http.StatusNotFound, "Order not found"
+	case errors.Is(err, errors.ErrProductNotFound):
+		return http.StatusNotFound, "No products found"
 	default:
-		return http.StatusInternalServerError, "Failed to create order"
+		return http.StatusInternalServerError, "Internal server error"
 	}
 }
 
+// ClientIDFromContext returns the client ID of the authenticated API key, or "" for unauthenticated requests.
+func ClientIDFromContext(ctx context.Context) string {
+	clientID, _ := ctx.Value(constants.ClientID).(string)
+	return clientID
+}
+
+// ClientIPFromContext returns the client IP resolved by middleware.ClientIP, or "" when it was not resolved.
+func ClientIPFromContext(ctx context.Context) string {
+	clientIP, _ := ctx.Value(constants.ClientIP).(string)
+	return clientIP
+}
+
 func generateRandomBytes(n int) ([]byte, error) {
 	b := make([]byte, n)
 	_, err := rand.Read(b)
@@ -51,6 +89,16 @@ func GenerateAPIKey() (string, string, string, error) {
 	clientIDBytes, _ := generateRandomBytes(8)
 	clientID := base64.RawURLEncoding.EncodeToString(clientIDBytes)
 
+	hash, fullKey, err := GenerateAPISecret(clientID)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	return clientID, hash, fullKey, nil
+}
+
+// GenerateAPISecret returns (secretHash, fullKey) for a new secret of clientID
+func GenerateAPISecret(clientID string) (string, string, error) {
 	// Secret value
 	secretBytes, _ := generateRandomBytes(32)
 	secret := base64.RawURLEncoding.EncodeToString(secretBytes)
@@ -58,11 +106,67 @@ func GenerateAPIKey() (string, string, string, error) {
 	// Hash the secret
 	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
 	if err != nil {
-		return "", "", "", err
+		return "", "", err
 	}
 
 	// Full key (what you return to client)
-	fullKey := fmt.Sprintf("%s.%s", clientID, secret)
+	return string(hash), fmt.Sprintf("%s.%s", clientID, secret), nil
+}
+
+// GenerateSigningSecret returns a new secret for signing requests
+func GenerateSigningSecret() (string, error) {
+	b, err := generateRandomBytes(32)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Seal encrypts plaintext with the AES key using GCM, the nonce is prepended to the base64 encoded result
+func Seal(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce, err := generateRandomBytes(gcm.NonceSize())
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plaintext), nil)), nil
+}
+
+// Open decrypts a value sealed by Seal with the same key
+func Open(key []byte, sealed string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("sealed value too short")
+	}
+
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
 
-	return clientID, string(hash), fullKey, nil
+	return cipher.NewGCM(block)
 }
@@ -3,14 +3,22 @@ package main
 import (
 	"context"
 	"flag"
-	"strings"
+	"fmt"
+	"io"
+	"os"
 
 	"github.com/malakagl/go-template/internal/config"
 	"github.com/malakagl/go-template/internal/database"
 	"github.com/malakagl/go-template/pkg/log"
 	"github.com/malakagl/go-template/pkg/repositories"
+	"github.com/malakagl/go-template/pkg/scopes"
 )
 
+// keyCreator is the part of repositories.ApiKeyRepository the admin key is created with
+type keyCreator interface {
+	CreateAPIKeyWithEndpoints(ctx context.Context, endpointIDs []uint, scopeNames []string) (string, error)
+}
+
 func main() {
 	ctx := context.Background()
 	var cfgPath string
@@ -29,30 +37,22 @@ func main() {
 		return
 	}
 
-	apiKeyRepo := repositories.NewApiKeyRepository(db)
-	endpoints, err := apiKeyRepo.FindAllEndpoints(ctx)
-	if err != nil {
-		log.Error().Err(err).Msg("failed to find all endpoints")
+	if err = createAdminKey(ctx, repositories.NewApiKeyRepository(db), os.Stdout); err != nil {
+		log.Error().Err(err).Msg("failed to create admin key")
 		return
 	}
 
-	var adminEndPoints []uint
-	for _, endpoint := range endpoints {
-		if strings.HasPrefix(endpoint.HTTPEndpoint, "/admin/") {
-			adminEndPoints = append(adminEndPoints, endpoint.ID)
-		}
-	}
-
-	if len(adminEndPoints) != 0 {
-		adminKey, err := apiKeyRepo.CreateAPIKeyWithEndpoints(ctx, adminEndPoints)
-		if err != nil {
-			log.Error().Err(err).Msg("failed to create admin key")
-			return
-		}
+	log.Info().Msg("created admin key")
+}
 
-		log.Info().Msgf("created admin key: %s", adminKey)
-		return
+// createAdminKey creates a key of the operator role, which holds every scope including admin, and writes it to w.
+// The key is only shown once, it is written outside the logger which redacts keys.
+func createAdminKey(ctx context.Context, repo keyCreator, w io.Writer) error {
+	adminKey, err := repo.CreateAPIKeyWithEndpoints(ctx, nil, []string{scopes.RoleOperator})
+	if err != nil {
+		return err
 	}
 
-	log.Info().Msg("no admin apis available with prefix '/admin/'")
+	_, err = fmt.Fprintln(w, adminKey)
+	return err
 }
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/malakagl/go-template/pkg/scopes"
+)
+
+type fakeKeyCreator struct {
+	scopes []string
+}
+
+func (f *fakeKeyCreator) CreateAPIKeyWithEndpoints(_ context.Context, _ []uint, scopeNames []string) (string, error) {
+	f.scopes = scopeNames
+	return "a1b2c3d4.0123456789abcdef0123456789abcdef", nil
+}
+
+func TestCreateAdminKey(t *testing.T) {
+	var out bytes.Buffer
+	repo := &fakeKeyCreator{}
+	if err := createAdminKey(t.Context(), repo, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.String() != "a1b2c3d4.0123456789abcdef0123456789abcdef\n" {
+		t.Errorf("expected the full key to be printed, got %q", out.String())
+	}
+	if !slices.Equal(repo.scopes, []string{scopes.RoleOperator}) {
+		t.Errorf("expected a key of the operator role, got %v", repo.scopes)
+	}
+}
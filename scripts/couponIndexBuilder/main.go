@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/malakagl/go-template/internal/config"
+	"github.com/malakagl/go-template/internal/couponcode"
+	"github.com/malakagl/go-template/pkg/log"
+)
+
+// Builds sorted coupon code indexes ahead of deployment so the server can map them on start up.
+// usage: go run ./scripts/couponIndexBuilder ./promocodes/couponbase1.gz ./promocodes/couponbase2.gz
+func main() {
+	ctx := context.Background()
+	flag.Parse()
+
+	log.Init("go-template", config.LoggingConfig{Level: "info", JsonFormat: false})
+	if flag.NArg() == 0 {
+		log.Fatal().Msg("no coupon code files given")
+	}
+
+	for _, path := range flag.Args() {
+		indexPath, err := couponcode.BuildIndex(ctx, path)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("failed to build index for %s", path)
+		}
+
+		log.Info().Msgf("index for %s written to %s", path, indexPath)
+	}
+}
@@ -46,10 +46,13 @@ func tearDownTestData() {
 		drop table if exists go_template_it.endpoints cascade;
 		drop table if exists go_template_it.products cascade;
 		drop table if exists go_template_it.orders cascade;
+		drop table if exists go_template_it.order_status_transitions cascade;
 		drop table if exists go_template_it.product_images cascade;
 		drop table if exists go_template_it.order_products cascade;
 		drop table if exists go_template_it.coupon_codes cascade;
 		drop table if exists go_template_it.files cascade;
+		drop table if exists go_template_it.idempotency_keys cascade;
+		drop table if exists go_template_it.promotions cascade;
 		drop table if exists go_template_it.schema_migrations cascade;
     `)
 	if err != nil {
@@ -105,8 +108,8 @@ func seedPostgresData() {
 	}
 
 	_, err = dbPool.Exec(ctx, `
-		INSERT INTO products (name, price, category) 
-			VALUES ('Chicken Waffle', 13.25, 'Waffle');
+		INSERT INTO products (name, price_amount, price_currency, category) 
+			VALUES ('Chicken Waffle', 1325, 'USD', 'Waffle');
 		INSERT INTO product_images (product_id, thumbnail, mobile, tablet, desktop) 
 			VALUES (1, '1/thumbnail.jpg', '1/mobile.jpg', '1/tablet.jpg', '1/desktop.jpg');
 		INSERT INTO api_keys (client_id, api_key) 